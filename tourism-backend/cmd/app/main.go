@@ -2,6 +2,8 @@ package main
 
 import (
 	"log"
+	// Tour schedules load time zones, also on hosts without a zoneinfo database.
+	_ "time/tzdata"
 
 	"tourism-backend/config"
 	"tourism-backend/internal/app"
@@ -0,0 +1,144 @@
+// Command media-migrate copies the media saved under the local upload
+// directory into the configured media store and points the database at it.
+//
+// Avatars become private media, the users keep their key and get signed URLs.
+// Run it once after switching MEDIA_BACKEND, with -dry-run to preview:
+//
+//	MEDIA_BACKEND=s3 go run ./cmd/media-migrate -from ./uploads
+package main
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"tourism-backend/config"
+	"tourism-backend/pkg/media"
+	"tourism-backend/pkg/postgres"
+)
+
+// _legacyPrefix starts the URLs stored before the media store existed.
+const _legacyPrefix = "./v1/tours/uploads/"
+
+// mediaColumns hold media URLs. Columns marked private store the key of private
+// media instead of a URL.
+var mediaColumns = []struct {
+	table, column string
+	private       bool
+}{
+	{table: "tourism.images", column: "image_url"},
+	{table: "tourism.videos", column: "video_url"},
+	{table: "tourism.panoramas", column: "panorama_url"},
+	{table: "tourism.review_photos", column: "photo_url"},
+	{table: "tourism.users", column: "avatar_url", private: true},
+}
+
+func main() {
+	from := flag.String("from", "", "local upload directory to migrate, defaults to media.local_dir")
+	dryRun := flag.Bool("dry-run", false, "only print what would be migrated")
+	flag.Parse()
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf("Config error: %s", err)
+	}
+	if *from == "" {
+		*from = cfg.Media.LocalDir
+	}
+
+	store, err := media.NewStore(cfg.Media)
+	if err != nil {
+		log.Fatalf("media store: %s", err)
+	}
+	pg, err := postgres.New(cfg.PG.URL)
+	if err != nil {
+		log.Fatalf("postgres: %s", err)
+	}
+
+	files, err := copyFiles(store, *from, *dryRun)
+	if err != nil {
+		log.Fatalf("copy media: %s", err)
+	}
+	log.Printf("copied %d files from %s", files, *from)
+
+	for _, c := range mediaColumns {
+		rows, err := rewriteColumn(pg, store, c.table, c.column, c.private, *dryRun)
+		if err != nil {
+			log.Fatalf("rewrite %s.%s: %s", c.table, c.column, err)
+		}
+		log.Printf("rewrote %d rows of %s.%s", rows, c.table, c.column)
+	}
+}
+
+// mediaKey maps the path of a file under the upload directory to its key.
+func mediaKey(rel string) string {
+	if strings.HasPrefix(rel, "avatars/") {
+		return media.PrivatePrefix + rel
+	}
+	return rel
+}
+
+func copyFiles(store media.Store, root string, dryRun bool) (int, error) {
+	files := 0
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		key := mediaKey(filepath.ToSlash(rel))
+		files++
+		if dryRun {
+			log.Printf("copy %s to %s", name, key)
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return store.Put(context.Background(), key, f, info.Size(), mime.TypeByExtension(path.Ext(key)))
+	})
+	return files, err
+}
+
+func rewriteColumn(pg *postgres.Postgres, store media.Store, table, column string, private, dryRun bool) (int, error) {
+	var rows []struct {
+		ID  string
+		URL string
+	}
+	err := pg.Conn.Raw("SELECT id, "+column+" AS url FROM "+table+" WHERE "+column+" LIKE ?", _legacyPrefix+"%").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		key := mediaKey(strings.TrimPrefix(row.URL, _legacyPrefix))
+		value := key
+		if !private {
+			value = store.URL(key)
+		}
+		if dryRun {
+			log.Printf("%s %s: %s -> %s", table, row.ID, row.URL, value)
+			continue
+		}
+		if err := pg.Conn.Exec("UPDATE "+table+" SET "+column+" = ? WHERE id = ?", value, row.ID).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,72 @@
+// Command ticket generates the key that signs QR tickets and checks ticket
+// tokens offline, with the public key only.
+//
+// Generate a key pair, then set TICKET_PRIVATE_KEY to the private key:
+//
+//	go run ./cmd/ticket keygen
+//
+// Check a scanned token against the key served at /v1/tours/tickets/public-key:
+//
+//	go run ./cmd/ticket verify -key <public key> <token>
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	"tourism-backend/pkg/ticket"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatal("usage: ticket keygen | ticket verify -key <public key> <token>")
+	}
+
+	switch os.Args[1] {
+	case "keygen":
+		keygen()
+	case "verify":
+		verify(os.Args[2:])
+	default:
+		log.Fatalf("unknown command %q, want keygen or verify", os.Args[1])
+	}
+}
+
+func keygen() {
+	key, err := ticket.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("TICKET_PRIVATE_KEY=%s\n", ticket.EncodePrivateKey(key))
+	fmt.Printf("public key: %s\n", ticket.EncodePublicKey(key.Public().(ed25519.PublicKey)))
+}
+
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	publicKey := flags.String("key", "", "base64 public key of the tickets")
+	_ = flags.Parse(args)
+	if *publicKey == "" || flags.NArg() != 1 {
+		log.Fatal("usage: ticket verify -key <public key> <token>")
+	}
+
+	key, err := ticket.ParsePublicKey(*publicKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	t, err := ticket.Verify(key, flags.Arg(0), time.Now())
+	if err != nil && !errors.Is(err, ticket.ErrTicketExpired) {
+		log.Fatal(err)
+	}
+
+	out, _ := json.MarshalIndent(t, "", "  ")
+	fmt.Println(string(out))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
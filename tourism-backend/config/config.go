@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -11,12 +12,18 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App    `yaml:"app"`
-		HTTP   `yaml:"http"`
-		Log    `yaml:"logger"`
-		PG     `yaml:"postgres"`
-		Kafka  `yaml:"kafka"`
-		Stripe `yaml:"stripe"`
+		App      `yaml:"app"`
+		HTTP     `yaml:"http"`
+		Log      `yaml:"logger"`
+		PG       `yaml:"postgres"`
+		Kafka    `yaml:"kafka"`
+		Stripe   `yaml:"stripe"`
+		Payment  `yaml:"payment"`
+		Purchase `yaml:"purchase"`
+		Media    `yaml:"media"`
+		Upload   `yaml:"upload"`
+		Schedule `yaml:"schedule"`
+		Ticket   `yaml:"ticket"`
 	}
 
 	// App -.
@@ -47,7 +54,69 @@ type (
 	}
 
 	Stripe struct {
-		SecretKey string `env:"STRIPE_SECRET_KEY" env-required:"true"`
+		SecretKey     string `env:"STRIPE_SECRET_KEY"`
+		WebhookSecret string `env:"STRIPE_WEBHOOK_SECRET"`
+	}
+
+	// Payment -. Purchases are charged with Stripe. The fake gateway, which
+	// pays purchases without charging anyone, is for development only and is
+	// chosen with PAYMENT_GATEWAY=fake, as `make run` does.
+	Payment struct {
+		Gateway      string        `yaml:"gateway"       env:"PAYMENT_GATEWAY"       env-default:"stripe"`
+		Currency     string        `yaml:"currency"      env:"PAYMENT_CURRENCY"      env-default:"kzt"`
+		FakeScenario string        `yaml:"fake_scenario" env:"PAYMENT_FAKE_SCENARIO" env-default:"success"`
+		FakeLatency  time.Duration `yaml:"fake_latency"  env:"PAYMENT_FAKE_LATENCY"  env-default:"5s"`
+		Workers      int           `yaml:"workers"       env:"PAYMENT_WORKERS"       env-default:"4"`
+		MaxAttempts  int           `yaml:"max_attempts"  env:"PAYMENT_MAX_ATTEMPTS"  env-default:"5"`
+		RetryBackoff time.Duration `yaml:"retry_backoff" env:"PAYMENT_RETRY_BACKOFF" env-default:"10s"`
+		PollInterval time.Duration `yaml:"poll_interval" env:"PAYMENT_POLL_INTERVAL" env-default:"2s"`
+		JobLease     time.Duration `yaml:"job_lease"     env:"PAYMENT_JOB_LEASE"     env-default:"1m"`
+	}
+
+	// Purchase -.
+	Purchase struct {
+		SeatHoldTTL      time.Duration `yaml:"seat_hold_ttl"      env:"PURCHASE_SEAT_HOLD_TTL"      env-default:"15m"`
+		SweepInterval    time.Duration `yaml:"sweep_interval"     env:"PURCHASE_SWEEP_INTERVAL"     env-default:"1m"`
+		WaitlistOfferTTL time.Duration `yaml:"waitlist_offer_ttl" env:"PURCHASE_WAITLIST_OFFER_TTL" env-default:"30m"`
+	}
+
+	// Media -.
+	Media struct {
+		Backend      string        `yaml:"backend"        env:"MEDIA_BACKEND"        env-default:"local"`
+		LocalDir     string        `yaml:"local_dir"      env:"MEDIA_LOCAL_DIR"      env-default:"./uploads"`
+		LocalURL     string        `yaml:"local_url"      env:"MEDIA_LOCAL_URL"      env-default:"./v1/tours/uploads"`
+		SigningKey   string        `                      env:"MEDIA_SIGNING_KEY"`
+		SignedURLTTL time.Duration `yaml:"signed_url_ttl" env:"MEDIA_SIGNED_URL_TTL" env-default:"15m"`
+		S3Endpoint   string        `yaml:"s3_endpoint"    env:"MEDIA_S3_ENDPOINT"`
+		S3Region     string        `yaml:"s3_region"      env:"MEDIA_S3_REGION"      env-default:"us-east-1"`
+		S3Bucket     string        `yaml:"s3_bucket"      env:"MEDIA_S3_BUCKET"`
+		S3AccessKey  string        `                      env:"MEDIA_S3_ACCESS_KEY"`
+		S3SecretKey  string        `                      env:"MEDIA_S3_SECRET_KEY"`
+		S3PathStyle  bool          `yaml:"s3_path_style"  env:"MEDIA_S3_PATH_STYLE"  env-default:"false"`
+		S3PublicURL  string        `yaml:"s3_public_url"  env:"MEDIA_S3_PUBLIC_URL"`
+		GCInterval   time.Duration `yaml:"gc_interval"    env:"MEDIA_GC_INTERVAL"    env-default:"6h"`
+		GCMinAge     time.Duration `yaml:"gc_min_age"     env:"MEDIA_GC_MIN_AGE"     env-default:"24h"`
+	}
+
+	// Upload -.
+	Upload struct {
+		MaxVideoSize    int64         `yaml:"max_video_size"    env:"UPLOAD_MAX_VIDEO_SIZE"    env-default:"4294967296"`
+		MaxPanoramaSize int64         `yaml:"max_panorama_size" env:"UPLOAD_MAX_PANORAMA_SIZE" env-default:"209715200"`
+		MaxChunkSize    int64         `yaml:"max_chunk_size"    env:"UPLOAD_MAX_CHUNK_SIZE"    env-default:"33554432"`
+		SessionTTL      time.Duration `yaml:"session_ttl"       env:"UPLOAD_SESSION_TTL"       env-default:"24h"`
+		SweepInterval   time.Duration `yaml:"sweep_interval"    env:"UPLOAD_SWEEP_INTERVAL"    env-default:"10m"`
+	}
+
+	// Schedule -.
+	Schedule struct {
+		Horizon          time.Duration `yaml:"horizon"           env:"SCHEDULE_HORIZON"           env-default:"2160h"`
+		GenerateInterval time.Duration `yaml:"generate_interval" env:"SCHEDULE_GENERATE_INTERVAL" env-default:"1h"`
+	}
+
+	// Ticket -.
+	Ticket struct {
+		PrivateKey string        `                env:"TICKET_PRIVATE_KEY"`
+		Validity   time.Duration `yaml:"validity" env:"TICKET_VALIDITY"    env-default:"24h"`
 	}
 
 	// RMQ -.
@@ -64,7 +133,7 @@ func NewConfig() (*Config, error) {
 
 	err := godotenv.Load()
 	if err != nil {
-		log.Fatalf("Error loading .env file", err)
+		log.Fatalf("Error loading .env file: %v", err)
 	}
 
 	err = cleanenv.ReadConfig("./config/config.yml", cfg)
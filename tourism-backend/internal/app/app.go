@@ -3,9 +3,11 @@ package app
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -17,8 +19,10 @@ import (
 	"tourism-backend/pkg/httpserver"
 	"tourism-backend/pkg/kafka"
 	"tourism-backend/pkg/logger"
+	"tourism-backend/pkg/media"
 	"tourism-backend/pkg/payment"
 	"tourism-backend/pkg/postgres"
+	"tourism-backend/pkg/ticket"
 )
 
 // @Summary Get static files (images/videos)
@@ -81,10 +85,41 @@ func Run(cfg *config.Config) {
 	//	kafkaProducer,
 	//	telegramClient,
 	//)
+	// Payment Gateway
+	paymentGateway, err := payment.NewGateway(cfg)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - payment.NewGateway: %w", err))
+	}
+
+	// Media Store
+	mediaStore, err := media.NewStore(cfg.Media)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - media.NewStore: %w", err))
+	}
+
+	// Ticket Key
+	var ticketKey ed25519.PrivateKey
+	if cfg.Ticket.PrivateKey == "" {
+		// QR tickets stop verifying after a restart, set TICKET_PRIVATE_KEY to keep them.
+		l.Warn("app - Run - TICKET_PRIVATE_KEY is not set, signing tickets with a temporary key")
+		ticketKey, err = ticket.GenerateKey()
+	} else {
+		ticketKey, err = ticket.ParsePrivateKey(cfg.Ticket.PrivateKey)
+	}
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - ticket key: %w", err))
+	}
+
 	// Use case
 	tourismUseCase := usecase.NewTourismUseCase(
-		repo.NewTourismRepo(pg),
+		repo.NewTourismRepo(pg, mediaStore),
 		kafkaProducer,
+		paymentGateway,
+		cfg.Purchase,
+		cfg.Upload,
+		cfg.Schedule,
+		cfg.Ticket,
+		ticketKey,
 	)
 	adminUseCase := usecase.NewAdminUseCase(
 		repo.NewAdminRepo(pg),
@@ -94,17 +129,26 @@ func Run(cfg *config.Config) {
 
 	// HTTP Server
 	handler := gin.New()
-	handler.Static("/v1/tours/uploads", "./uploads")
+	// Media on the local disk is served by the app, S3 serves its own.
+	if mediaHandler, ok := mediaStore.(http.Handler); ok {
+		handler.GET("/v1/tours/uploads/*key", gin.WrapH(http.StripPrefix("/v1/tours/uploads", mediaHandler)))
+	}
 	handler.MaxMultipartMemory = 200 << 20
 
 	// Casbin
 	csbn := casbin.InitCasbin()
 
 	// Payment Processor
-	paymentProcessor := payment.NewPaymentProcessor(10, tourismUseCase)
+	paymentProcessor := payment.NewPaymentProcessor(tourismUseCase, tourismUseCase, paymentGateway, cfg.Payment)
+	paymentProcessor.Start(ctx)
+
+	go tourismUseCase.RunSeatHoldSweeper(ctx, cfg.Purchase.SweepInterval)
+	go tourismUseCase.RunUploadSweeper(ctx, cfg.Upload.SweepInterval)
+	go tourismUseCase.RunMediaCollector(ctx, cfg.Media.GCInterval, cfg.Media.GCMinAge)
+	go tourismUseCase.RunScheduleGenerator(ctx, cfg.Schedule.GenerateInterval)
 
 	// New Router
-	v1.NewRouter(handler, l, service, csbn, paymentProcessor, cfg)
+	v1.NewRouter(handler, l, service, csbn, paymentProcessor, paymentGateway, cfg)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
 	// Waiting signal
@@ -4,6 +4,7 @@ import (
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"tourism-backend/internal/entity"
 	"tourism-backend/internal/usecase"
 	"tourism-backend/pkg/logger"
 	"tourism-backend/utils"
@@ -29,24 +30,32 @@ func newAdminRoutes(handler *gin.RouterGroup, t usecase.AdminInterface, l logger
 	}
 }
 
-// GetUsers retrieves a list of users.
-// @Summary Get all users
-// @Description Fetches a list of all registered users.
+// GetUsers retrieves a page of users.
+// @Summary Get users
+// @Description Fetches a page of registered users, newest first.
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} entity.User
+// @Param limit query int false "Users per page, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} entity.UserPage
+// @Failure 400 {object} map[string]string "Invalid sort or cursor"
 // @Failure 500 {object} map[string]string
 // @Router /v1/admin/users [get]
 // @Security Bearer
 func (r *adminRoutes) GetUsers(c *gin.Context) {
-	users, err := r.t.GetUsers()
+	var page entity.PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	users, err := r.t.GetUsers(&page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		c.JSON(pageErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, &users)
+	c.JSON(http.StatusOK, users)
 }
@@ -2,9 +2,7 @@
 package v1
 
 import (
-	"fmt"
 	"github.com/casbin/casbin/v2"
-	"github.com/stripe/stripe-go/v82"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"net/http"
@@ -26,7 +24,7 @@ import (
 // @version     1.0
 // @host        localhost:8080
 // @BasePath    /v1/tours
-func NewRouter(handler *gin.Engine, l logger.Interface, service *usecase.Service, csbn *casbin.Enforcer, paymentProcessor *payment.PaymentProcessor, cfg *config.Config) {
+func NewRouter(handler *gin.Engine, l logger.Interface, service *usecase.Service, csbn *casbin.Enforcer, paymentProcessor *payment.PaymentProcessor, paymentGateway payment.PaymentGateway, cfg *config.Config) {
 	// Options
 	handler.Use(gin.Logger())
 	handler.Use(gin.Recovery())
@@ -41,16 +39,10 @@ func NewRouter(handler *gin.Engine, l logger.Interface, service *usecase.Service
 	})
 	// Prometheus metrics
 	handler.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	stripe.Key = cfg.Stripe.SecretKey
-	if stripe.Key == "" {
-		fmt.Println("Please set STRIPE_SECRET_KEY environment variable")
-		return
-	}
-	fmt.Println("Stripe secret key: ", stripe.Key)
 	// Routers
 	h := handler.Group("/v1")
 	{
-		newTourismRoutes(h, service.TourUseCase, l, csbn, paymentProcessor)
+		newTourismRoutes(h, service.TourUseCase, l, csbn, paymentProcessor, paymentGateway, cfg.Stripe.WebhookSecret)
 		newAdminRoutes(h, service.AdminUseCase, l, csbn)
 	}
 
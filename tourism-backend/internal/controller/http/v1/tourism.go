@@ -1,50 +1,70 @@
 package v1
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/paymentintent"
 	"github.com/stripe/stripe-go/v82/webhook"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 	"tourism-backend/internal/entity"
 	"tourism-backend/internal/usecase"
+	"tourism-backend/pkg/ical"
+	"tourism-backend/pkg/imaging"
 	"tourism-backend/pkg/logger"
+	"tourism-backend/pkg/media"
 	"tourism-backend/pkg/payment"
+	"tourism-backend/pkg/ticket"
 	"tourism-backend/utils"
 )
 
+const _maxTrackFileSize = 10 << 20
+
 type tourismRoutes struct {
-	t usecase.TourismInterface
-	l logger.Interface
-	p *payment.PaymentProcessor
+	t                   usecase.TourismInterface
+	l                   logger.Interface
+	p                   *payment.PaymentProcessor
+	g                   payment.PaymentGateway
+	stripeWebhookSecret string
 }
 
-func newTourismRoutes(handler *gin.RouterGroup, t usecase.TourismInterface, l logger.Interface, csbn *casbin.Enforcer, payment *payment.PaymentProcessor) {
-	r := &tourismRoutes{t, l, payment}
+func newTourismRoutes(handler *gin.RouterGroup, t usecase.TourismInterface, l logger.Interface, csbn *casbin.Enforcer, paymentProcessor *payment.PaymentProcessor, paymentGateway payment.PaymentGateway, stripeWebhookSecret string) {
+	r := &tourismRoutes{t, l, paymentProcessor, paymentGateway, stripeWebhookSecret}
 	h := handler.Group("/tours")
 	{
 		user := h.Group("/users")
 		user.Use(utils.JWTAuthMiddleware())
 		{
 			user.GET("/me", r.GetMe)
+			user.GET("/me/purchases", r.GetMyPurchases)
 			user.POST("/like", r.LikeTour)
 			user.POST("/avatar", r.AddAvatar)
 			user.GET("/avatar", r.GetMyAvatar)
+			user.GET("/calendar", r.GetCalendarLinks)
+			user.POST("/calendar/reset", r.ResetCalendarLinks)
 			user.GET("/get-purchase-qr/:id", r.GetPurchaseQR)
+			user.POST("/purchases/:id/cancel", r.CancelPurchase)
+			user.POST("/waitlist", r.JoinWaitlist)
+			user.GET("/waitlist", r.GetMyWaitlist)
+			user.DELETE("/waitlist/:id", r.LeaveWaitlist)
+			user.POST("/waitlist/:id/claim", r.ClaimWaitlistOffer)
+			user.POST("/reviews", r.CreateReview)
 		}
 
 		usertracking := h.Group("/")
 		usertracking.Use(utils.JWTAuthMiddleware())
 		{
 			usertracking.GET("/tour-events/:id", r.GetTourEventByID)
+			usertracking.GET("/tour-events/:id/quote", r.QuoteTourEvent)
 		}
 
 		pay := h.Group("/payment")
@@ -53,27 +73,68 @@ func newTourismRoutes(handler *gin.RouterGroup, t usecase.TourismInterface, l lo
 			pay.POST("/", r.PayTourEvent)
 			pay.POST("/create-payment-intent", r.CreatePaymentIntent)
 			pay.POST("/confirm-payment", r.ConfirmPayment)
-			//pay.POST("/stripe-webhook", r.HandleStripeWebHook)
 		}
+		// Stripe calls the webhook without a bearer token, the payload signature is verified instead.
+		// Without a secret anyone could sign events, so the webhook only exists with one.
+		if stripeWebhookSecret != "" {
+			h.POST("/payment/stripe-webhook", r.HandleStripeWebhook)
+		}
+		// Calendar apps subscribe without a bearer token, the feed URLs hold a secret token instead.
+		h.GET("/calendar/:token/purchases.ics", r.GetPurchaseCalendar)
+		h.GET("/calendar/:token/tour-events.ics", r.GetTourEventCalendar)
+		h.GET("/tickets/public-key", r.GetTicketKey)
 
 		protected := h.Group("/provider")
 		protected.Use(utils.JWTAuthMiddleware(), utils.CasbinMiddleware(csbn))
 		{
 			protected.POST("/", r.CreateTour)
 			protected.POST("/tour-event", r.CreateTourEvent)
+			protected.PATCH("/tour-events/:id", r.UpdateTourEvent)
+			protected.POST("/tour-events/:id/cancel", r.CancelTourEvent)
+			protected.GET("/tour-events/:id/attendance", r.GetTourEventAttendance)
+			protected.POST("/:id/schedules", r.CreateTourSchedule)
+			protected.GET("/:id/schedules", r.GetTourSchedules)
+			protected.PATCH("/schedules/:id", r.UpdateTourSchedule)
+			protected.DELETE("/schedules/:id", r.DeleteTourSchedule)
 			protected.POST("/tour-category", r.CreateTourCategory)
 			protected.POST("/tour-location", r.CreateTourLocation)
-			protected.GET("/tour-location/:id", r.GetTourLocationByID)
+			protected.GET("/tour-location/:id", r.GetTourLocationByTourID)
 			protected.POST("/:id/", r.AddFilesToTourByTourID)
-			protected.PATCH("/", r.ChangeTour)
-			protected.POST("/:id/check", r.CheckPurchase)
+			protected.PATCH("/:id", r.UpdateTour)
+			protected.POST("/:id/archive", r.ArchiveTour)
+			protected.POST("/:id/restore", r.RestoreTour)
+			protected.DELETE("/:id", r.DeleteTour)
+			protected.POST("/tickets/check", r.CheckTicket)
+			protected.POST("/tickets/check-in", r.CheckInTicket)
+			protected.POST("/purchases/:id/cancel", r.CancelPurchaseByProvider)
+			protected.POST("/promo-codes", r.CreatePromoCode)
+			protected.GET("/promo-codes", r.GetMyPromoCodes)
+			protected.POST("/pricing-rules", r.CreatePricingRule)
+			protected.DELETE("/pricing-rules/:id", r.DeletePricingRule)
+			protected.POST("/reviews/:id/reply", r.ReplyToReview)
+			protected.PUT("/:id/waypoints", r.SetWaypoints)
+			protected.POST("/:id/track", r.ImportTourTrack)
+			protected.DELETE("/:id/track", r.DeleteTourTrack)
+			protected.POST("/:id/uploads", r.CreateUpload)
+			protected.HEAD("/uploads/:id", r.GetUploadOffset)
+			protected.GET("/uploads/:id", r.GetUpload)
+			protected.PATCH("/uploads/:id", r.AppendUpload)
+			protected.DELETE("/uploads/:id", r.CancelUpload)
+			protected.DELETE("/:id/media/:kind/:media_id", r.DeleteTourMedia)
+			protected.PUT("/:id/media/:kind/order", r.ReorderTourMedia)
+			protected.PUT("/:id/cover", r.SetTourCover)
 		}
 
 		h.GET("/v1/tours/uploads/:type/:filename", r.GetStaticFiles)
 		h.GET("/", r.GetTours)
 		h.GET("/:id", r.GetTourByID)
 		h.GET("/:id/tour-events", r.GetTourEventsByTourID)
+		h.GET("/:id/reviews", r.GetTourReviews)
+		h.GET("/:id/route", r.GetTourRouteMap)
 		h.GET("/categories", r.GetAllCategories)
+		h.GET("/search", r.SearchTours)
+		h.GET("/nearby", r.GetToursNearby)
+		h.GET("/in-bounds", r.GetToursInBounds)
 		h.GET("/tour-events", r.GetFilteredTourEvents)
 		h.GET("/tour-events/:id/weather", r.GetWeatherByTourEventID)
 	}
@@ -95,15 +156,25 @@ func (r *tourismRoutes) GetTourEventsByTourID(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// HandleStripeWebhook godoc
+// @Summary Stripe webhook
+// @Description Receives signed Stripe events and moves the matching purchase to Paid or Failed. The webhook only exists when STRIPE_WEBHOOK_SECRET is set.
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param Stripe-Signature header string true "Stripe signature"
+// @Success 200 {object} map[string]string "Event processed"
+// @Failure 400 {object} map[string]string "Invalid payload or signature"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/tours/payment/stripe-webhook [post]
 func (r *tourismRoutes) HandleStripeWebhook(c *gin.Context) {
-	const endpointSecret = "whsec_your_webhook_secret"
 	payload, err := c.GetRawData()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	event, err := webhook.ConstructEvent(payload, c.GetHeader("Stripe-Signature"), endpointSecret)
+	event, err := webhook.ConstructEvent(payload, c.GetHeader("Stripe-Signature"), r.stripeWebhookSecret)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -117,8 +188,14 @@ func (r *tourismRoutes) HandleStripeWebhook(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		// Handle successful payment
-		fmt.Printf("Payment succeeded for %d!\n", paymentIntent.Amount)
+		err = r.t.HandlePaymentSucceeded(paymentIntent.ID)
+		if err != nil && webhookShouldRetry(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Stripe webhook: payment %s succeeded but was not applied: %v\n", paymentIntent.ID, err)
+		}
 
 	case "payment_intent.payment_failed":
 		var paymentIntent stripe.PaymentIntent
@@ -127,25 +204,42 @@ func (r *tourismRoutes) HandleStripeWebhook(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		// Handle failed payment
-		fmt.Printf("Payment failed: %v\n", paymentIntent.LastPaymentError)
+		err = r.t.HandlePaymentFailed(paymentIntent.ID)
+		if err != nil && webhookShouldRetry(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Stripe webhook: payment %s failed but was not applied: %v\n", paymentIntent.ID, err)
+		}
 
 	default:
-		fmt.Printf("Unhandled event type: %s\n", event.Type)
+		log.Printf("Stripe webhook: unhandled event type: %s\n", event.Type)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
+// webhookShouldRetry reports whether Stripe should deliver the event again.
+// Events for unknown purchases or purchases that already left Processing never succeed on retry.
+func webhookShouldRetry(err error) bool {
+	return !errors.Is(err, usecase.ErrPurchaseNotFound) && !errors.Is(err, usecase.ErrInvalidPurchaseTransition)
+}
+
 // CreatePaymentIntent godoc
 // @Summary Create Payment Intent
-// @Description Creation of Payment Intent for Stripe
+// @Description Creation of Payment Intent for a processing purchase of the authenticated user. An optional promo code discounts the purchase before it is charged, a code that takes off the whole amount pays the purchase without a payment intent. Gateways that charge without the client, like the fake one, charge the purchase as soon as it is created, promo codes have to be given with the purchase then.
 // @Tags Payment
+// @Accept json
 // @Produce json
+// @Param request body entity.CreatePaymentIntentRequest true "Purchase to pay"
 // @Security BearerAuth
-// @Success 200 {object} entity.CreatePaymentIntentRequest "Purchase information"
+// @Success 200 {object} entity.CreatePaymentIntentResponse "Client secret of the payment intent"
 // @Failure 400 {object} map[string]string "Invalid json body"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Purchase belongs to another user"
+// @Failure 404 {object} map[string]string "Promo code not found"
+// @Failure 409 {object} map[string]string "Purchase is not waiting for payment or the promo code cannot be used"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /v1/tours/payment/create-payment-intent [post]
 // @Security Bearer
@@ -156,22 +250,42 @@ func (r *tourismRoutes) CreatePaymentIntent(c *gin.Context) {
 		return
 	}
 
-	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(req.Amount),
-		Currency: stripe.String(req.Currency),
-		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
-			Enabled: stripe.Bool(true),
-		},
+	userID := utils.GetUserIDFromContext(c)
+	var purchase *entity.Purchase
+	var err error
+	if req.PromoCode != "" {
+		purchase, err = r.t.ApplyPromoCode(userID, req.PurchaseID, req.PromoCode)
+	} else {
+		purchase, err = r.t.GetPayablePurchase(userID, req.PurchaseID)
+	}
+	if err != nil {
+		c.JSON(promoCodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if purchase.Status == entity.PurchaseStatusPaid {
+		// The promo code took off the whole amount, there is nothing to charge.
+		c.JSON(http.StatusOK, entity.CreatePaymentIntentResponse{Status: purchase.Status})
+		return
 	}
 
-	pi, err := paymentintent.New(params)
+	charge, err := r.g.Charge(payment.ChargeRequest{
+		PurchaseID: purchase.ID,
+		Amount:     payment.AmountFromPrice(purchase.Amount),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	err = r.t.AttachPaymentIntent(userID, purchase.ID, charge.ID)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, entity.CreatePaymentIntentResponse{
-		ClientSecret: pi.ClientSecret,
+		ClientSecret: charge.ClientSecret,
+		Status:       entity.PurchaseStatusProcessing,
 	})
 }
 
@@ -182,444 +296,1917 @@ func (r *tourismRoutes) ConfirmPayment(c *gin.Context) {
 		return
 	}
 
-	pi, err := paymentintent.Get(req.PaymentIntentID, nil)
+	charge, err := r.g.Status(req.PaymentIntentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status":         pi.Status,
-		"amount":         pi.Amount,
-		"currency":       pi.Currency,
-		"payment_method": pi.PaymentMethod.ID,
-		"receipt_email":  pi.ReceiptEmail,
+		"status":         charge.Status,
+		"amount":         charge.Amount,
+		"currency":       charge.Currency,
+		"payment_method": charge.PaymentMethod,
+		"receipt_email":  charge.ReceiptEmail,
 	})
 }
 
-// CheckPurchase godoc
-// @Summary Check QR Info
-// @Description Checks whether the authenticated provider is the owner of the tour event associated with the given purchase ID
+// CheckTicket godoc
+// @Summary Check a QR ticket
+// @Description Verifies the signed token scanned from a QR ticket and returns its seat, purchase and check-in, if any, without using the ticket. The ticket must belong to a tour event of the authenticated provider and its purchase must still be paid.
 // @Tags Provider
-// @Param id path string true "Purchase ID (UUID)"
+// @Accept json
 // @Produce json
+// @Param request body entity.CheckTicketDTO true "Scanned ticket token"
 // @Security BearerAuth
-// @Success 200 {object} entity.Purchase "Purchase information"
-// @Failure 400 {object} map[string]string "Invalid purchase ID"
+// @Success 200 {object} entity.CheckedTicketDTO "Ticket and purchase information"
+// @Failure 400 {object} map[string]string "Malformed or forged ticket"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "You are not the owner of this tour event"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/tours/provider/{id}/check [post]
+// @Failure 409 {object} map[string]string "The purchase was cancelled"
+// @Failure 410 {object} map[string]string "The ticket expired"
+// @Router /v1/tours/provider/tickets/check [post]
 // @Security Bearer
-func (r *tourismRoutes) CheckPurchase(c *gin.Context) {
-	userID := utils.GetUserIDFromContext(c)
-	purchaseIDStr := c.Param("id")
-	purchaseID, err := uuid.Parse(purchaseIDStr)
+func (r *tourismRoutes) CheckTicket(c *gin.Context) {
+	var dto entity.CheckTicketDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := r.t.CheckTicket(utils.GetUserIDFromContext(c), dto.Token)
 	if err != nil {
-		log.Println("CheckPurchase err:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing purchase ID"})
+		c.JSON(ticketErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CheckInTicket godoc
+// @Summary Check in a QR ticket
+// @Description Verifies the signed token scanned from a QR ticket like the check endpoint and marks the ticket as used by the authenticated provider. Only the owner of the tour can check its tickets in, there are no staff accounts. A ticket can be checked in once.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param request body entity.CheckTicketDTO true "Scanned ticket token"
+// @Security BearerAuth
+// @Success 200 {object} entity.CheckedTicketDTO "Checked-in ticket"
+// @Failure 400 {object} map[string]string "Malformed or forged ticket"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "You are not the owner of this tour event"
+// @Failure 409 {object} map[string]string "The ticket was already checked in or its purchase was cancelled"
+// @Failure 410 {object} map[string]string "The ticket expired"
+// @Router /v1/tours/provider/tickets/check-in [post]
+// @Security Bearer
+func (r *tourismRoutes) CheckInTicket(c *gin.Context) {
+	var dto entity.CheckTicketDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	result, err := r.t.CheckPurchase(userID, purchaseID)
+	result, err := r.t.CheckInTicket(utils.GetUserIDFromContext(c), dto.Token)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(ticketErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
-// GetPurchaseQR godoc
-// @Summary Get QR code for a purchase
-// @Description Returns a QR code for the specified purchase ID if the user has access
+// GetTicketKey godoc
+// @Summary Public key of QR tickets
+// @Description Returns the base64 Ed25519 public key that signs the ticket tokens, for scanners that check tickets offline. A token is the base64url encoding of version (1 byte), purchase ID (16), tour event ID (16), seat (2), expiry in Unix seconds (4) and the signature of those bytes (64).
+// @Tags Provider
+// @Produce json
+// @Success 200 {object} entity.TicketKeyDTO "Ticket public key"
+// @Router /v1/tours/tickets/public-key [get]
+func (r *tourismRoutes) GetTicketKey(c *gin.Context) {
+	c.JSON(http.StatusOK, r.t.GetTicketKey())
+}
+
+func ticketErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ticket.ErrInvalidTicket):
+		return http.StatusBadRequest
+	case errors.Is(err, ticket.ErrTicketExpired):
+		return http.StatusGone
+	case errors.Is(err, usecase.ErrTicketRevoked), errors.Is(err, usecase.ErrTicketAlreadyUsed):
+		return http.StatusConflict
+	case errors.Is(err, usecase.ErrPurchaseForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CancelPurchase godoc
+// @Summary Cancel a purchase
+// @Description Cancels a purchase of the authenticated user, returns the seat to the tour event and refunds the payment
 // @Tags Users
 // @Param id path string true "Purchase ID (UUID)"
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "QR code data"
+// @Success 200 {object} entity.Purchase "Cancelled purchase"
 // @Failure 400 {object} map[string]string "Invalid purchase ID"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Error getting purchase QR"
-// @Router /v1/tours/users/get-purchase-qr/{id}/ [get]
+// @Failure 403 {object} map[string]string "Purchase belongs to another user"
+// @Failure 409 {object} map[string]string "Purchase cannot be cancelled in its current status"
+// @Router /v1/tours/users/purchases/{id}/cancel [post]
 // @Security Bearer
-func (r *tourismRoutes) GetPurchaseQR(c *gin.Context) {
+func (r *tourismRoutes) CancelPurchase(c *gin.Context) {
 	userID := utils.GetUserIDFromContext(c)
-	purchaseIDStr := c.Param("id")
-	purchaseID, err := uuid.Parse(purchaseIDStr)
+	purchaseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		log.Println("GetPurchaseQR err:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing purchase ID"})
 		return
 	}
-	result, err := r.t.GetPurchaseQR(userID, purchaseID)
+
+	result, err := r.t.CancelPurchase(userID, purchaseID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting purchase QR"})
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
-// GetMyAvatar returns the avatar image info of the authenticated user.
-//
-// @Summary Get my avatar
-// @Description Returns avatar metadata or path for the authenticated user.
-// @Tags Users
+// CancelPurchaseByProvider godoc
+// @Summary Cancel a purchase of an owned tour event
+// @Description Cancels a purchase of a tour event owned by the authenticated provider and refunds the payment
+// @Tags Provider
+// @Param id path string true "Purchase ID (UUID)"
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "Avatar info retrieved successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/tours/users/avatar [get]
+// @Success 200 {object} entity.Purchase "Cancelled purchase"
+// @Failure 400 {object} map[string]string "Invalid purchase ID"
+// @Failure 403 {object} map[string]string "You are not the owner of this tour event"
+// @Failure 409 {object} map[string]string "Purchase cannot be cancelled in its current status"
+// @Router /v1/tours/provider/purchases/{id}/cancel [post]
 // @Security Bearer
-func (r *tourismRoutes) GetMyAvatar(c *gin.Context) {
+func (r *tourismRoutes) CancelPurchaseByProvider(c *gin.Context) {
 	userID := utils.GetUserIDFromContext(c)
-	result, err := r.t.GetMyAvatar(userID)
+	purchaseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user avatar"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing purchase ID"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": result})
-	return
+
+	result, err := r.t.CancelPurchaseByProvider(userID, purchaseID)
+	if err != nil {
+		c.JSON(purchaseErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
 }
 
-// AddAvatar uploads avatar image for a specific tour.
-//
-// @Summary Upload avatar for a user
-// @Description Allows authenticated users to upload avatar images.
+func purchaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrPurchaseForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidPurchaseTransition),
+		errors.Is(err, usecase.ErrTourArchived):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// JoinWaitlist godoc
+// @Summary Join the waitlist of a tour event
+// @Description Puts the authenticated user in line for a tour event without enough free places. Freed places are offered in the order users joined.
 // @Tags Users
-// @Accept multipart/form-data
+// @Accept json
 // @Produce json
-// @Param avatar formData file true "Avatar Image"
+// @Param request body entity.JoinWaitlistDTO true "Tour event and number of places"
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "Avatar uploaded successfully"
-// @Failure 400 {object} map[string]string "Invalid request format or missing required fields"
-// @Failure 403 {object} map[string]string "Unauthorized: You are not the owner of this tour"
-// @Failure 500 {object} map[string]string "Failed to save file or database error"
-// @Router /v1/tours/users/avatar/ [post]
+// @Success 201 {object} entity.WaitlistEntry "Waitlist entry"
+// @Failure 400 {object} map[string]string "Invalid request or more places than the tour event has"
+// @Failure 409 {object} map[string]string "Places are available or the user is already in line"
+// @Router /v1/tours/users/waitlist [post]
 // @Security Bearer
-func (r *tourismRoutes) AddAvatar(c *gin.Context) {
-	userID := utils.GetUserIDFromContext(c)
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 200<<20) // 200MB
-
-	if err := c.Request.ParseMultipartForm(200 << 20); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large"})
+func (r *tourismRoutes) JoinWaitlist(c *gin.Context) {
+	var dto entity.JoinWaitlistDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
-	avatar, exists := c.FormFile("avatar")
-	if exists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error uploading file"})
-		return
+	if dto.Quantity == 0 {
+		dto.Quantity = 1
 	}
-	filename := userID.String() + filepath.Ext(avatar.Filename)
-	filespath := "./uploads/avatars/" + filename
-	filespathToSave := "./v1/tours/uploads/avatars/" + filename
-
-	if err := c.SaveUploadedFile(avatar, filespath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	if dto.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
 		return
 	}
-	err := r.t.SaveMyAvatar(userID, filespathToSave)
+
+	userID := utils.GetUserIDFromContext(c)
+	entry, err := r.t.JoinWaitlist(userID, &dto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Avatar uploaded successfully", "result": filespathToSave})
-
+	c.JSON(http.StatusCreated, entry)
 }
 
-// LikeTour godoc
-// @Summary      Like a tour
-// @Description  Allows a user to like a specific tour
-// @Tags         Users
-// @Accept       json
-// @Produce      json
-// @Param        likeTourDTO  body      entity.LikeTourDTO  true  "Tour ID to like"
-// @Success      200          {object}  entity.UserFavorites
-// @Failure 500 {object} map[string]string
-// @Security     BearerAuth
-// @Router       /v1/tours/users/like [post]
-func (r *tourismRoutes) LikeTour(c *gin.Context) {
-	var likeTourDTO entity.LikeTourDTO
-
-	if err := c.ShouldBindJSON(&likeTourDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetMyWaitlist godoc
+// @Summary Get my waitlist entries
+// @Description Returns the waitlist entries of the authenticated user, newest first
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.WaitlistEntry "Waitlist entries"
+// @Failure 500 {object} map[string]string "Error getting waitlist"
+// @Router /v1/tours/users/waitlist [get]
+// @Security Bearer
+func (r *tourismRoutes) GetMyWaitlist(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	entries, err := r.t.GetMyWaitlist(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting waitlist"})
 		return
 	}
+	c.JSON(http.StatusOK, entries)
+}
 
+// LeaveWaitlist godoc
+// @Summary Leave a waitlist
+// @Description Takes the authenticated user out of line. Places of an open offer go to the next person.
+// @Tags Users
+// @Param id path string true "Waitlist entry ID (UUID)"
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Left the waitlist"
+// @Failure 400 {object} map[string]string "Invalid waitlist entry ID"
+// @Failure 403 {object} map[string]string "Waitlist entry belongs to another user"
+// @Router /v1/tours/users/waitlist/{id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) LeaveWaitlist(c *gin.Context) {
 	userID := utils.GetUserIDFromContext(c)
-	tourIDUUID, err := uuid.Parse(likeTourDTO.TourID)
-	result, err := r.t.LikeTour(userID, tourIDUUID)
+	entryID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing waitlist entry ID"})
+		return
 	}
-	c.JSON(http.StatusOK, result)
+
+	if err := r.t.LeaveWaitlist(userID, entryID); err != nil {
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist"})
 }
 
-// GetMe godoc
-// @Summary Get current user info
-// @Description Returns the information of the currently authenticated user
+// ClaimWaitlistOffer godoc
+// @Summary Claim a waitlist offer
+// @Description Buys the places offered to the authenticated user. For tour events with ticket types the body splits the offered places between the types. The purchase is paid like a regular one.
 // @Tags Users
+// @Accept json
+// @Param id path string true "Waitlist entry ID (UUID)"
+// @Param request body entity.ClaimWaitlistOfferDTO false "Ticket types of the offered places"
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} entity.User
-// @Failure 500 {object} map[string]string
-// @Router /v1/tours/users/me [get]
+// @Success 200 {object} map[string]interface{} "Processing purchase"
+// @Failure 400 {object} map[string]string "Invalid waitlist entry ID or ticket selection"
+// @Failure 403 {object} map[string]string "Waitlist entry belongs to another user"
+// @Failure 409 {object} map[string]string "The offer expired or was never made"
+// @Router /v1/tours/users/waitlist/{id}/claim [post]
 // @Security Bearer
-func (r *tourismRoutes) GetMe(c *gin.Context) {
+func (r *tourismRoutes) ClaimWaitlistOffer(c *gin.Context) {
 	userID := utils.GetUserIDFromContext(c)
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing waitlist entry ID"})
+		return
+	}
 
-	result, err := r.t.GetMe(userID)
+	var dto entity.ClaimWaitlistOfferDTO
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&dto); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	purchase, err := r.t.ClaimWaitlistOffer(userID, entryID, purchaseItems(dto.Tickets))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(waitlistErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, result)
+
+	r.p.Notify()
+
+	c.JSON(http.StatusOK, gin.H{"Purchase": purchase})
+}
+
+func waitlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrWaitlistForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrWaitlistNotNeeded),
+		errors.Is(err, usecase.ErrAlreadyWaitlisted),
+		errors.Is(err, usecase.ErrWaitlistOfferUnavailable):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
 }
 
-// ChangeTour godoc
-// @Summary Change an existing tour
-// @Description Updates the details of a tour. Only the tour owner (provider) can modify their tours.
+// CreatePromoCode godoc
+// @Summary Create a promo code
+// @Description Creates a percentage or fixed amount promo code. Providers must scope it to their own tour or tour event, admins may scope it to anything or nothing.
 // @Tags Provider
 // @Accept json
 // @Produce json
+// @Param request body entity.CreatePromoCodeDTO true "Promo code"
 // @Security BearerAuth
-// @Param ChangeTour body entity.Tour true "Tour object to update"
-// @Success 200 {object} entity.Tour "Updated tour info"
-// @Failure 400 {object} map[string]string "Bad request - invalid body"
-// @Failure 403 {object} map[string]string "Forbidden - not the owner"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /v1/tours/provider [patch]
+// @Success 201 {object} entity.PromoCode "Created promo code"
+// @Failure 400 {object} map[string]string "Invalid promo code"
+// @Failure 403 {object} map[string]string "Scope is not owned by the provider"
+// @Router /v1/tours/provider/promo-codes [post]
 // @Security Bearer
-func (r *tourismRoutes) ChangeTour(c *gin.Context) {
-	var changeTour entity.Tour
-
-	if err := c.ShouldBind(&changeTour); err != nil {
+func (r *tourismRoutes) CreatePromoCode(c *gin.Context) {
+	var dto entity.CreatePromoCodeDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if !r.t.CheckTourOwner(changeTour.ID, utils.GetUserIDFromContext(c)) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	userID := utils.GetUserIDFromContext(c)
+	promoCode, err := r.t.CreatePromoCode(userID, utils.GetRoleFromContext(c), &dto)
+	if err != nil {
+		c.JSON(promoCodeErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusCreated, promoCode)
+}
 
-	result, err := r.t.ChangeTour(&changeTour)
+// GetMyPromoCodes godoc
+// @Summary Get my promo codes
+// @Description Returns the promo codes created by the authenticated provider, newest first
+// @Tags Provider
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.PromoCode "Promo codes"
+// @Failure 500 {object} map[string]string "Error getting promo codes"
+// @Router /v1/tours/provider/promo-codes [get]
+// @Security Bearer
+func (r *tourismRoutes) GetMyPromoCodes(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	promoCodes, err := r.t.GetMyPromoCodes(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting promo codes"})
 		return
 	}
+	c.JSON(http.StatusOK, promoCodes)
+}
 
-	c.JSON(http.StatusOK, result)
-	return
+func promoCodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrPromoCodeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrPromoCodeForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrPromoCodeExpired),
+		errors.Is(err, usecase.ErrPromoCodeNotApplicable),
+		errors.Is(err, usecase.ErrPromoCodeUsedUp):
+		return http.StatusConflict
+	default:
+		return purchaseErrorStatus(err)
+	}
 }
 
-// AddFilesToTourByTourID uploads multiple panorama images for a specific tour.
-//
-// @Summary Upload panoramas for a tour
-// @Description Allows authenticated tour providers to upload multiple panorama images for a specific tour.
-// Only the owner of the tour can upload panoramas.
+// CreatePricingRule godoc
+// @Summary Add a pricing rule to a tour event
+// @Description Adds an early_bird, last_minute or group discount to a tour event of the authenticated provider. The discount is applied at purchase time.
 // @Tags Provider
-// @Accept multipart/form-data
+// @Accept json
 // @Produce json
-// @Param id path string true "Tour ID (UUID)"
-// @Param panoramas formData file true "Panorama images (can be multiple)"
+// @Param request body entity.CreatePricingRuleDTO true "Pricing rule"
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "Images uploaded successfully"
-// @Failure 400 {object} map[string]string "Invalid request format or missing required fields"
-// @Failure 403 {object} map[string]string "Unauthorized: You are not the owner of this tour"
-// @Failure 500 {object} map[string]string "Failed to save file or database error"
-// @Router /v1/tours/provider/{id}/ [post]
+// @Success 201 {object} entity.PricingRule "Created pricing rule"
+// @Failure 400 {object} map[string]string "Invalid pricing rule"
+// @Failure 403 {object} map[string]string "You are not the owner of this tour event"
+// @Router /v1/tours/provider/pricing-rules [post]
 // @Security Bearer
-func (r *tourismRoutes) AddFilesToTourByTourID(c *gin.Context) {
-	tourID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
+func (r *tourismRoutes) CreatePricingRule(c *gin.Context) {
+	var dto entity.CreatePricingRuleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	userID := utils.GetUserIDFromContext(c)
-
-	if !r.t.CheckTourOwner(tourID, userID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+	rule, err := r.t.CreatePricingRule(userID, &dto)
+	if err != nil {
+		c.JSON(pricingRuleErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusCreated, rule)
+}
 
-	// Parse multiple files
-	form, err := c.MultipartForm()
+// DeletePricingRule godoc
+// @Summary Delete a pricing rule
+// @Description Removes a pricing rule from a tour event of the authenticated provider
+// @Tags Provider
+// @Param id path string true "Pricing rule ID (UUID)"
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Pricing rule deleted"
+// @Failure 400 {object} map[string]string "Invalid pricing rule ID"
+// @Failure 403 {object} map[string]string "You are not the owner of this tour event"
+// @Router /v1/tours/provider/pricing-rules/{id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) DeletePricingRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing pricing rule ID"})
 		return
 	}
 
-	files := form.File["panoramas"]
-	if len(files) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No panoramas provided"})
+	userID := utils.GetUserIDFromContext(c)
+	if err := r.t.DeletePricingRule(userID, ruleID); err != nil {
+		c.JSON(pricingRuleErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pricing rule deleted"})
+}
 
-	var panoramas []*entity.Panorama
-
-	for _, file := range files {
-		filename := uuid.New().String() + filepath.Ext(file.Filename)
-		filespath := "./uploads/panoramas/" + filename
+func pricingRuleErrorStatus(err error) int {
+	if errors.Is(err, usecase.ErrPricingRuleForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
 
-		// Save the image file
-		if err := c.SaveUploadedFile(file, filespath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-			return
-		}
+// CreateReview godoc
+// @Summary Review a tour event
+// @Description Leaves a 1-5 star rating, a text review and optional photos for a tour event the authenticated user paid for and that already took place. Each tour event can be reviewed once.
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param tour_event_id formData string true "Tour Event ID"
+// @Param rating formData int true "Rating from 1 to 5"
+// @Param text formData string false "Review text"
+// @Param photos formData file false "Review photos (multiple allowed)"
+// @Security BearerAuth
+// @Success 201 {object} entity.Review "Created review"
+// @Failure 400 {object} map[string]string "Invalid review"
+// @Failure 403 {object} map[string]string "The user did not attend the tour event"
+// @Failure 409 {object} map[string]string "The tour event is already reviewed"
+// @Router /v1/tours/users/reviews [post]
+// @Security Bearer
+func (r *tourismRoutes) CreateReview(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 50<<20) // 50MB
 
-		panoramas = append(panoramas, &entity.Panorama{
-			PanoramaURL: "./v1/tours/uploads/panoramas/" + filename,
-			TourID:      tourID,
-		})
+	if err := c.Request.ParseMultipartForm(50 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large"})
+		return
 	}
 
-	// Save to database
-	result, err := r.t.AddFilesToTourByTourID(panoramas)
+	tourEventID, err := uuid.Parse(c.PostForm("tour_event_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save panoramas to DB"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour event ID"})
 		return
 	}
+	rating, err := strconv.Atoi(c.PostForm("rating"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be a number from 1 to 5"})
+		return
+	}
+
+	var photoFiles []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		photoFiles = form.File["photos"]
+	}
+
+	review := &entity.Review{
+		TourEventID: tourEventID,
+		UserID:      utils.GetUserIDFromContext(c),
+		Rating:      rating,
+		Text:        c.PostForm("text"),
+	}
+
+	createdReview, err := r.t.CreateReview(review, photoFiles)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createdReview)
+}
+
+// ReplyToReview godoc
+// @Summary Reply to a review
+// @Description Posts the public reply of the tour owner to a review, replacing an earlier reply
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID (UUID)"
+// @Param request body entity.ReplyToReviewDTO true "Reply"
+// @Security BearerAuth
+// @Success 200 {object} entity.Review "Review with the reply"
+// @Failure 400 {object} map[string]string "Invalid review ID or reply"
+// @Failure 403 {object} map[string]string "You are not the owner of the reviewed tour"
+// @Router /v1/tours/provider/reviews/{id}/reply [post]
+// @Security Bearer
+func (r *tourismRoutes) ReplyToReview(c *gin.Context) {
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing review ID"})
+		return
+	}
+	var dto entity.ReplyToReviewDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	review, err := r.t.ReplyToReview(userID, reviewID, dto.Reply)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// SearchTours godoc
+// @Summary Search tours
+// @Description Full-text search over tour names, descriptions, routes and category names. Results are ranked by relevance and the matched words are wrapped in <b></b> in name_highlight and snippet. The query supports quoted phrases, OR and -word.
+// @Tags Tours
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Results per page, 20 by default and at most 50"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {array} entity.TourSearchResult "Matching tours, best first"
+// @Failure 400 {object} map[string]string "Empty query or invalid paging"
+// @Failure 500 {object} map[string]string "Error searching tours"
+// @Router /v1/tours/search [get]
+func (r *tourismRoutes) SearchTours(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	results, err := r.t.SearchTours(c.Query("q"), limit, offset)
+	if errors.Is(err, usecase.ErrEmptySearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching tours"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetTourReviews godoc
+// @Summary Get reviews of a tour
+// @Description Returns a page of the reviews of a tour
+// @Tags Tours
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param page query int false "Page number, 1 by default"
+// @Param page_size query int false "Reviews per page, 10 by default and at most 50"
+// @Param sort query string false "newest (default), oldest, rating_desc or rating_asc"
+// @Success 200 {object} entity.ReviewPage "Page of reviews"
+// @Failure 400 {object} map[string]string "Invalid tour ID or paging"
+// @Failure 500 {object} map[string]string "Error getting reviews"
+// @Router /v1/tours/{id}/reviews [get]
+func (r *tourismRoutes) GetTourReviews(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page_size"})
+		return
+	}
+
+	result, err := r.t.GetTourReviews(&entity.ReviewFilter{
+		TourID:   tourID,
+		Page:     page,
+		PageSize: pageSize,
+		Sort:     c.Query("sort"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting reviews"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrReviewNotAllowed), errors.Is(err, usecase.ErrReviewReplyForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrAlreadyReviewed):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetPurchaseQR godoc
+// @Summary Get QR codes for a purchase
+// @Description Returns one QR code per seat of the specified purchase ID if the user has access
+// @Tags Users
+// @Param id path string true "Purchase ID (UUID)"
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.PurchaseQRDTO "QR code data per seat"
+// @Failure 400 {object} map[string]string "Invalid purchase ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Error getting purchase QR"
+// @Router /v1/tours/users/get-purchase-qr/{id}/ [get]
+// @Security Bearer
+func (r *tourismRoutes) GetPurchaseQR(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	purchaseIDStr := c.Param("id")
+	purchaseID, err := uuid.Parse(purchaseIDStr)
+	if err != nil {
+		log.Println("GetPurchaseQR err:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing purchase ID"})
+		return
+	}
+	result, err := r.t.GetPurchaseQR(userID, purchaseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting purchase QR"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMyAvatar returns the avatar image info of the authenticated user.
+//
+// @Summary Get my avatar
+// @Description Returns avatar metadata or path for the authenticated user.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Avatar info retrieved successfully"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/tours/users/avatar [get]
+// @Security Bearer
+func (r *tourismRoutes) GetMyAvatar(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	result, err := r.t.GetMyAvatar(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting user avatar"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+	return
+}
+
+// GetCalendarLinks godoc
+// @Summary Get my calendar feed URLs
+// @Description Returns the private iCalendar subscription URLs of the authenticated user: one with their paid purchases and, for providers, one with the events of their tours. The URLs work without a bearer token, so they should be kept secret.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.CalendarLinksDTO "Feed URLs"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/tours/users/calendar [get]
+// @Security Bearer
+func (r *tourismRoutes) GetCalendarLinks(c *gin.Context) {
+	token, err := r.t.GetCalendarToken(utils.GetUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, calendarLinks(c, token))
+}
+
+// ResetCalendarLinks godoc
+// @Summary Reset my calendar feed URLs
+// @Description Replaces the secret token of the calendar feed URLs of the authenticated user. Subscriptions to the old URLs stop working.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.CalendarLinksDTO "New feed URLs"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /v1/tours/users/calendar/reset [post]
+// @Security Bearer
+func (r *tourismRoutes) ResetCalendarLinks(c *gin.Context) {
+	token, err := r.t.ResetCalendarToken(utils.GetUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, calendarLinks(c, token))
+}
+
+// calendarLinks builds the feed URLs on the host the request was sent to.
+func calendarLinks(c *gin.Context, token string) *entity.CalendarLinksDTO {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+
+	base := scheme + "://" + host + "/v1/tours/calendar/" + token
+	return &entity.CalendarLinksDTO{
+		PurchasesURL:  base + "/purchases.ics",
+		TourEventsURL: base + "/tour-events.ics",
+	}
+}
+
+// GetPurchaseCalendar godoc
+// @Summary Calendar feed of my purchases
+// @Description iCalendar feed with the paid purchases of the user owning the token: date, place, tour name and meeting point. Meant to be subscribed to from Google or Apple Calendar, no bearer token needed.
+// @Tags Users
+// @Produce text/calendar
+// @Param token path string true "Calendar token"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} map[string]string "Unknown token"
+// @Router /v1/tours/calendar/{token}/purchases.ics [get]
+func (r *tourismRoutes) GetPurchaseCalendar(c *gin.Context) {
+	calendar, err := r.t.GetPurchaseCalendar(c.Param("token"))
+	writeCalendar(c, calendar, err, "purchases.ics")
+}
+
+// GetTourEventCalendar godoc
+// @Summary Calendar feed of my tour events
+// @Description iCalendar feed with the events of the tours of the provider owning the token, with their sold and free places. Meant to be subscribed to from Google or Apple Calendar, no bearer token needed.
+// @Tags Provider
+// @Produce text/calendar
+// @Param token path string true "Calendar token"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} map[string]string "Unknown token"
+// @Router /v1/tours/calendar/{token}/tour-events.ics [get]
+func (r *tourismRoutes) GetTourEventCalendar(c *gin.Context) {
+	calendar, err := r.t.GetTourEventCalendar(c.Param("token"))
+	writeCalendar(c, calendar, err, "tour-events.ics")
+}
+
+func writeCalendar(c *gin.Context, calendar *ical.Calendar, err error, filename string) {
+	if errors.Is(err, usecase.ErrCalendarNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	c.Data(http.StatusOK, ical.ContentType, buf.Bytes())
+}
+
+// AddAvatar uploads avatar image for a specific tour.
+//
+// @Summary Upload avatar for a user
+// @Description Allows authenticated users to upload avatar images.
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "Avatar Image"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Avatar uploaded successfully"
+// @Failure 400 {object} map[string]string "Invalid request format or missing required fields"
+// @Failure 403 {object} map[string]string "Unauthorized: You are not the owner of this tour"
+// @Failure 500 {object} map[string]string "Failed to save file or database error"
+// @Router /v1/tours/users/avatar/ [post]
+// @Security Bearer
+func (r *tourismRoutes) AddAvatar(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 200<<20) // 200MB
+
+	if err := c.Request.ParseMultipartForm(200 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large"})
+		return
+	}
+	avatar, exists := c.FormFile("avatar")
+	if exists != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error uploading file"})
+		return
+	}
+	result, err := r.t.SaveMyAvatar(userID, avatar)
+	if err != nil {
+		if isInvalidMedia(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Avatar uploaded successfully", "result": result})
+
+}
+
+// LikeTour godoc
+// @Summary      Like a tour
+// @Description  Allows a user to like a specific tour
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        likeTourDTO  body      entity.LikeTourDTO  true  "Tour ID to like"
+// @Success      200          {object}  entity.UserFavorites
+// @Failure 500 {object} map[string]string
+// @Security     BearerAuth
+// @Router       /v1/tours/users/like [post]
+func (r *tourismRoutes) LikeTour(c *gin.Context) {
+	var likeTourDTO entity.LikeTourDTO
+
+	if err := c.ShouldBindJSON(&likeTourDTO); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	tourIDUUID, err := uuid.Parse(likeTourDTO.TourID)
+	result, err := r.t.LikeTour(userID, tourIDUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMe godoc
+// @Summary Get current user info
+// @Description Returns the information of the currently authenticated user
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.User
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/users/me [get]
+// @Security Bearer
+func (r *tourismRoutes) GetMe(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+
+	result, err := r.t.GetMe(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMyPurchases godoc
+// @Summary Get my purchases
+// @Description Returns a page of the purchases of the authenticated user. GET /users/me only includes the latest ones.
+// @Tags Users
+// @Produce json
+// @Param sort query string false "newest (default), date or price"
+// @Param limit query int false "Purchases per page, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} entity.PurchasePage
+// @Failure 400 {object} map[string]string "Invalid sort or cursor"
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/users/me/purchases [get]
+// @Security Bearer
+func (r *tourismRoutes) GetMyPurchases(c *gin.Context) {
+	var page entity.PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purchases, err := r.t.GetMyPurchases(utils.GetUserIDFromContext(c), &page)
+	if err != nil {
+		c.JSON(pageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, purchases)
+}
+
+// UpdateTour godoc
+// @Summary Update a tour
+// @Description Changes the name, description, route, Telegram chat link or AirPano link of a tour owned by the provider. Fields left out of the request keep their value.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param request body entity.UpdateTourDTO true "Fields to change"
+// @Security BearerAuth
+// @Success 200 {object} entity.Tour "Updated tour"
+// @Failure 400 {object} map[string]string "Invalid tour ID or body"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 404 {object} map[string]string "Tour not found"
+// @Router /v1/tours/provider/{id} [patch]
+// @Security Bearer
+func (r *tourismRoutes) UpdateTour(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	var dto entity.UpdateTourDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tour, err := r.t.UpdateTour(tourID, &dto)
+	if err != nil {
+		c.JSON(tourErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tour)
+}
+
+// ArchiveTour godoc
+// @Summary Archive a tour
+// @Description Hides a tour owned by the provider from the listings, search and map and stops the sales of its events. Its events, purchases and reviews are kept.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Tour archived"
+// @Failure 400 {object} map[string]string "Invalid tour ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 404 {object} map[string]string "Tour not found"
+// @Router /v1/tours/provider/{id}/archive [post]
+// @Security Bearer
+func (r *tourismRoutes) ArchiveTour(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	if err := r.t.ArchiveTour(tourID); err != nil {
+		c.JSON(tourErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tour archived"})
+}
+
+// RestoreTour godoc
+// @Summary Restore an archived tour
+// @Description Lists an archived tour owned by the provider again.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Tour restored"
+// @Failure 400 {object} map[string]string "Invalid tour ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 404 {object} map[string]string "Tour not found"
+// @Router /v1/tours/provider/{id}/restore [post]
+// @Security Bearer
+func (r *tourismRoutes) RestoreTour(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	if err := r.t.RestoreTour(tourID); err != nil {
+		c.JSON(tourErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tour restored"})
+}
+
+// DeleteTour godoc
+// @Summary Delete a tour
+// @Description Deletes a tour owned by the provider with its events. It is refused while upcoming events have paid or pending purchases, unless refund is true: then the upcoming events are cancelled one by one, refunding their purchases and ending their waitlists, before the tour is deleted. If a refund fails the events after it are left on sale and the request can be repeated to retry it.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param refund query bool false "Cancel and refund the purchases of upcoming events first"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Tour deleted"
+// @Failure 400 {object} map[string]string "Invalid tour ID or refund flag, or a refund failed"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 409 {object} map[string]string "Upcoming events have purchases"
+// @Router /v1/tours/provider/{id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) DeleteTour(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	refund := false
+	if value := c.Query("refund"); value != "" {
+		if refund, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refund must be true or false"})
+			return
+		}
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	if err := r.t.DeleteTour(tourID, refund); err != nil {
+		c.JSON(tourErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tour deleted"})
+}
+
+func tourErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrTourNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrTourHasPurchases):
+		return http.StatusConflict
+	default:
+		return purchaseErrorStatus(err)
+	}
+}
+
+// AddFilesToTourByTourID uploads multiple panorama images for a specific tour.
+//
+// @Summary Upload panoramas for a tour
+// @Description Allows authenticated tour providers to upload multiple panorama images for a specific tour.
+// Only the owner of the tour can upload panoramas.
+// @Tags Provider
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Tour ID (UUID)"
+// @Param panoramas formData file true "Panorama images (can be multiple)"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Images uploaded successfully"
+// @Failure 400 {object} map[string]string "Invalid request format or missing required fields"
+// @Failure 403 {object} map[string]string "Unauthorized: You are not the owner of this tour"
+// @Failure 500 {object} map[string]string "Failed to save file or database error"
+// @Router /v1/tours/provider/{id}/ [post]
+// @Security Bearer
+func (r *tourismRoutes) AddFilesToTourByTourID(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+
+	if !r.t.CheckTourOwner(tourID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	// Parse multiple files
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data"})
+		return
+	}
+
+	files := form.File["panoramas"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No panoramas provided"})
+		return
+	}
+
+	// Save the files and the panoramas
+	result, err := r.t.AddFilesToTourByTourID(tourID, files)
+	if err != nil {
+		if isInvalidMedia(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save panoramas to DB"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Images uploaded successfully", "result": result})
+}
+
+// GetTourEventByID retrieves details of a specific tour event.
+// @Summary Get tour event by ID
+// @Description Fetches detailed information of a tour event by its ID
+// @Tags Tour Events
+// @Produce json
+// @Param id path string true "Tour Event ID"
+// @Success 200 {object} entity.TourEvent
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/tour-events/{id} [get]
+func (r *tourismRoutes) GetTourEventByID(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
+
+	tourEventID, err := uuid.Parse(c.Param("id"))
+
+	tourEvent, err := r.t.GetTourEventByID(tourEventID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	go r.t.TrackUserAction(userID, tourEvent.TourID)
+
+	c.JSON(http.StatusOK, tourEvent)
+}
+
+// QuoteTourEvent godoc
+// @Summary Quote the price of a tour event
+// @Description Explains what the requested places cost right now: ticket types and pricing rules of the tour event and an optional promo code, line by line.
+// @Tags Tour Events
+// @Produce json
+// @Param id path string true "Tour Event ID"
+// @Param quantity query int false "Number of places of a tour event without ticket types, 1 by default"
+// @Param ticket query []string false "Ticket type and quantity as <ticket_type_id>:<quantity>, repeated per ticket type" collectionFormat(multi)
+// @Param promo_code query string false "Promo code to apply"
+// @Security BearerAuth
+// @Success 200 {object} entity.PriceQuote "Price breakdown"
+// @Failure 400 {object} map[string]string "Invalid tour event ID or quantity"
+// @Failure 404 {object} map[string]string "Promo code not found"
+// @Failure 409 {object} map[string]string "Promo code cannot be used"
+// @Router /v1/tours/tour-events/{id}/quote [get]
+// @Security Bearer
+func (r *tourismRoutes) QuoteTourEvent(c *gin.Context) {
+	tourEventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour event ID"})
+		return
+	}
+	quantity, err := strconv.Atoi(c.DefaultQuery("quantity", "1"))
+	if err != nil || quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
+		return
+	}
+
+	var items []entity.PurchaseItem
+	for _, ticket := range c.QueryArray("ticket") {
+		ticketTypeID, ticketQuantity, ok := strings.Cut(ticket, ":")
+		item := entity.PurchaseItem{}
+		if item.TicketTypeID, err = uuid.Parse(ticketTypeID); err != nil || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket must be <ticket_type_id>:<quantity>"})
+			return
+		}
+		if item.Quantity, err = strconv.Atoi(ticketQuantity); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket must be <ticket_type_id>:<quantity>"})
+			return
+		}
+		items = append(items, item)
+	}
+
+	userID := utils.GetUserIDFromContext(c)
+	purchase := &entity.Purchase{
+		TourEventID: tourEventID,
+		UserID:      userID,
+		Quantity:    quantity,
+		Items:       items,
+	}
+	quote, err := r.t.QuotePrice(userID, purchase, c.Query("promo_code"))
+	if err != nil {
+		c.JSON(promoCodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// GetWeatherByTourEventID retrieves weather information for a specific tour event.
+// @Summary Get weather by tour event ID
+// @Description Fetches weather information related to a tour event
+// @Tags Weather
+// @Produce json
+// @Param id path string true "Tour Event ID"
+// @Success 200 {object} entity.WeatherInfo
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/tour-events/{id}/weather [get]
+func (r *tourismRoutes) GetWeatherByTourEventID(c *gin.Context) {
+	tourEventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+	weatherInfo, err := r.t.GetWeatherByTourEventID(tourEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	c.JSON(http.StatusOK, weatherInfo)
+}
+
+// GetFilteredTourEvents retrieves filtered tour events based on query parameters.
+// @Summary Get filtered tour events
+// @Description Fetches a page of open tour events filtered by criteria. Pass next_cursor of a page as cursor to get the following one.
+// @Tags Tour Events
+// @Produce json
+// @Param start_date query string false "Start Date (YYYY-MM-DD)"
+// @Param end_date query string false "End Date (YYYY-MM-DD)"
+// @Param min_price query number false "Minimum Price"
+// @Param max_price query number false "Maximum Price"
+// @Param sort query string false "date (default), price, popularity, rating or newest"
+// @Param limit query int false "Tour events per page, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} entity.TourEventPage
+// @Failure 400 {object} map[string]string "Invalid filter, sort or cursor"
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/tour-events [get]
+func (r *tourismRoutes) GetFilteredTourEvents(c *gin.Context) {
+	filter, err := tourEventFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var page entity.PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tourEvents, err := r.t.GetFilteredTourEvents(filter, &page)
+	if err != nil {
+		c.JSON(pageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tourEvents)
+}
+
+// tourEventFilterFromQuery reads the category_ids, start_date, end_date,
+// min_price and max_price query parameters.
+func tourEventFilterFromQuery(c *gin.Context) (*entity.TourEventFilter, error) {
+	var filter entity.TourEventFilter
+
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		return nil, err
+	}
+	categoryIDs := c.QueryArray("category_ids")
+
+	for _, id := range categoryIDs {
+		parsedID, err := uuid.Parse(id)
+		if err == nil {
+			filter.CategoryIDs = append(filter.CategoryIDs, parsedID)
+		}
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Images uploaded successfully", "result": result})
+	startDateStr := c.Query("start_date")
+	endDateStr := c.Query("end_date")
+	if startDateStr != "" {
+		startDate, err1 := time.Parse("2006-01-02", startDateStr)
+		if err1 == nil {
+			filter.StartDate = startDate
+		}
+	}
+	if endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err == nil {
+			filter.EndDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		}
+	}
+	if minPrice := c.Query("min_price"); minPrice != "" {
+		filter.MinPrice = utils.ParseFloat(minPrice)
+	}
+	if maxPrice := c.Query("max_price"); maxPrice != "" {
+		filter.MaxPrice = utils.ParseFloat(maxPrice)
+	}
+	return &filter, nil
 }
 
-// GetTourEventByID retrieves details of a specific tour event.
-// @Summary Get tour event by ID
-// @Description Fetches detailed information of a tour event by its ID
-// @Tags Tour Events
+// GetToursNearby godoc
+// @Summary Find tours near a point
+// @Description Returns the tours whose location is within radius_km of a point, nearest first. The tour event filters keep only tours with a matching open event.
+// @Tags Tours
 // @Produce json
-// @Param id path string true "Tour Event ID"
-// @Success 200 {object} entity.TourEvent
+// @Param lat query number true "Latitude"
+// @Param lng query number true "Longitude"
+// @Param radius_km query number false "Search radius in km, 50 by default and at most 1000"
+// @Param category_ids query []string false "Category IDs" collectionFormat(multi)
+// @Param start_date query string false "Start Date (YYYY-MM-DD)"
+// @Param end_date query string false "End Date (YYYY-MM-DD)"
+// @Param min_price query number false "Minimum Price"
+// @Param max_price query number false "Maximum Price"
+// @Param limit query int false "Results per page, 20 by default and at most 100"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {array} entity.TourNearby "Tours with their distance, nearest first"
+// @Failure 400 {object} map[string]string "Invalid location, radius or filter"
 // @Failure 500 {object} map[string]string
-// @Router /v1/tours/tour-events/{id} [get]
-func (r *tourismRoutes) GetTourEventByID(c *gin.Context) {
-	userID := utils.GetUserIDFromContext(c)
+// @Router /v1/tours/nearby [get]
+func (r *tourismRoutes) GetToursNearby(c *gin.Context) {
+	query, err := geoQueryFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Origin == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng are required"})
+		return
+	}
+	if radius := c.Query("radius_km"); radius != "" {
+		if query.RadiusKm, err = strconv.ParseFloat(radius, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid radius_km"})
+			return
+		}
+	}
+	r.getToursNearby(c, query)
+}
 
-	tourEventID, err := uuid.Parse(c.Param("id"))
+// GetToursInBounds godoc
+// @Summary Find tours inside a map viewport
+// @Description Returns the tours whose location is inside a bounding box, nearest to lat and lng (the center of the box by default) first. west may be greater than east when the box crosses the antimeridian. The tour event filters keep only tours with a matching open event.
+// @Tags Tours
+// @Produce json
+// @Param north query number true "Northern latitude"
+// @Param south query number true "Southern latitude"
+// @Param east query number true "Eastern longitude"
+// @Param west query number true "Western longitude"
+// @Param lat query number false "Latitude to sort by distance from"
+// @Param lng query number false "Longitude to sort by distance from"
+// @Param category_ids query []string false "Category IDs" collectionFormat(multi)
+// @Param start_date query string false "Start Date (YYYY-MM-DD)"
+// @Param end_date query string false "End Date (YYYY-MM-DD)"
+// @Param min_price query number false "Minimum Price"
+// @Param max_price query number false "Maximum Price"
+// @Param limit query int false "Results per page, 20 by default and at most 100"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {array} entity.TourNearby "Tours with their distance, nearest first"
+// @Failure 400 {object} map[string]string "Invalid bounds or filter"
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/in-bounds [get]
+func (r *tourismRoutes) GetToursInBounds(c *gin.Context) {
+	query, err := geoQueryFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	tourEvent, err := r.t.GetTourEventByID(tourEventID)
+	var bounds [4]float64
+	for i, name := range []string{"north", "south", "east", "west"} {
+		if bounds[i], err = strconv.ParseFloat(c.Query(name), 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "north, south, east and west are required"})
+			return
+		}
+	}
+	query.Bounds = &entity.GeoBounds{North: bounds[0], South: bounds[1], East: bounds[2], West: bounds[3]}
+	r.getToursNearby(c, query)
+}
+
+// geoQueryFromQuery reads the lat, lng, tour event filter and paging query
+// parameters shared by the location searches.
+func geoQueryFromQuery(c *gin.Context) (*entity.GeoQuery, error) {
+	filter, err := tourEventFilterFromQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	query := &entity.GeoQuery{Filter: filter}
+
+	if lat, lng := c.Query("lat"), c.Query("lng"); lat != "" || lng != "" {
+		latitude, err := strconv.ParseFloat(lat, 64)
+		if err != nil {
+			return nil, errors.New("invalid lat")
+		}
+		longitude, err := strconv.ParseFloat(lng, 64)
+		if err != nil {
+			return nil, errors.New("invalid lng")
+		}
+		query.Origin = &entity.GeoPoint{Latitude: latitude, Longitude: longitude}
+	}
+	if query.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		return nil, errors.New("invalid limit")
+	}
+	if query.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+		return nil, errors.New("invalid offset")
+	}
+	return query, nil
+}
+
+func (r *tourismRoutes) getToursNearby(c *gin.Context, query *entity.GeoQuery) {
+	results, err := r.t.GetToursNearby(query)
+	if errors.Is(err, usecase.ErrInvalidGeoQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching tours"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetTourLocationByTourID retrieves the location of a tour.
+// @Summary Get tour location by tour ID
+// @Description Fetches the location of a tour owned by the provider.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Security BearerAuth
+// @Success 200 {object} entity.TourLocation "Tour location details"
+// @Router /v1/tours/provider/tour-location/{id} [get]
+// @Security Bearer
+func (r *tourismRoutes) GetTourLocationByTourID(c *gin.Context) {
+	userID := utils.GetUserIDFromContext(c)
 
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+	tourLocation, err := r.t.GetTourLocationByTourID(tourID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	go r.t.TrackUserAction(userID, tourEvent.TourID)
 
-	c.JSON(http.StatusOK, tourEvent)
+	c.JSON(http.StatusOK, tourLocation)
+
 }
 
-// GetWeatherByTourEventID retrieves weather information for a specific tour event.
-// @Summary Get weather by tour event ID
-// @Description Fetches weather information related to a tour event
-// @Tags Weather
+// SetWaypoints godoc
+// @Summary Set the route of a tour
+// @Description Replaces the waypoints of a tour owned by the provider. The first waypoint is the meeting point, the last one the end point and the ones in between are named stops. The tour location moves to the meeting point.
+// @Tags Provider
+// @Accept json
 // @Produce json
-// @Param id path string true "Tour Event ID"
-// @Success 200 {object} entity.WeatherInfo
-// @Failure 500 {object} map[string]string
-// @Router /v1/tours/tour-events/{id}/weather [get]
-func (r *tourismRoutes) GetWeatherByTourEventID(c *gin.Context) {
-	tourEventID, err := uuid.Parse(c.Param("id"))
+// @Param id path string true "Tour ID"
+// @Param request body entity.SetWaypointsDTO true "Waypoints in route order"
+// @Security BearerAuth
+// @Success 200 {array} entity.Waypoint "Saved waypoints"
+// @Failure 400 {object} map[string]string "Invalid tour ID or waypoints"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/waypoints [put]
+// @Security Bearer
+func (r *tourismRoutes) SetWaypoints(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	var dto entity.SetWaypointsDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	weatherInfo, err := r.t.GetWeatherByTourEventID(tourEventID)
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	waypoints, err := r.t.SetWaypoints(tourID, dto.Waypoints)
+	if err != nil {
+		c.JSON(routeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, waypoints)
+}
+
+// ImportTourTrack godoc
+// @Summary Upload the track of a tour
+// @Description Replaces the track of a tour owned by the provider with the lines of a GPX (.gpx) or GeoJSON (.geojson, .json) file.
+// @Tags Provider
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param file formData file true "GPX or GeoJSON file, at most 10 MB"
+// @Security BearerAuth
+// @Success 201 {object} entity.TourTrack "Imported track"
+// @Failure 400 {object} map[string]string "Invalid tour ID or track file"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/track [post]
+// @Security Bearer
+func (r *tourismRoutes) ImportTourTrack(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No track file provided"})
+		return
+	}
+	if file.Size > _maxTrackFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The track file is larger than 10 MB"})
+		return
+	}
+	data, err := readFormFile(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	track, err := r.t.ImportTourTrack(tourID, file.Filename, data)
+	if err != nil {
+		c.JSON(routeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, track)
+}
+
+// DeleteTourTrack godoc
+// @Summary Delete the track of a tour
+// @Description Removes the uploaded track, the route map falls back to straight lines between the waypoints.
+// @Tags Provider
+// @Param id path string true "Tour ID"
+// @Security BearerAuth
+// @Success 204 "Track deleted"
+// @Failure 400 {object} map[string]string "Invalid tour ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/track [delete]
+// @Security Bearer
+func (r *tourismRoutes) DeleteTourTrack(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	if err := r.t.DeleteTourTrack(tourID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetTourRouteMap godoc
+// @Summary Get the route map of a tour
+// @Description Returns the route of a tour as a GeoJSON FeatureCollection: the track line (or straight lines between the waypoints without a track) and a point per waypoint. distance_km is the length of the route.
+// @Tags Tours
+// @Produce application/geo+json
+// @Param id path string true "Tour ID"
+// @Success 200 {object} entity.FeatureCollection
+// @Failure 400 {object} map[string]string "Invalid tour ID"
+// @Failure 500 {object} map[string]string
+// @Router /v1/tours/{id}/route [get]
+func (r *tourismRoutes) GetTourRouteMap(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+
+	routeMap, err := r.t.GetTourRouteMap(tourID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, routeMap)
+}
+
+func routeErrorStatus(err error) int {
+	if errors.Is(err, usecase.ErrInvalidWaypoints) || errors.Is(err, usecase.ErrInvalidTrack) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// CreateUpload godoc
+// @Summary Start a resumable upload
+// @Description Opens an upload session for a video or panorama of a tour owned by the provider. The file is then sent in chunks with PATCH to the Location of the session and attached to the tour when its last byte arrives. An optional checksum is the SHA-256 of the whole file in hex, verified before the file is attached.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param request body entity.CreateUploadDTO true "Kind (video or panorama), file name, size in bytes and checksum"
+// @Security BearerAuth
+// @Success 201 {object} entity.UploadSession "Upload session"
+// @Failure 400 {object} map[string]string "Invalid tour ID or upload"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/uploads [post]
+// @Security Bearer
+func (r *tourismRoutes) CreateUpload(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	userID := utils.GetUserIDFromContext(c)
+	if !r.t.CheckTourOwner(tourID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	var dto entity.CreateUploadDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := r.t.CreateUpload(userID, tourID, &dto)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Location", "/v1/tours/provider/uploads/"+session.ID.String())
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUploadOffset godoc
+// @Summary Get the offset of an upload
+// @Description Returns the number of bytes received so far in the Upload-Offset header and the file size in Upload-Length, to resume an interrupted upload from there.
+// @Tags Provider
+// @Param id path string true "Upload ID"
+// @Security BearerAuth
+// @Success 200 "Upload-Offset and Upload-Length headers"
+// @Failure 403 "You are not the owner of the upload"
+// @Failure 404 "Upload not found"
+// @Router /v1/tours/provider/uploads/{id} [head]
+// @Security Bearer
+func (r *tourismRoutes) GetUploadOffset(c *gin.Context) {
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	session, err := r.t.GetUpload(utils.GetUserIDFromContext(c), uploadID)
+	if err != nil {
+		c.Status(uploadErrorStatus(err))
+		return
+	}
+	setUploadHeaders(c, session)
+	c.Status(http.StatusOK)
+}
+
+// GetUpload godoc
+// @Summary Get an upload
+// @Description Returns an upload session with its offset and status, and the attached media once it is completed.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Security BearerAuth
+// @Success 200 {object} entity.UploadSession "Upload session"
+// @Failure 403 {object} map[string]string "You are not the owner of the upload"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Router /v1/tours/provider/uploads/{id} [get]
+// @Security Bearer
+func (r *tourismRoutes) GetUpload(c *gin.Context) {
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing upload ID"})
+		return
+	}
+	session, err := r.t.GetUpload(utils.GetUserIDFromContext(c), uploadID)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusOK, session)
+}
+
+// AppendUpload godoc
+// @Summary Send a chunk of an upload
+// @Description Appends the request body to the upload. Upload-Offset must be the offset of the upload, Upload-Checksum optionally holds "sha256 <base64 digest>" of the chunk. The last chunk completes the upload and attaches the file to the tour, an empty chunk at the end of the file retries a failed completion.
+// @Tags Provider
+// @Accept application/offset+octet-stream
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Param Upload-Offset header int true "Offset of the chunk"
+// @Param Upload-Checksum header string false "Checksum of the chunk"
+// @Security BearerAuth
+// @Success 200 {object} entity.UploadSession "Upload session after the chunk"
+// @Failure 400 {object} map[string]string "Invalid chunk"
+// @Failure 403 {object} map[string]string "You are not the owner of the upload"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Failure 409 {object} map[string]string "The offset does not match the upload"
+// @Failure 410 {object} map[string]string "The upload is completed, failed or expired"
+// @Failure 422 {object} map[string]string "Checksum mismatch"
+// @Router /v1/tours/provider/uploads/{id} [patch]
+// @Security Bearer
+func (r *tourismRoutes) AppendUpload(c *gin.Context) {
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing upload ID"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be the offset of the chunk"})
+		return
+	}
+
+	session, err := r.t.AppendUpload(utils.GetUserIDFromContext(c), uploadID, offset, c.GetHeader("Upload-Checksum"), c.Request.Body)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
-	c.JSON(http.StatusOK, weatherInfo)
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusOK, session)
 }
 
-// GetFilteredTourEvents retrieves filtered tour events based on query parameters.
-// @Summary Get filtered tour events
-// @Description Fetches a list of tour events filtered by criteria
-// @Tags Tour Events
+// CancelUpload godoc
+// @Summary Cancel an upload
+// @Description Drops an upload session with the chunks received so far.
+// @Tags Provider
 // @Produce json
-// @Param start_date query string false "Start Date (YYYY-MM-DD)"
-// @Param end_date query string false "End Date (YYYY-MM-DD)"
-// @Param min_price query number false "Minimum Price"
-// @Param max_price query number false "Maximum Price"
-// @Success 200 {array} entity.TourEvent
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /v1/tours/tour-events [get]
-func (r *tourismRoutes) GetFilteredTourEvents(c *gin.Context) {
-	var filter entity.TourEventFilter
-
-	if err := c.ShouldBindQuery(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// @Param id path string true "Upload ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Upload cancelled"
+// @Failure 403 {object} map[string]string "You are not the owner of the upload"
+// @Failure 404 {object} map[string]string "Upload not found"
+// @Router /v1/tours/provider/uploads/{id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) CancelUpload(c *gin.Context) {
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing upload ID"})
 		return
 	}
-	categoryIDs := c.QueryArray("category_ids")
-
-	for _, id := range categoryIDs {
-		parsedID, err := uuid.Parse(id)
-		if err == nil {
-			filter.CategoryIDs = append(filter.CategoryIDs, parsedID)
-		}
+	if err := r.t.CancelUpload(utils.GetUserIDFromContext(c), uploadID); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
 
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
-	if startDateStr != "" {
-		startDate, err1 := time.Parse("2006-01-02", startDateStr)
-		if err1 == nil {
-			filter.StartDate = startDate
-		}
+// DeleteTourMedia godoc
+// @Summary Delete a media of a tour
+// @Description Deletes an image, video or panorama of a tour owned by the provider together with its files.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param kind path string true "Media kind: images, videos or panoramas"
+// @Param media_id path string true "Media ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Media deleted"
+// @Failure 400 {object} map[string]string "Invalid tour ID, media kind or media ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 404 {object} map[string]string "The tour has no such media"
+// @Router /v1/tours/provider/{id}/media/{kind}/{media_id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) DeleteTourMedia(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
 	}
-	if endDateStr != "" {
-		endDate, err := time.Parse("2006-01-02", endDateStr)
-		if err == nil {
-			filter.EndDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-		}
+	mediaID, err := uuid.Parse(c.Param("media_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing media ID"})
+		return
 	}
-	if minPrice := c.Query("min_price"); minPrice != "" {
-		filter.MinPrice = utils.ParseFloat(minPrice)
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
 	}
-	if maxPrice := c.Query("max_price"); maxPrice != "" {
-		filter.MaxPrice = utils.ParseFloat(maxPrice)
+
+	if err := r.t.DeleteTourMedia(tourID, c.Param("kind"), mediaID); err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted"})
+}
 
-	tourEvents, err := r.t.GetFilteredTourEvents(&filter)
+// ReorderTourMedia godoc
+// @Summary Reorder the media of a tour
+// @Description Orders the images, videos or panoramas of a tour owned by the provider. The request lists the IDs of every media of the kind in the new order.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param kind path string true "Media kind: images, videos or panoramas"
+// @Param request body entity.ReorderMediaDTO true "Media IDs in order"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Media reordered"
+// @Failure 400 {object} map[string]string "Invalid tour ID, media kind or order"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/media/{kind}/order [put]
+// @Security Bearer
+func (r *tourismRoutes) ReorderTourMedia(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tour events"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tourEvents)
+	var dto entity.ReorderMediaDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := r.t.ReorderTourMedia(tourID, c.Param("kind"), dto.IDs); err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Media reordered"})
 }
 
-// GetTourLocationByID retrieves a tour location by ID.
-// @Summary Get tour location by ID
-// @Description Fetches details of a specific tour location.
+// SetTourCover godoc
+// @Summary Set the cover image of a tour
+// @Description Makes an image the cover of a tour owned by the provider. The cover is listed before the other images of the tour.
 // @Tags Provider
+// @Accept json
 // @Produce json
-// @Param id path string true "Tour Location ID"
+// @Param id path string true "Tour ID"
+// @Param request body entity.SetCoverImageDTO true "Image ID"
 // @Security BearerAuth
-// @Success 200 {object} entity.TourLocation "Tour location details"
-// @Router /v1/tours/provider/tour-location/{id} [get]
+// @Success 200 {object} map[string]string "Cover image set"
+// @Failure 400 {object} map[string]string "Invalid tour ID or image ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Failure 404 {object} map[string]string "The tour has no such image"
+// @Router /v1/tours/provider/{id}/cover [put]
 // @Security Bearer
-func (r *tourismRoutes) GetTourLocationByID(c *gin.Context) {
-	userID := utils.GetUserIDFromContext(c)
-
+func (r *tourismRoutes) SetTourCover(c *gin.Context) {
 	tourID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.Status(http.StatusBadRequest)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
 		return
 	}
-	if !r.t.CheckTourOwner(tourID, userID) {
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
 		return
 	}
-	tourLocation, err := r.t.GetTourLocationByID(tourID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+	var dto entity.SetCoverImageDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := r.t.SetTourCover(tourID, dto.ImageID); err != nil {
+		c.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cover image set"})
+}
 
-	c.JSON(http.StatusOK, tourLocation)
+func mediaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrMediaNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrInvalidMediaKind), errors.Is(err, usecase.ErrInvalidMediaOrder):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func setUploadHeaders(c *gin.Context, session *entity.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Cache-Control", "no-store")
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrUploadForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrUploadOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, usecase.ErrUploadClosed):
+		return http.StatusGone
+	case errors.Is(err, usecase.ErrUploadChecksumMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, usecase.ErrInvalidUpload):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// isInvalidMedia reports whether an upload was refused for its content, as
+// opposed to failing to be stored.
+func isInvalidMedia(err error) bool {
+	return errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooLarge) || errors.Is(err, media.ErrUnsupportedType)
+}
 
+// readFormFile reads an uploaded file into memory.
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
 }
 
 // CreateTourLocation creates a new tour location.
@@ -648,7 +2235,7 @@ func (r *tourismRoutes) CreateTourLocation(c *gin.Context) {
 
 	createdTourLocation, err := r.t.CreateTourLocation(&createTourLocationDTO)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Tour Location created successfully!", "Tour Location": createdTourLocation})
@@ -707,7 +2294,7 @@ func (r *tourismRoutes) GetAllCategories(c *gin.Context) {
 
 // PayTourEvent handles tour event payments.
 // @Summary Pay for a tour event
-// @Description Processes a payment for a selected tour event
+// @Description Reserves the requested number of places (1 by default) on a tour event and processes the payment for all of them. An optional promo code discounts the purchase.
 // @Tags Payments
 // @Accept json
 // @Produce json
@@ -715,6 +2302,7 @@ func (r *tourismRoutes) GetAllCategories(c *gin.Context) {
 // @Param request body entity.TourPurchaseRequest true "Purchase Request"
 // @Success 200 {object} entity.Purchase
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "The tour is archived"
 // @Router /v1/tours/payment [post]
 // @Security Bearer
 func (r *tourismRoutes) PayTourEvent(c *gin.Context) {
@@ -724,28 +2312,50 @@ func (r *tourismRoutes) PayTourEvent(c *gin.Context) {
 		return
 	}
 
+	if purchaseRaw.Quantity == 0 && len(purchaseRaw.Tickets) == 0 {
+		purchaseRaw.Quantity = 1
+	}
+	if purchaseRaw.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
+		return
+	}
+
 	UserID := utils.GetUserIDFromContext(c)
 
 	purchase := entity.Purchase{
 		TourEventID: purchaseRaw.TourEventID,
 		UserID:      UserID,
-		Status:      "Processing",
+		Status:      entity.PurchaseStatusProcessing,
+		Quantity:    purchaseRaw.Quantity,
+		Items:       purchaseItems(purchaseRaw.Tickets),
 	}
 
-	processingPurchase, err := r.t.CreatePurchase(&purchase)
+	processingPurchase, err := r.t.CreatePurchase(&purchase, purchaseRaw.PromoCode)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(promoCodeErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	r.p.PurchaseQueue <- processingPurchase
+	// The purchase is already queued in the database, wake a payment worker up
+	r.p.Notify()
 
 	c.JSON(http.StatusOK, gin.H{"Purchase": processingPurchase})
 }
 
+func purchaseItems(tickets []entity.TicketSelectionDTO) []entity.PurchaseItem {
+	items := make([]entity.PurchaseItem, 0, len(tickets))
+	for _, ticket := range tickets {
+		items = append(items, entity.PurchaseItem{
+			TicketTypeID: ticket.TicketTypeID,
+			Quantity:     ticket.Quantity,
+		})
+	}
+	return items
+}
+
 // CreateTourEvent handles the creation of a new tour event related to some specific tour with images and videos.
 // @Summary Create a new tour event
-// @Description Create a new tour event.
+// @Description Create a new tour event. Optional ticket types share its places and may have their own limits.
 // @Tags Provider
 // @Accept json
 // @Produce json
@@ -780,8 +2390,19 @@ func (r *tourismRoutes) CreateTourEvent(c *gin.Context) {
 		AmountOfPlaces: createTourEventDTO.AmountOfPlaces,
 		InstaPostURL:   createTourEventDTO.InstaPostURL,
 	}
+	for _, ticketType := range createTourEventDTO.TicketTypes {
+		tour.TicketTypes = append(tour.TicketTypes, entity.TicketType{
+			Name:      ticketType.Name,
+			Price:     ticketType.Price,
+			MaxPlaces: ticketType.MaxPlaces,
+		})
+	}
 
 	createdTourEvent, err := r.t.CreateTourEvent(tour)
+	if errors.Is(err, usecase.ErrInvalidTicketType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tour. Make sure the tour with such ID exists"})
 		return
@@ -790,6 +2411,251 @@ func (r *tourismRoutes) CreateTourEvent(c *gin.Context) {
 
 }
 
+// UpdateTourEvent godoc
+// @Summary Update a tour event
+// @Description Changes the date, price, place, number of places or Instagram post of an upcoming tour event owned by the provider. amount_of_places counts the places already sold and may not go below them. Paid purchasers are notified when the date or the place changes, and new places are offered to the waitlist first. An event of a schedule whose date, price, place or places change is detached from the schedule, which no longer updates or deletes it.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Tour event ID"
+// @Param request body entity.UpdateTourEventDTO true "Fields to change"
+// @Security BearerAuth
+// @Success 200 {object} entity.TourEvent "Updated tour event"
+// @Failure 400 {object} map[string]string "Invalid tour event ID or body"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour event"
+// @Failure 409 {object} map[string]string "Tour event is cancelled or already took place"
+// @Router /v1/tours/provider/tour-events/{id} [patch]
+// @Security Bearer
+func (r *tourismRoutes) UpdateTourEvent(c *gin.Context) {
+	tourEventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour event ID"})
+		return
+	}
+
+	var dto entity.UpdateTourEventDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tourEvent, err := r.t.UpdateTourEvent(utils.GetUserIDFromContext(c), tourEventID, &dto)
+	if err != nil {
+		c.JSON(tourEventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tourEvent)
+}
+
+// CancelTourEvent godoc
+// @Summary Cancel a tour event
+// @Description Closes an upcoming tour event owned by the provider for good, ends its waitlist, cancels and refunds all of its purchases and notifies every purchaser and person in line. If a refund fails the request can be repeated to retry it.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour event ID"
+// @Security BearerAuth
+// @Success 200 {object} entity.TourEvent "Cancelled tour event"
+// @Failure 400 {object} map[string]string "Invalid tour event ID, or a refund failed"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour event"
+// @Failure 409 {object} map[string]string "Tour event already took place"
+// @Router /v1/tours/provider/tour-events/{id}/cancel [post]
+// @Security Bearer
+func (r *tourismRoutes) CancelTourEvent(c *gin.Context) {
+	tourEventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour event ID"})
+		return
+	}
+
+	tourEvent, err := r.t.CancelTourEvent(utils.GetUserIDFromContext(c), tourEventID)
+	if err != nil {
+		c.JSON(tourEventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tourEvent)
+}
+
+// GetTourEventAttendance godoc
+// @Summary Attendance of a tour event
+// @Description Lists the paid seats of a tour event owned by the provider with their check-ins, and counts them: checked in, and not checked in as remaining before the start of the tour event or as no-shows after it.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour event ID"
+// @Security BearerAuth
+// @Success 200 {object} entity.AttendanceDTO "Attendance of the tour event"
+// @Failure 400 {object} map[string]string "Invalid tour event ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour event"
+// @Router /v1/tours/provider/tour-events/{id}/attendance [get]
+// @Security Bearer
+func (r *tourismRoutes) GetTourEventAttendance(c *gin.Context) {
+	tourEventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour event ID"})
+		return
+	}
+
+	attendance, err := r.t.GetTourEventAttendance(utils.GetUserIDFromContext(c), tourEventID)
+	if err != nil {
+		c.JSON(tourEventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attendance)
+}
+
+func tourEventErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrTourEventForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrTourEventClosed):
+		return http.StatusConflict
+	default:
+		return purchaseErrorStatus(err)
+	}
+}
+
+// CreateTourSchedule godoc
+// @Summary Create a recurring schedule of a tour
+// @Description Repeats a tour owned by the provider by an RFC 5545 RRULE, for example FREQ=WEEKLY;BYDAY=SA. Every occurrence starts at the wall clock time of starts_at in time_zone and becomes a tour event with the default price, place and number of places, except on the exception dates (YYYY-MM-DD). Events are created ahead over a rolling horizon.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Param request body entity.CreateTourScheduleDTO true "Schedule"
+// @Security BearerAuth
+// @Success 201 {object} entity.TourSchedule "Created schedule"
+// @Failure 400 {object} map[string]string "Invalid tour ID or schedule"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/schedules [post]
+// @Security Bearer
+func (r *tourismRoutes) CreateTourSchedule(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	var dto entity.CreateTourScheduleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule, err := r.t.CreateTourSchedule(tourID, &dto)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// GetTourSchedules godoc
+// @Summary List the schedules of a tour
+// @Description Returns the recurring schedules of a tour owned by the provider.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Tour ID"
+// @Security BearerAuth
+// @Success 200 {array} entity.TourSchedule "Schedules"
+// @Failure 400 {object} map[string]string "Invalid tour ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the tour"
+// @Router /v1/tours/provider/{id}/schedules [get]
+// @Security Bearer
+func (r *tourismRoutes) GetTourSchedules(c *gin.Context) {
+	tourID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing tour ID"})
+		return
+	}
+	if !r.t.CheckTourOwner(tourID, utils.GetUserIDFromContext(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: You are not owner of this tour"})
+		return
+	}
+
+	schedules, err := r.t.GetTourSchedules(tourID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// UpdateTourSchedule godoc
+// @Summary Update a schedule of a tour
+// @Description Changes a schedule owned by the provider. Upcoming events of the series that were never sold, have no waitlist and were not edited by hand follow the change, other events are left alone.
+// @Tags Provider
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Param request body entity.UpdateTourScheduleDTO true "Fields to change"
+// @Security BearerAuth
+// @Success 200 {object} entity.TourSchedule "Updated schedule"
+// @Failure 400 {object} map[string]string "Invalid schedule ID or schedule"
+// @Failure 403 {object} map[string]string "You are not the owner of the schedule"
+// @Failure 404 {object} map[string]string "Schedule not found"
+// @Router /v1/tours/provider/schedules/{id} [patch]
+// @Security Bearer
+func (r *tourismRoutes) UpdateTourSchedule(c *gin.Context) {
+	scheduleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing schedule ID"})
+		return
+	}
+
+	var dto entity.UpdateTourScheduleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule, err := r.t.UpdateTourSchedule(utils.GetUserIDFromContext(c), scheduleID, &dto)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteTourSchedule godoc
+// @Summary Delete a schedule of a tour
+// @Description Deletes a schedule owned by the provider with its upcoming events that were never sold, have no waitlist and were not edited by hand. Other events stay and can be cancelled one by one.
+// @Tags Provider
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Schedule deleted"
+// @Failure 400 {object} map[string]string "Invalid schedule ID"
+// @Failure 403 {object} map[string]string "You are not the owner of the schedule"
+// @Failure 404 {object} map[string]string "Schedule not found"
+// @Router /v1/tours/provider/schedules/{id} [delete]
+// @Security Bearer
+func (r *tourismRoutes) DeleteTourSchedule(c *gin.Context) {
+	scheduleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing schedule ID"})
+		return
+	}
+
+	if err := r.t.DeleteTourSchedule(utils.GetUserIDFromContext(c), scheduleID); err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted"})
+}
+
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrScheduleForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidSchedule):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // GetStaticFiles serves static files (images and videos) for a given tour.
 // @Summary Get static files for a tour
 // @Description Fetches images and videos for a specific tour by ID.Example http://localhost:8080/uploads/videos/4f72a1cb-6ed4-4f01-b38b-b605d3062236.mp4.
@@ -823,25 +2689,41 @@ func (r *tourismRoutes) GetTourByID(c *gin.Context) {
 	c.JSON(http.StatusOK, tour)
 }
 
-// GetTours retrieves a list of available tours.
-// @Summary Get all tours
-// @Description Fetches a list of all available tours
+// GetTours retrieves a page of available tours.
+// @Summary Get tours
+// @Description Fetches a page of tours with their images, upcoming open events and rating. Pass next_cursor of a page as cursor to get the following one.
 // @Tags Tours
 // @Produce json
-// @Success 200 {array} entity.Tour
+// @Param sort query string false "newest (default), date, price, popularity or rating"
+// @Param limit query int false "Tours per page, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} entity.TourPage
+// @Failure 400 {object} map[string]string "Invalid sort or cursor"
 // @Failure 500 {object} map[string]string
 // @Router /v1/tours [get]
 func (r *tourismRoutes) GetTours(c *gin.Context) {
+	var page entity.PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	tours, err := r.t.GetTours()
+	tours, err := r.t.GetTours(&page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tours"})
+		c.JSON(pageErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, tours)
 }
 
+func pageErrorStatus(err error) int {
+	if errors.Is(err, usecase.ErrInvalidSort) || errors.Is(err, usecase.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // CreateTour handles the creation of a new tour with images and videos.
 // @Summary Create a new tour
 // @Description Create a new tour with images and videos.
@@ -903,6 +2785,10 @@ func (r *tourismRoutes) CreateTour(c *gin.Context) {
 
 	createdTour, err := r.t.CreateTour(tour, imageFiles, videoFiles)
 	if err != nil {
+		if isInvalidMedia(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tour"})
 		return
 	}
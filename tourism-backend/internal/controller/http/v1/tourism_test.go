@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82/webhook"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"tourism-backend/internal/usecase"
+)
+
+const testWebhookSecret = "whsec_test_fixture_secret"
+
+// fakeTourism records the webhook calls; any other method panics.
+type fakeTourism struct {
+	usecase.TourismInterface
+	succeeded []string
+	failed    []string
+	err       error
+}
+
+func (f *fakeTourism) HandlePaymentSucceeded(paymentIntentID string) error {
+	f.succeeded = append(f.succeeded, paymentIntentID)
+	return f.err
+}
+
+func (f *fakeTourism) HandlePaymentFailed(paymentIntentID string) error {
+	f.failed = append(f.failed, paymentIntentID)
+	return f.err
+}
+
+func sendWebhook(t *testing.T, f *fakeTourism, fixture, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    secret,
+		Timestamp: time.Now(),
+	})
+
+	gin.SetMode(gin.TestMode)
+	handler := gin.New()
+	r := &tourismRoutes{t: f, stripeWebhookSecret: testWebhookSecret}
+	handler.POST("/webhook", r.HandleStripeWebhook)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signed.Header)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleStripeWebhookSucceeded(t *testing.T) {
+	f := &fakeTourism{}
+	w := sendWebhook(t, f, "payment_intent_succeeded.json", testWebhookSecret)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(f.succeeded) != 1 || f.succeeded[0] != "pi_3PaidFixture000001" {
+		t.Fatalf("succeeded = %v, want [pi_3PaidFixture000001]", f.succeeded)
+	}
+	if len(f.failed) != 0 {
+		t.Fatalf("failed = %v, want none", f.failed)
+	}
+}
+
+func TestHandleStripeWebhookPaymentFailed(t *testing.T) {
+	f := &fakeTourism{}
+	w := sendWebhook(t, f, "payment_intent_payment_failed.json", testWebhookSecret)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(f.failed) != 1 || f.failed[0] != "pi_3FailedFixture0001" {
+		t.Fatalf("failed = %v, want [pi_3FailedFixture0001]", f.failed)
+	}
+}
+
+func TestHandleStripeWebhookInvalidSignature(t *testing.T) {
+	f := &fakeTourism{}
+	w := sendWebhook(t, f, "payment_intent_succeeded.json", "whsec_wrong_secret")
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if len(f.succeeded) != 0 {
+		t.Fatalf("succeeded = %v, want none", f.succeeded)
+	}
+}
+
+func TestHandleStripeWebhookUnknownPurchase(t *testing.T) {
+	f := &fakeTourism{err: usecase.ErrPurchaseNotFound}
+	w := sendWebhook(t, f, "payment_intent_succeeded.json", testWebhookSecret)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d so Stripe stops retrying", w.Code, http.StatusOK)
+	}
+}
+
+func TestHandleStripeWebhookRetriesOnError(t *testing.T) {
+	f := &fakeTourism{err: os.ErrDeadlineExceeded}
+	w := sendWebhook(t, f, "payment_intent_succeeded.json", testWebhookSecret)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestStripeWebhookRouteNeedsSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, secret := range []string{"", testWebhookSecret} {
+		handler := gin.New()
+		newTourismRoutes(handler.Group("/v1"), &fakeTourism{}, nil, nil, nil, nil, secret)
+
+		registered := false
+		for _, route := range handler.Routes() {
+			if route.Path == "/v1/tours/payment/stripe-webhook" {
+				registered = true
+			}
+		}
+		if registered != (secret != "") {
+			t.Errorf("webhook registered = %v with secret %q", registered, secret)
+		}
+	}
+}
@@ -31,8 +31,97 @@ type WeatherCondition struct {
 	Code int    `json:"code"`
 }
 
+// TourPurchaseRequest buys Quantity places of a tour event without ticket types,
+// or the places listed in Tickets of a tour event with ticket types.
 type TourPurchaseRequest struct {
-	TourEventID uuid.UUID `json:"tour_event_id"`
+	TourEventID uuid.UUID            `json:"tour_event_id"`
+	Quantity    int                  `json:"quantity"`
+	Tickets     []TicketSelectionDTO `json:"tickets"`
+	PromoCode   string               `json:"promo_code"`
+}
+
+type TicketSelectionDTO struct {
+	TicketTypeID uuid.UUID `json:"ticket_type_id" binding:"required"`
+	Quantity     int       `json:"quantity" binding:"required"`
+}
+
+type CreatePromoCodeDTO struct {
+	Code           string     `json:"code" binding:"required"`
+	DiscountType   string     `json:"discount_type" binding:"required"`
+	Value          float64    `json:"value" binding:"required"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	MaxUses        int        `json:"max_uses"`
+	MaxUsesPerUser int        `json:"max_uses_per_user"`
+	TourID         *uuid.UUID `json:"tour_id"`
+	CategoryID     *uuid.UUID `json:"category_id"`
+	TourEventID    *uuid.UUID `json:"tour_event_id"`
+}
+
+type CreatePricingRuleDTO struct {
+	TourEventID  uuid.UUID  `json:"tour_event_id" binding:"required"`
+	Type         string     `json:"type" binding:"required"`
+	Percent      float64    `json:"percent" binding:"required"`
+	StartsBefore *time.Time `json:"starts_before"`
+	HoursBefore  int        `json:"hours_before"`
+	MinQuantity  int        `json:"min_quantity"`
+}
+
+// PriceAdjustment is one line of a price breakdown. Amount is negative for discounts.
+type PriceAdjustment struct {
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+	RuleID      *uuid.UUID `json:"rule_id,omitempty"`
+}
+
+// PriceQuote explains how the amount of a purchase is computed.
+type PriceQuote struct {
+	TourEventID uuid.UUID         `json:"tour_event_id"`
+	Quantity    int               `json:"quantity"`
+	UnitPrice   float64           `json:"unit_price,omitempty"`
+	Items       []PurchaseItem    `json:"items,omitempty"`
+	Subtotal    float64           `json:"subtotal"`
+	Adjustments []PriceAdjustment `json:"adjustments"`
+	Total       float64           `json:"total"`
+}
+
+// TourSearchResult is a tour matching a search query. NameHighlight and Snippet
+// wrap the matched words in <b></b>.
+type TourSearchResult struct {
+	Tour          Tour    `json:"tour"`
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+type ReplyToReviewDTO struct {
+	Reply string `json:"reply" binding:"required"`
+}
+
+// ReviewFilter selects a page of the reviews of a tour. Sort is one of
+// newest, oldest, rating_desc or rating_asc.
+type ReviewFilter struct {
+	TourID   uuid.UUID
+	Page     int
+	PageSize int
+	Sort     string
+}
+
+type ReviewPage struct {
+	Reviews  []*Review `json:"reviews"`
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+}
+
+type ClaimWaitlistOfferDTO struct {
+	Tickets []TicketSelectionDTO `json:"tickets"`
+}
+
+type JoinWaitlistDTO struct {
+	TourEventID uuid.UUID `json:"tour_event_id" binding:"required"`
+	Quantity    int       `json:"quantity"`
 }
 
 type LoginUserDTO struct {
@@ -40,13 +129,33 @@ type LoginUserDTO struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// CreateTourEventDTO creates a tour event. Its TicketTypes share AmountOfPlaces;
+// without ticket types every place costs Price.
 type CreateTourEventDTO struct {
-	Date           time.Time `json:"date" gorm:"not null"`
-	Price          float64   `json:"price" gorm:"not null"`
-	Place          string    `json:"place" gorm:"not null"`
-	TourID         uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
-	AmountOfPlaces float64   `json:"amount_of_places" gorm:"not null"`
-	InstaPostURL   string    `json:"insta_post_url"`
+	Date           time.Time             `json:"date" gorm:"not null"`
+	Price          float64               `json:"price" gorm:"not null"`
+	Place          string                `json:"place" gorm:"not null"`
+	TourID         uuid.UUID             `json:"tour_id" gorm:"type:uuid;index"`
+	AmountOfPlaces float64               `json:"amount_of_places" gorm:"not null"`
+	InstaPostURL   string                `json:"insta_post_url"`
+	TicketTypes    []CreateTicketTypeDTO `json:"ticket_types"`
+}
+
+// UpdateTourEventDTO holds the fields of a tour event its provider may change.
+// AmountOfPlaces is the new number of places, including those already sold.
+// Fields left out of the request keep their value.
+type UpdateTourEventDTO struct {
+	Date           *time.Time `json:"date"`
+	Price          *float64   `json:"price" binding:"omitempty,gte=0"`
+	Place          *string    `json:"place" binding:"omitempty,min=1"`
+	AmountOfPlaces *float64   `json:"amount_of_places" binding:"omitempty,gte=0"`
+	InstaPostURL   *string    `json:"insta_post_url"`
+}
+
+type CreateTicketTypeDTO struct {
+	Name      string  `json:"name" binding:"required"`
+	Price     float64 `json:"price"`
+	MaxPlaces int     `json:"max_places"`
 }
 
 type CreateTourCategoryDTO struct {
@@ -68,13 +177,120 @@ type TourEventFilter struct {
 	MaxPrice    float64     `json:"max_price,omitempty"`
 }
 
+// IsEmpty reports whether the filter matches every open tour event.
+func (f *TourEventFilter) IsEmpty() bool {
+	return len(f.CategoryIDs) == 0 && f.StartDate.IsZero() && f.EndDate.IsZero() && f.MinPrice == 0 && f.MaxPrice == 0
+}
+
+// GeoQuery selects tours by their location, either within RadiusKm of Origin
+// or inside Bounds. Results are sorted by the distance from Origin, which
+// defaults to the center of Bounds. A non-empty Filter keeps only tours with a
+// matching open event.
+type GeoQuery struct {
+	Origin   *GeoPoint
+	RadiusKm float64
+	Bounds   *GeoBounds
+	Filter   *TourEventFilter
+	Limit    int
+	Offset   int
+}
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// GeoBounds is a map viewport. West is greater than East when the viewport
+// crosses the antimeridian.
+type GeoBounds struct {
+	North float64
+	South float64
+	East  float64
+	West  float64
+}
+
+type TourNearby struct {
+	Tour       Tour    `json:"tour"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// UpdateTourDTO holds the fields of a tour its provider may change. Fields
+// left out of the request keep their value.
+type UpdateTourDTO struct {
+	Name            *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description     *string `json:"description"`
+	Route           *string `json:"route"`
+	TelegramChatURL *string `json:"telegram_chat_url"`
+	AirpanoLink     *string `json:"airpano_link"`
+}
+
+// ReorderMediaDTO lists every media of one kind of a tour in its new order.
+type ReorderMediaDTO struct {
+	IDs []uuid.UUID `json:"ids" binding:"required"`
+}
+
+type SetCoverImageDTO struct {
+	ImageID uuid.UUID `json:"image_id" binding:"required"`
+}
+
+// CalendarLinksDTO holds the subscription URLs of the calendar feeds of a
+// user. Anyone with a URL can read the feed.
+type CalendarLinksDTO struct {
+	PurchasesURL  string `json:"purchases_url"`
+	TourEventsURL string `json:"tour_events_url"`
+}
+
 type Notification struct {
 	Topic      string                 `json:"topic" binding:"required"`
 	Data       map[string]interface{} `json:"data" binding:"required"`
 	Recipients []uuid.UUID            `json:"recipients" binding:"required"`
 }
 
+// PurchaseQRDTO is the ticket of one seat. The QR code encodes the signed
+// token, which the provider scans at check-in.
 type PurchaseQRDTO struct {
-	QRCode string `json:"qr_code"`
-	URL    string `json:"url"`
+	Seat      int       `json:"seat"`
+	QRCode    string    `json:"qr_code"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CheckTicketDTO struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CheckedTicketDTO is a scanned ticket that admits one person to the tour
+// event. CheckIn is nil until the ticket is used.
+type CheckedTicketDTO struct {
+	Seat      int       `json:"seat"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Purchase  *Purchase `json:"purchase"`
+	CheckIn   *CheckIn  `json:"check_in"`
+}
+
+// AttendanceDTO counts the paid seats of a tour event for the headcount at the
+// meeting point. Seats not checked in are Remaining until the tour event
+// starts and NoShow after it.
+type AttendanceDTO struct {
+	TourEventID uuid.UUID              `json:"tour_event_id"`
+	Date        time.Time              `json:"date"`
+	Sold        int                    `json:"sold"`
+	CheckedIn   int                    `json:"checked_in"`
+	NoShow      int                    `json:"no_show"`
+	Remaining   int                    `json:"remaining"`
+	Tickets     []*AttendanceTicketDTO `json:"tickets"`
+}
+
+type AttendanceTicketDTO struct {
+	PurchaseID uuid.UUID `json:"purchase_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username"`
+	Seat       int       `json:"seat"`
+	CheckIn    *CheckIn  `json:"check_in"`
+}
+
+// TicketKeyDTO holds the public key that verifies ticket tokens offline.
+type TicketKeyDTO struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
 }
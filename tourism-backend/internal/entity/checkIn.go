@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// CheckIn records that the ticket of one seat of a purchase was used at the
+// meeting point, when and by which user. Tours have no staff accounts, so that
+// user is the provider who owns the tour. A seat is checked in once.
+type CheckIn struct {
+	gorm.Model    `swaggerignore:"true"`
+	ID            uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	PurchaseID    uuid.UUID `json:"purchase_id" gorm:"type:uuid;not null;uniqueIndex:idx_check_in_seat,priority:1"`
+	Purchase      Purchase  `json:"-" gorm:"foreignKey:PurchaseID;constraint:OnDelete:CASCADE;"`
+	Seat          int       `json:"seat" gorm:"not null;uniqueIndex:idx_check_in_seat,priority:2"`
+	TourEventID   uuid.UUID `json:"tour_event_id" gorm:"type:uuid;not null;index"`
+	CheckedInAt   time.Time `json:"checked_in_at" gorm:"not null"`
+	CheckedInByID uuid.UUID `json:"checked_in_by" gorm:"type:uuid;not null"`
+}
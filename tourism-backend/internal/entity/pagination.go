@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Sort options of the paginated listings. Date and price list the lowest
+// value first, the others the highest.
+const (
+	SortDate       = "date"
+	SortPrice      = "price"
+	SortPopularity = "popularity"
+	SortRating     = "rating"
+	SortNewest     = "newest"
+)
+
+// PageQuery selects a page of a listing. After is the decoded Cursor and is
+// set by the usecase before the query reaches the repository.
+type PageQuery struct {
+	Limit  int     `form:"limit"`
+	Cursor string  `form:"cursor"`
+	Sort   string  `form:"sort"`
+	After  *Cursor `form:"-"`
+}
+
+// Cursor is the sort key and ID of the last item of a page. The next page
+// starts right after it. Time holds the key of date-like sorts, Value the key
+// of the numeric ones.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Time  time.Time `json:"t,omitempty"`
+	Value float64   `json:"v,omitempty"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Encode turns the cursor into the opaque string handed to clients.
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == uuid.Nil {
+		return nil, errors.New("cursor without id")
+	}
+	return &c, nil
+}
+
+type TourPage struct {
+	Tours      []*Tour `json:"tours"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type TourEventPage struct {
+	TourEvents []*TourEvent `json:"tour_events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type PurchasePage struct {
+	Purchases  []*Purchase `json:"purchases"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+type UserPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Payment job statuses.
+const (
+	PaymentJobStatusPending = "pending"
+	PaymentJobStatusRunning = "running"
+	PaymentJobStatusDone    = "done"
+	PaymentJobStatusFailed  = "failed"
+)
+
+// PaymentJob is a durable request to charge a purchase. There is at most one
+// job per purchase.
+type PaymentJob struct {
+	gorm.Model  `swaggerignore:"true"`
+	ID          uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	PurchaseID  uuid.UUID `json:"purchase_id" gorm:"type:uuid;uniqueIndex"`
+	Purchase    Purchase  `json:"-" gorm:"foreignKey:PurchaseID;constraint:OnDelete:CASCADE;"`
+	Status      string    `json:"status" gorm:"not null;default:pending;index"`
+	Attempts    int       `json:"attempts" gorm:"not null;default:0"`
+	NextRunAt   time.Time `json:"next_run_at" gorm:"index"`
+	LockedUntil time.Time `json:"locked_until"`
+	LastError   string    `json:"last_error"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Pricing rule types.
+//   - early_bird discounts purchases made before StartsBefore.
+//   - last_minute discounts purchases made less than HoursBefore hours before the tour event.
+//   - group discounts purchases of at least MinQuantity places.
+const (
+	PricingRuleEarlyBird  = "early_bird"
+	PricingRuleLastMinute = "last_minute"
+	PricingRuleGroup      = "group"
+)
+
+// PricingRule takes Percent percent off the price of a tour event when it applies.
+type PricingRule struct {
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourEventID  uuid.UUID  `json:"tour_event_id" gorm:"type:uuid;index"`
+	TourEvent    TourEvent  `json:"-" gorm:"foreignKey:TourEventID;constraint:OnDelete:CASCADE;"`
+	Type         string     `json:"type" gorm:"not null"`
+	Percent      float64    `json:"percent" gorm:"not null"`
+	StartsBefore *time.Time `json:"starts_before"`
+	HoursBefore  int        `json:"hours_before"`
+	MinQuantity  int        `json:"min_quantity"`
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// Promo code discount types.
+const (
+	PromoCodeTypePercentage = "percentage"
+	PromoCodeTypeFixed      = "fixed"
+)
+
+// PromoCode gives a discount on purchases. A code without TourID, CategoryID
+// and TourEventID applies to every tour event. Zero limits mean unlimited uses.
+type PromoCode struct {
+	gorm.Model     `swaggerignore:"true"`
+	ID             uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Code           string     `json:"code" gorm:"uniqueIndex;not null"`
+	DiscountType   string     `json:"discount_type" gorm:"not null"`
+	Value          float64    `json:"value" gorm:"not null"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	MaxUses        int        `json:"max_uses"`
+	MaxUsesPerUser int        `json:"max_uses_per_user"`
+	TourID         *uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
+	CategoryID     *uuid.UUID `json:"category_id" gorm:"type:uuid;index"`
+	TourEventID    *uuid.UUID `json:"tour_event_id" gorm:"type:uuid;index"`
+	CreatedByID    uuid.UUID  `json:"created_by_id" gorm:"type:uuid;index"`
+}
+
+// Discount returns how much the code takes off amount, never more than amount.
+func (p *PromoCode) Discount(amount float64) float64 {
+	discount := p.Value
+	if p.DiscountType == PromoCodeTypePercentage {
+		discount = math.Round(amount*p.Value) / 100
+	}
+	return math.Min(discount, amount)
+}
+
+// IsValidAt reports whether the validity window of the code contains t.
+func (p *PromoCode) IsValidAt(t time.Time) bool {
+	if p.ValidFrom != nil && t.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidUntil != nil && t.After(*p.ValidUntil) {
+		return false
+	}
+	return true
+}
@@ -3,25 +3,56 @@ package entity
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// Purchase statuses. A purchase starts as Processing and ends in one of
+// Paid, Refunded, Failed or Expired; CancelRequested is the intermediate
+// state while a refund is in flight.
+const (
+	PurchaseStatusProcessing      = "Processing"
+	PurchaseStatusPaid            = "Paid"
+	PurchaseStatusCancelRequested = "CancelRequested"
+	PurchaseStatusRefunded        = "Refunded"
+	PurchaseStatusFailed          = "Failed"
+	PurchaseStatusExpired         = "Expired"
 )
 
 type Purchase struct {
-	gorm.Model  `swaggerignore:"true"`
-	ID          uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
-	User        User      `json:"User"`
-	TourEvent   TourEvent `json:"TourEvent"`
-	UserID      uuid.UUID `json:"UserID"`
-	TourEventID uuid.UUID `json:"TourEventID"`
-	Status      string    `json:"Status"`
+	gorm.Model      `swaggerignore:"true"`
+	ID              uuid.UUID      `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	User            User           `json:"User"`
+	TourEvent       TourEvent      `json:"TourEvent"`
+	UserID          uuid.UUID      `json:"UserID"`
+	TourEventID     uuid.UUID      `json:"TourEventID"`
+	Status          string         `json:"Status"`
+	PaymentIntentID string         `json:"PaymentIntentID" gorm:"index"`
+	Quantity        int            `json:"Quantity" gorm:"not null;default:1"`
+	Amount          float64        `json:"Amount"`
+	HoldExpiresAt   *time.Time     `json:"HoldExpiresAt" gorm:"index"`
+	PromoCodeID     *uuid.UUID     `json:"PromoCodeID" gorm:"type:uuid;index"`
+	PromoCode       *PromoCode     `json:"PromoCode,omitempty"`
+	Discount        float64        `json:"Discount"`
+	PricingDiscount float64        `json:"PricingDiscount"`
+	Items           []PurchaseItem `json:"Items" gorm:"foreignKey:PurchaseID;references:ID;constraint:OnDelete:CASCADE;"`
+}
+
+// IsFree reports whether discounts left less than a cent to charge.
+func (p *Purchase) IsFree() bool {
+	return math.Round(p.Amount*100) <= 0
 }
 
 type CreatePaymentIntentRequest struct {
-	Amount   int64  `json:"amount"`
-	Currency string `json:"currency"`
+	PurchaseID uuid.UUID `json:"purchase_id" binding:"required"`
+	PromoCode  string    `json:"promo_code"`
 }
 
+// CreatePaymentIntentResponse has no client secret when discounts made the
+// purchase free, it is Paid right away then.
 type CreatePaymentIntentResponse struct {
-	ClientSecret string `json:"clientSecret"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	Status       string `json:"status"`
 }
 
 type ConfirmPaymentRequest struct {
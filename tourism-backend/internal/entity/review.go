@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// Review is a rating a traveler left after a tour event they paid for took place.
+// A user reviews each tour event at most once.
+type Review struct {
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID     `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourID       uuid.UUID     `json:"tour_id" gorm:"type:uuid;index"`
+	Tour         Tour          `json:"-" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	TourEventID  uuid.UUID     `json:"tour_event_id" gorm:"type:uuid;uniqueIndex:idx_review_user_tour_event"`
+	UserID       uuid.UUID     `json:"user_id" gorm:"type:uuid;uniqueIndex:idx_review_user_tour_event"`
+	Username     string        `json:"username" gorm:"->;-:migration"`
+	Rating       int           `json:"rating" gorm:"not null;index"`
+	Text         string        `json:"text"`
+	ReviewPhotos []ReviewPhoto `json:"photos" gorm:"foreignKey:ReviewID;references:ID;constraint:OnDelete:CASCADE;"`
+	Reply        string        `json:"reply"`
+	RepliedAt    *time.Time    `json:"replied_at"`
+}
+
+type ReviewPhoto struct {
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID `json:"ID" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	ReviewID     uuid.UUID `json:"review_id" gorm:"type:uuid;index"`
+	PhotoURL     string    `json:"photo_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+// TourRating aggregates the reviews of a tour. Histogram maps each star
+// rating from 1 to 5 to the number of reviews that gave it.
+type TourRating struct {
+	Average   float64       `json:"average"`
+	Count     int64         `json:"count"`
+	Histogram map[int]int64 `json:"histogram"`
+}
+
+// NewTourRating returns the rating of a tour without reviews.
+func NewTourRating() *TourRating {
+	return &TourRating{Histogram: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+}
+
+// Add counts count more reviews of the given star rating. The average is
+// rounded to two decimals.
+func (r *TourRating) Add(rating int, count int64) {
+	r.Histogram[rating] += count
+	r.Count += count
+
+	var stars int64
+	for rating, count := range r.Histogram {
+		stars += int64(rating) * count
+	}
+	r.Average = math.Round(float64(stars)/float64(r.Count)*100) / 100
+}
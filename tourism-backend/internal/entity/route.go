@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Waypoint kinds. A route starts at its meeting point, passes its stops in
+// order and finishes at its end point.
+const (
+	WaypointKindMeeting = "meeting"
+	WaypointKindStop    = "stop"
+	WaypointKindEnd     = "end"
+)
+
+// Waypoint is a point of the route of a tour. Position orders the waypoints
+// from 0, StopMinutes is how long the group stays at a stop.
+type Waypoint struct {
+	gorm.Model  `swaggerignore:"true"`
+	ID          uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourID      uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
+	Tour        Tour      `json:"-" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	Position    int       `json:"position" gorm:"not null"`
+	Kind        string    `json:"kind" gorm:"not null"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Latitude    float64   `json:"latitude" gorm:"not null"`
+	Longitude   float64   `json:"longitude" gorm:"not null"`
+	StopMinutes int       `json:"stop_minutes"`
+}
+
+// TourTrack is the recorded geometry of the route of a tour. Each segment is a
+// line of [longitude, latitude] or [longitude, latitude, elevation] positions.
+type TourTrack struct {
+	gorm.Model `swaggerignore:"true"`
+	ID         uuid.UUID     `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourID     uuid.UUID     `json:"tour_id" gorm:"type:uuid;uniqueIndex"`
+	Tour       Tour          `json:"-" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	Segments   [][][]float64 `json:"segments" gorm:"type:jsonb;serializer:json"`
+	Source     string        `json:"source"`
+}
+
+// FeatureCollection is the route map of a tour in GeoJSON, see RFC 7946.
+// DistanceKm is a foreign member with the length of the route.
+type FeatureCollection struct {
+	Type       string     `json:"type"`
+	Features   []*Feature `json:"features"`
+	DistanceKm float64    `json:"distance_km"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates" swaggertype:"array,number"`
+}
+
+type WaypointDTO struct {
+	Kind        string  `json:"kind" binding:"required"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	StopMinutes int     `json:"stop_minutes"`
+}
+
+type SetWaypointsDTO struct {
+	Waypoints []WaypointDTO `json:"waypoints" binding:"required"`
+}
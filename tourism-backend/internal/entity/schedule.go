@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// TourSchedule repeats a tour by an RFC 5545 recurrence rule. StartsAt is the
+// start of the series and every occurrence begins at its wall clock time in
+// TimeZone. Occurrences become tour events with the default Price, Place and
+// AmountOfPlaces up to GeneratedUntil, except on the local dates listed in
+// ExceptionDates as YYYY-MM-DD.
+type TourSchedule struct {
+	gorm.Model     `swaggerignore:"true"`
+	ID             uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourID         uuid.UUID  `json:"tour_id" gorm:"type:uuid;index"`
+	Tour           Tour       `json:"-" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	RRule          string     `json:"rrule" gorm:"column:rrule;not null"`
+	TimeZone       string     `json:"time_zone" gorm:"not null"`
+	StartsAt       time.Time  `json:"starts_at" gorm:"not null"`
+	Price          float64    `json:"price" gorm:"not null"`
+	Place          string     `json:"place" gorm:"not null"`
+	AmountOfPlaces float64    `json:"amount_of_places" gorm:"not null"`
+	ExceptionDates []string   `json:"exception_dates" gorm:"type:jsonb;serializer:json"`
+	GeneratedUntil *time.Time `json:"generated_until"`
+}
+
+// CreateTourScheduleDTO creates a schedule, for example every Saturday at ten
+// with rrule "FREQ=WEEKLY;BYDAY=SA" and starts_at on a Saturday at 10:00.
+type CreateTourScheduleDTO struct {
+	RRule          string    `json:"rrule" binding:"required"`
+	TimeZone       string    `json:"time_zone" binding:"required"`
+	StartsAt       time.Time `json:"starts_at" binding:"required"`
+	Price          float64   `json:"price" binding:"gte=0"`
+	Place          string    `json:"place" binding:"required"`
+	AmountOfPlaces float64   `json:"amount_of_places" binding:"gt=0"`
+	ExceptionDates []string  `json:"exception_dates"`
+}
+
+// UpdateTourScheduleDTO changes a schedule. Fields left out of the request
+// keep their value, exception_dates replaces the whole list.
+type UpdateTourScheduleDTO struct {
+	RRule          *string    `json:"rrule" binding:"omitempty,min=1"`
+	TimeZone       *string    `json:"time_zone" binding:"omitempty,min=1"`
+	StartsAt       *time.Time `json:"starts_at"`
+	Price          *float64   `json:"price" binding:"omitempty,gte=0"`
+	Place          *string    `json:"place" binding:"omitempty,min=1"`
+	AmountOfPlaces *float64   `json:"amount_of_places" binding:"omitempty,gt=0"`
+	ExceptionDates *[]string  `json:"exception_dates"`
+}
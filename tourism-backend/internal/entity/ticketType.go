@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TicketType is a ticket category of a tour event, e.g. adult or child. All
+// ticket types share TourEvent.AmountOfPlaces; a positive MaxPlaces caps how many
+// places of the type can be held at once.
+type TicketType struct {
+	gorm.Model  `swaggerignore:"true"`
+	ID          uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourEventID uuid.UUID `json:"tour_event_id" gorm:"type:uuid;index"`
+	Name        string    `json:"name" gorm:"not null"`
+	Price       float64   `json:"price" gorm:"not null"`
+	MaxPlaces   int       `json:"max_places"`
+	HeldPlaces  int       `json:"held_places" gorm:"not null;default:0"`
+}
+
+// PurchaseItem records how many places of a ticket type a purchase holds and at which price.
+type PurchaseItem struct {
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	PurchaseID   uuid.UUID  `json:"purchase_id" gorm:"type:uuid;index"`
+	TicketTypeID uuid.UUID  `json:"ticket_type_id" gorm:"type:uuid;index"`
+	TicketType   TicketType `json:"ticket_type" gorm:"foreignKey:TicketTypeID;constraint:OnDelete:CASCADE;"`
+	Quantity     int        `json:"quantity" gorm:"not null"`
+	UnitPrice    float64    `json:"unit_price" gorm:"not null"`
+}
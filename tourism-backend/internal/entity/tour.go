@@ -3,25 +3,31 @@ package entity
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"time"
 )
 
+// Tour is a tour offered by a provider. An archived tour is hidden from the
+// listings, search and map but keeps its events, purchases and reviews.
 type Tour struct {
 	gorm.Model      `swaggerignore:"true"`
-	ID              uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
-	Description     string    `json:"description"`
-	Route           string    `json:"route"`
-	OwnerID         uuid.UUID `json:"owner_id" gorm:"type:uuid;index"`
-	Name            string    `json:"name"`
-	TelegramChatURL string    `json:"telegram_chat_url"`
+	ID              uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	Description     string     `json:"description"`
+	Route           string     `json:"route"`
+	OwnerID         uuid.UUID  `json:"owner_id" gorm:"type:uuid;index"`
+	Name            string     `json:"name"`
+	TelegramChatURL string     `json:"telegram_chat_url"`
+	ArchivedAt      *time.Time `json:"archived_at" gorm:"index"`
 	// Relationships
-	TourImages        []Image         `json:"tour_images" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	TourVideos        []Video         `json:"tour_videos" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	TourPanoramas     []Panorama      `json:"tour_panoramas" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	TourEvents        []TourEvent     `json:"tour_events" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	TourCategories    []TourCategory  `json:"tour_categories" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	TourLocation      *TourLocation   `json:"tour_location" gorm:"foreignKey:TourID;references:ID"`
-	TourUserFavorites []UserFavorites `json:"tour_user_favorites" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
-	AirpanoLink       string          `json:"airpano_link"`
+	TourImages        []Image            `json:"tour_images" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	TourVideos        []Video            `json:"tour_videos" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	TourPanoramas     []Panorama         `json:"tour_panoramas" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	TourEvents        []TourEvent        `json:"tour_events" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	TourCategories    []TourCategory     `json:"tour_categories" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	TourLocation      *TourLocation      `json:"tour_location" gorm:"foreignKey:TourID;references:ID"`
+	TourUserFavorites []UserFavorites    `json:"tour_user_favorites" gorm:"foreignKey:TourID;references:ID;constraint:OnDelete:CASCADE;"`
+	AirpanoLink       string             `json:"airpano_link"`
+	Rating            *TourRating        `json:"rating" gorm:"-"`
+	RouteMap          *FeatureCollection `json:"route_map,omitempty" gorm:"-"`
 }
 
 type Category struct {
@@ -49,12 +55,29 @@ type TourLocation struct {
 	Longitude float64 `json:"longitude"`
 }
 
+// Kinds of tour media, as named in the media routes of the API.
+const (
+	MediaKindImages    = "images"
+	MediaKindVideos    = "videos"
+	MediaKindPanoramas = "panoramas"
+)
+
+// Image is a photo of a tour in several renditions. ImageURL is the full
+// rendition, Width and Height its size. Images uploaded before renditions
+// existed only have an ImageURL. The cover image of a tour comes first, the
+// others follow by Position.
 type Image struct {
-	gorm.Model `swaggerignore:"true"`
-	ID         uuid.UUID `json:"ID" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	TourID     uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
-	Tour       Tour      `gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
-	ImageURL   string    `json:"image_url"`
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID `json:"ID" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	TourID       uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
+	Tour         Tour      `gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	ImageURL     string    `json:"image_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	CardURL      string    `json:"card_url"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position" gorm:"not null;default:0"`
+	IsCover      bool      `json:"is_cover" gorm:"not null;default:false"`
 }
 
 type Video struct {
@@ -63,12 +86,17 @@ type Video struct {
 	TourID     uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
 	Tour       Tour      `gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
 	VideoURL   string    `json:"video_url"`
+	Position   int       `json:"position" gorm:"not null;default:0"`
 }
 
 type Panorama struct {
-	gorm.Model  `swaggerignore:"true"`
-	ID          uuid.UUID `json:"ID" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	TourID      uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
-	Tour        Tour      `gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
-	PanoramaURL string    `json:"panorama_url"`
+	gorm.Model   `swaggerignore:"true"`
+	ID           uuid.UUID `json:"ID" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	TourID       uuid.UUID `json:"tour_id" gorm:"type:uuid;index"`
+	Tour         Tour      `gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	PanoramaURL  string    `json:"panorama_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position" gorm:"not null;default:0"`
 }
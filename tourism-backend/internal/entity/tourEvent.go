@@ -6,17 +6,28 @@ import (
 	"time"
 )
 
+// TourEvent is a date of a tour. AmountOfPlaces counts the places still free.
+// A cancelled event is closed for good and its purchases are refunded. Events
+// created by a TourSchedule have its ScheduleID, one per date, and the
+// occurrence they were created for in ScheduledDate. An event whose date,
+// price, place or places were changed by hand is Detached: its schedule no
+// longer updates or deletes it.
 type TourEvent struct {
 	gorm.Model      `swaggerignore:"true"`
 	ID              uuid.UUID `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
 	Tour            Tour
-	Date            time.Time  `json:"date" gorm:"not null"`
-	Price           float64    `json:"price" gorm:"not null"`
-	Place           string     `json:"place" gorm:"not null"`
-	AmountOfPlaces  float64    `json:"amount" gorm:"not null"`
-	IsOpened        bool       `json:"is_opened" gorm:"not null;default:true"`
-	TourID          uuid.UUID  `json:"tour_id" gorm:"type:uuid;index"`
-	Purchases       []Purchase `gorm:"foreignKey:TourEventID;references:ID"`
-	InstaPostURL    string     `json:"insta_post_url"`
-	TelegramChatURL string     `json:"telegram_chat_url"`
+	Date            time.Time    `json:"date" gorm:"not null;uniqueIndex:idx_tour_event_occurrence,priority:2,where:deleted_at IS NULL"`
+	Price           float64      `json:"price" gorm:"not null"`
+	Place           string       `json:"place" gorm:"not null"`
+	AmountOfPlaces  float64      `json:"amount" gorm:"not null"`
+	IsOpened        bool         `json:"is_opened" gorm:"not null;default:true"`
+	CancelledAt     *time.Time   `json:"cancelled_at"`
+	TourID          uuid.UUID    `json:"tour_id" gorm:"type:uuid;index"`
+	ScheduleID      *uuid.UUID   `json:"schedule_id" gorm:"type:uuid;uniqueIndex:idx_tour_event_occurrence,priority:1,where:deleted_at IS NULL"`
+	ScheduledDate   *time.Time   `json:"scheduled_date"`
+	Detached        bool         `json:"detached" gorm:"not null;default:false"`
+	Purchases       []Purchase   `gorm:"foreignKey:TourEventID;references:ID"`
+	TicketTypes     []TicketType `json:"ticket_types" gorm:"foreignKey:TourEventID;references:ID;constraint:OnDelete:CASCADE;"`
+	InstaPostURL    string       `json:"insta_post_url"`
+	TelegramChatURL string       `json:"telegram_chat_url"`
 }
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Kinds of media uploaded in chunks.
+const (
+	UploadKindVideo    = "video"
+	UploadKindPanorama = "panorama"
+)
+
+// Upload session statuses. An Open session takes chunks until it has Size
+// bytes and becomes Completed once its file is attached to the tour, or Failed
+// when the file is invalid.
+const (
+	UploadStatusOpen      = "Open"
+	UploadStatusCompleted = "Completed"
+	UploadStatusFailed    = "Failed"
+)
+
+// UploadSession is a resumable upload of a video or panorama of a tour. Offset
+// is the number of bytes received so far, chunks continue from it. Checksum is
+// the optional SHA-256 of the whole file in hex, MediaID and MediaURL point to
+// the attached Video or Panorama once the session is Completed.
+type UploadSession struct {
+	gorm.Model `swaggerignore:"true"`
+	ID         uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourID     uuid.UUID  `json:"tour_id" gorm:"type:uuid;index"`
+	Tour       Tour       `json:"-" gorm:"foreignKey:TourID;constraint:OnDelete:CASCADE;"`
+	OwnerID    uuid.UUID  `json:"owner_id" gorm:"type:uuid;index"`
+	Kind       string     `json:"kind" gorm:"not null"`
+	Filename   string     `json:"filename"`
+	Size       int64      `json:"size" gorm:"not null"`
+	Offset     int64      `json:"offset" gorm:"column:upload_offset;not null;default:0"`
+	Checksum   string     `json:"checksum"`
+	Status     string     `json:"status" gorm:"not null;index"`
+	Error      string     `json:"error,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`
+	MediaID    *uuid.UUID `json:"media_id" gorm:"type:uuid"`
+	MediaURL   string     `json:"media_url"`
+}
+
+// UploadChunk is a received part of an upload session, stored in the media
+// store under Key until the session completes.
+type UploadChunk struct {
+	gorm.Model      `swaggerignore:"true"`
+	ID              uuid.UUID     `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	UploadSessionID uuid.UUID     `json:"upload_session_id" gorm:"type:uuid;index"`
+	UploadSession   UploadSession `json:"-" gorm:"foreignKey:UploadSessionID;constraint:OnDelete:CASCADE;"`
+	Offset          int64         `json:"offset" gorm:"column:chunk_offset;not null"`
+	Size            int64         `json:"size" gorm:"not null"`
+	Key             string        `json:"-" gorm:"not null"`
+}
+
+type CreateUploadDTO struct {
+	Kind     string `json:"kind" binding:"required"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size" binding:"required"`
+	Checksum string `json:"checksum"`
+}
@@ -5,6 +5,10 @@ import (
 	"gorm.io/gorm"
 )
 
+const UserRoleAdmin = "admin"
+
+// User is an account. CalendarToken is the secret in the URLs of the
+// calendar feeds of the user, it is set the first time they are asked for.
 type User struct {
 	gorm.Model          `swaggerignore:"true"`
 	ID                  uuid.UUID       `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
@@ -16,6 +20,7 @@ type User struct {
 	PurchasedTourEvents []Purchase      `gorm:"foreignKey:UserID;references:ID"`
 	FavoriteTours       []UserFavorites `gorm:"foreignKey:UserID;references:ID"`
 	AvatarURL           string
+	CalendarToken       *string `json:"-" gorm:"uniqueIndex"`
 }
 
 type UserFavorites struct {
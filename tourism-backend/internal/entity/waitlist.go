@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Waitlist entry statuses. A Waiting entry becomes Offered when seats free up
+// and the offer either gets Claimed or Expired. Left entries were withdrawn by the user.
+const (
+	WaitlistStatusWaiting = "Waiting"
+	WaitlistStatusOffered = "Offered"
+	WaitlistStatusClaimed = "Claimed"
+	WaitlistStatusExpired = "Expired"
+	WaitlistStatusLeft    = "Left"
+)
+
+// WaitlistEntry is a user's place in line for a sold-out tour event. Seats of an
+// Offered entry are taken out of TourEvent.AmountOfPlaces until the offer ends.
+type WaitlistEntry struct {
+	gorm.Model     `swaggerignore:"true"`
+	ID             uuid.UUID  `json:"ID" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`
+	TourEventID    uuid.UUID  `json:"tour_event_id" gorm:"type:uuid;index"`
+	TourEvent      TourEvent  `json:"-" gorm:"foreignKey:TourEventID;constraint:OnDelete:CASCADE;"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	Quantity       int        `json:"quantity" gorm:"not null;default:1"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at"`
+}
+
+// PickWaitlistOffers returns the entries of waiting, in line order, that get an
+// offer of the free places. An entry asking for more places than are left is
+// passed over, so a large group does not hold up smaller ones behind it. It
+// also returns the places left after the offers.
+func PickWaitlistOffers(waiting []*WaitlistEntry, places float64) ([]*WaitlistEntry, float64) {
+	var picked []*WaitlistEntry
+	for _, entry := range waiting {
+		if float64(entry.Quantity) > places {
+			continue
+		}
+		places -= float64(entry.Quantity)
+		picked = append(picked, entry)
+	}
+	return picked, places
+}
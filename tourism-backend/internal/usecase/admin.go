@@ -18,10 +18,13 @@ func NewAdminUseCase(r *repo.AdminRepo) *AdminUseCase {
 	}
 }
 
-func (a *AdminUseCase) GetUsers() ([]*entity.User, error) {
-	users, err := a.repo.GetUsers()
+func (a *AdminUseCase) GetUsers(page *entity.PageQuery) (*entity.UserPage, error) {
+	if err := preparePage(page, _userSorts); err != nil {
+		return nil, err
+	}
+	users, next, err := a.repo.GetUsers(page)
 	if err != nil {
 		return nil, fmt.Errorf("get users: %w", err)
 	}
-	return users, nil
+	return &entity.UserPage{Users: users, NextCursor: encodeCursor(next)}, nil
 }
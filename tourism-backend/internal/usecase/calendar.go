@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/ical"
+)
+
+// _calendarRefreshInterval tells calendar apps how often to fetch a feed again.
+const _calendarRefreshInterval = time.Hour
+
+var ErrCalendarNotFound = errors.New("calendar not found")
+
+func newCalendarToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("generate calendar token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// GetCalendarToken returns the secret in the calendar feed URLs of the user,
+// creating it on first use.
+func (t *TourismUseCase) GetCalendarToken(userID uuid.UUID) (string, error) {
+	token, err := newCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	return t.repo.GetCalendarToken(userID, token)
+}
+
+// ResetCalendarToken gives the user a new calendar token. Subscriptions to the
+// feeds under the old one stop working.
+func (t *TourismUseCase) ResetCalendarToken(userID uuid.UUID) (string, error) {
+	token, err := newCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	if err := t.repo.SetCalendarToken(userID, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetPurchaseCalendar returns the feed of the paid purchases of the user with
+// the calendar token.
+func (t *TourismUseCase) GetPurchaseCalendar(token string) (*ical.Calendar, error) {
+	user, err := t.calendarUser(token)
+	if err != nil {
+		return nil, err
+	}
+	purchases, err := t.repo.GetCalendarPurchases(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calendar := &ical.Calendar{Name: "My tours", RefreshInterval: _calendarRefreshInterval}
+	for _, purchase := range purchases {
+		tourEvent := purchase.TourEvent
+		description := fmt.Sprintf("Places: %d", purchase.Quantity)
+		geo := tourGeo(&tourEvent.Tour)
+		if geo != nil {
+			description += fmt.Sprintf("\nMeeting point: %.6f, %.6f", geo.Latitude, geo.Longitude)
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("purchase-%s@tourism-backend", purchase.ID),
+			Stamp:       now,
+			Start:       tourEvent.Date,
+			Summary:     tourEvent.Tour.Name,
+			Description: description,
+			Location:    tourEvent.Place,
+			Geo:         geo,
+			Status:      tourEventStatus(&tourEvent),
+		})
+	}
+	return calendar, nil
+}
+
+// GetTourEventCalendar returns the feed of the events of the tours of the
+// provider with the calendar token, with their sold and free places.
+func (t *TourismUseCase) GetTourEventCalendar(token string) (*ical.Calendar, error) {
+	user, err := t.calendarUser(token)
+	if err != nil {
+		return nil, err
+	}
+	tourEvents, sold, err := t.repo.GetProviderTourEvents(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calendar := &ical.Calendar{Name: "Tour events", RefreshInterval: _calendarRefreshInterval}
+	for _, tourEvent := range tourEvents {
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("tour-event-%s@tourism-backend", tourEvent.ID),
+			Stamp:       now,
+			Start:       tourEvent.Date,
+			Summary:     tourEvent.Tour.Name,
+			Description: fmt.Sprintf("Sold places: %d\nFree places: %.0f", sold[tourEvent.ID], tourEvent.AmountOfPlaces),
+			Location:    tourEvent.Place,
+			Geo:         tourGeo(&tourEvent.Tour),
+			Status:      tourEventStatus(tourEvent),
+		})
+	}
+	return calendar, nil
+}
+
+func (t *TourismUseCase) calendarUser(token string) (*entity.User, error) {
+	if token == "" {
+		return nil, ErrCalendarNotFound
+	}
+	user, err := t.repo.GetUserByCalendarToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrCalendarNotFound
+	}
+	return user, nil
+}
+
+func tourGeo(tour *entity.Tour) *ical.Geo {
+	if tour.TourLocation == nil {
+		return nil
+	}
+	return &ical.Geo{Latitude: tour.TourLocation.Latitude, Longitude: tour.TourLocation.Longitude}
+}
+
+func tourEventStatus(tourEvent *entity.TourEvent) string {
+	if tourEvent.CancelledAt != nil {
+		return ical.StatusCancelled
+	}
+	return ical.StatusConfirmed
+}
@@ -0,0 +1,518 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"mime/multipart"
+	"sort"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// fakeRepo keeps purchases, tour events, waitlist entries, check-ins and
+// reviews in memory.
+// Methods the tests do not need panic through the embedded nil TourismRepo.
+type fakeRepo struct {
+	TourismRepo
+	purchases  map[uuid.UUID]*entity.Purchase
+	tourEvents map[uuid.UUID]*entity.TourEvent
+	waitlist   map[uuid.UUID]*entity.WaitlistEntry
+	deleted    map[uuid.UUID]bool
+	promoCodes map[string]*entity.PromoCode
+	checkIns   []*entity.CheckIn
+	reviews    []*entity.Review
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		purchases:  make(map[uuid.UUID]*entity.Purchase),
+		tourEvents: make(map[uuid.UUID]*entity.TourEvent),
+		waitlist:   make(map[uuid.UUID]*entity.WaitlistEntry),
+		deleted:    make(map[uuid.UUID]bool),
+		promoCodes: make(map[string]*entity.PromoCode),
+	}
+}
+
+// addTourEvent stores an open tour event of a tour owned by ownerID.
+func (r *fakeRepo) addTourEvent(ownerID uuid.UUID, date time.Time, places float64) *entity.TourEvent {
+	tourID := uuid.New()
+	tourEvent := &entity.TourEvent{
+		ID:             uuid.New(),
+		Tour:           entity.Tour{ID: tourID, OwnerID: ownerID, Name: "Kolsai lakes"},
+		TourID:         tourID,
+		Date:           date,
+		AmountOfPlaces: places,
+		IsOpened:       true,
+	}
+	r.tourEvents[tourEvent.ID] = tourEvent
+	return tourEvent
+}
+
+// addPurchase stores a purchase of places seats of the tour event, the seats
+// are taken from the tour event while the purchase holds them.
+func (r *fakeRepo) addPurchase(tourEvent *entity.TourEvent, status string, places int) *entity.Purchase {
+	purchase := &entity.Purchase{
+		ID:          uuid.New(),
+		UserID:      uuid.New(),
+		User:        entity.User{Username: "traveller"},
+		TourEventID: tourEvent.ID,
+		Status:      status,
+		Quantity:    places,
+		Amount:      float64(places) * 10000,
+	}
+	r.purchases[purchase.ID] = purchase
+	return purchase
+}
+
+// addWaitlistEntry puts a new user in line for places seats of the tour event,
+// behind everybody added before.
+func (r *fakeRepo) addWaitlistEntry(tourEvent *entity.TourEvent, status string, places int) *entity.WaitlistEntry {
+	entry := &entity.WaitlistEntry{
+		ID:          uuid.New(),
+		TourEventID: tourEvent.ID,
+		UserID:      uuid.New(),
+		Quantity:    places,
+		Status:      status,
+	}
+	entry.CreatedAt = time.Unix(int64(len(r.waitlist)), 0)
+	if status == entity.WaitlistStatusOffered {
+		offerExpiresAt := time.Now().Add(time.Hour)
+		entry.OfferExpiresAt = &offerExpiresAt
+	}
+	r.waitlist[entry.ID] = entry
+	return entry
+}
+
+func (r *fakeRepo) GetPurchaseByID(purchaseID uuid.UUID) (*entity.Purchase, error) {
+	purchase, ok := r.purchases[purchaseID]
+	if !ok {
+		return nil, fmt.Errorf("get purchase by id: record not found")
+	}
+	loaded := *purchase
+	if tourEvent, ok := r.tourEvents[purchase.TourEventID]; ok {
+		loaded.TourEvent = *tourEvent
+	}
+	return &loaded, nil
+}
+
+func (r *fakeRepo) GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error) {
+	tourEvent, ok := r.tourEvents[id]
+	if !ok {
+		return nil, fmt.Errorf("record not found")
+	}
+	loaded := *tourEvent
+	return &loaded, nil
+}
+
+func (r *fakeRepo) UpdateTourEvent(tourEventID uuid.UUID, fields map[string]interface{}, capacity *float64) (bool, error) {
+	tourEvent := r.tourEvents[tourEventID]
+	if capacity != nil {
+		var held float64
+		for _, purchase := range r.purchases {
+			if purchase.TourEventID == tourEventID && (purchase.Status == entity.PurchaseStatusProcessing || purchase.Status == entity.PurchaseStatusPaid) {
+				held += float64(purchase.Quantity)
+			}
+		}
+		if *capacity < held {
+			return false, nil
+		}
+		tourEvent.AmountOfPlaces = *capacity - held
+	}
+	for column, value := range fields {
+		switch column {
+		case "date":
+			tourEvent.Date = value.(time.Time)
+		case "price":
+			tourEvent.Price = value.(float64)
+		case "place":
+			tourEvent.Place = value.(string)
+		}
+	}
+	return true, nil
+}
+
+func (r *fakeRepo) CancelTourEvent(tourEventID uuid.UUID, cancelledAt time.Time) ([]*entity.WaitlistEntry, error) {
+	tourEvent := r.tourEvents[tourEventID]
+	var ended []*entity.WaitlistEntry
+	for _, entry := range r.waitlist {
+		if entry.TourEventID != tourEventID || (entry.Status != entity.WaitlistStatusWaiting && entry.Status != entity.WaitlistStatusOffered) {
+			continue
+		}
+		if entry.Status == entity.WaitlistStatusOffered {
+			tourEvent.AmountOfPlaces += float64(entry.Quantity)
+		}
+		entry.Status = entity.WaitlistStatusExpired
+		ended = append(ended, entry)
+	}
+	tourEvent.IsOpened = false
+	tourEvent.CancelledAt = &cancelledAt
+	return ended, nil
+}
+
+func (r *fakeRepo) GetPaidPurchaserIDs(tourEventID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	for _, purchase := range r.purchases {
+		if purchase.TourEventID == tourEventID && purchase.Status == entity.PurchaseStatusPaid {
+			userIDs = append(userIDs, purchase.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (r *fakeRepo) GetActiveTourEventPurchases(tourEventID uuid.UUID) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	for _, purchase := range r.purchases {
+		if purchase.TourEventID != tourEventID {
+			continue
+		}
+		switch purchase.Status {
+		case entity.PurchaseStatusProcessing, entity.PurchaseStatusPaid, entity.PurchaseStatusCancelRequested:
+			loaded := *purchase
+			purchases = append(purchases, &loaded)
+		}
+	}
+	return purchases, nil
+}
+
+// GetTourEventsByTourID returns the events of a tour by date.
+func (r *fakeRepo) GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEvent, error) {
+	var tourEvents []*entity.TourEvent
+	for _, tourEvent := range r.tourEvents {
+		if tourEvent.TourID == tourID {
+			loaded := *tourEvent
+			tourEvents = append(tourEvents, &loaded)
+		}
+	}
+	sort.Slice(tourEvents, func(i, j int) bool { return tourEvents[i].Date.Before(tourEvents[j].Date) })
+	return tourEvents, nil
+}
+
+func (r *fakeRepo) GetActiveTourPurchases(tourID uuid.UUID, now time.Time) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	for _, tourEvent := range r.tourEvents {
+		if tourEvent.TourID != tourID || !tourEvent.Date.After(now) {
+			continue
+		}
+		active, _ := r.GetActiveTourEventPurchases(tourEvent.ID)
+		purchases = append(purchases, active...)
+	}
+	return purchases, nil
+}
+
+func (r *fakeRepo) DeleteTour(tourID uuid.UUID, now time.Time) (bool, error) {
+	if active, _ := r.GetActiveTourPurchases(tourID, now); len(active) > 0 {
+		return false, nil
+	}
+	r.deleted[tourID] = true
+	return true, nil
+}
+
+func (r *fakeRepo) GetWaitlistEntryByID(entryID uuid.UUID) (*entity.WaitlistEntry, error) {
+	entry, ok := r.waitlist[entryID]
+	if !ok {
+		return nil, fmt.Errorf("get waitlist entry by id: record not found")
+	}
+	loaded := *entry
+	return &loaded, nil
+}
+
+func (r *fakeRepo) CreateCheckIn(checkIn *entity.CheckIn) (*entity.CheckIn, bool, error) {
+	existing, _ := r.GetCheckIn(checkIn.PurchaseID, checkIn.Seat)
+	if existing != nil {
+		return existing, false, nil
+	}
+	checkIn.ID = uuid.New()
+	r.checkIns = append(r.checkIns, checkIn)
+	return checkIn, true, nil
+}
+
+func (r *fakeRepo) GetCheckIn(purchaseID uuid.UUID, seat int) (*entity.CheckIn, error) {
+	for _, checkIn := range r.checkIns {
+		if checkIn.PurchaseID == purchaseID && checkIn.Seat == seat {
+			return checkIn, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) GetAttendance(tourEventID uuid.UUID) ([]*entity.Purchase, []*entity.CheckIn, error) {
+	var purchases []*entity.Purchase
+	for _, purchase := range r.purchases {
+		if purchase.TourEventID == tourEventID && purchase.Status == entity.PurchaseStatusPaid {
+			purchases = append(purchases, purchase)
+		}
+	}
+	var checkIns []*entity.CheckIn
+	for _, checkIn := range r.checkIns {
+		if checkIn.TourEventID == tourEventID {
+			checkIns = append(checkIns, checkIn)
+		}
+	}
+	return purchases, checkIns, nil
+}
+
+func (r *fakeRepo) ReleasePurchase(purchaseID uuid.UUID, from, to string) error {
+	if err := r.UpdatePurchaseStatus(purchaseID, from, to); err != nil {
+		return err
+	}
+	purchase := r.purchases[purchaseID]
+	tourEvent := r.tourEvents[purchase.TourEventID]
+	tourEvent.AmountOfPlaces += float64(purchase.Quantity)
+	for _, item := range purchase.Items {
+		for i := range tourEvent.TicketTypes {
+			if tourEvent.TicketTypes[i].ID == item.TicketTypeID {
+				tourEvent.TicketTypes[i].HeldPlaces -= item.Quantity
+			}
+		}
+	}
+	return nil
+}
+
+// GetExpiredSeatHolds returns copies of the processing purchases whose hold ran out.
+func (r *fakeRepo) GetExpiredSeatHolds(now time.Time, _ time.Duration) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	for _, purchase := range r.purchases {
+		if purchase.Status == entity.PurchaseStatusProcessing && purchase.HoldExpiresAt != nil && purchase.HoldExpiresAt.Before(now) {
+			loaded := *purchase
+			purchases = append(purchases, &loaded)
+		}
+	}
+	return purchases, nil
+}
+
+func (r *fakeRepo) UpdatePurchaseStatus(purchaseID uuid.UUID, from, to string) error {
+	purchase, ok := r.purchases[purchaseID]
+	if !ok || purchase.Status != from {
+		return fmt.Errorf("purchase %s is not in status %s", purchaseID, from)
+	}
+	purchase.Status = to
+	return nil
+}
+
+// CreatePurchase takes the places from the tour event like the Postgres repo,
+// which refuses a purchase of more places than are free.
+func (r *fakeRepo) CreatePurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error) {
+	tourEvent := r.tourEvents[purchase.TourEventID]
+	if tourEvent.AmountOfPlaces < float64(purchase.Quantity) {
+		return nil, fmt.Errorf("create purchase transaction failed: not enough places left for %d travelers", purchase.Quantity)
+	}
+	tourEvent.AmountOfPlaces -= float64(purchase.Quantity)
+	if promoCode != nil {
+		purchase.PromoCodeID = &promoCode.ID
+		purchase.Discount = promoCode.Discount(purchase.Amount)
+		purchase.Amount -= purchase.Discount
+	}
+	purchase.ID = uuid.New()
+	r.purchases[purchase.ID] = purchase
+	return r.GetPurchaseByID(purchase.ID)
+}
+
+func (r *fakeRepo) GetPricingRulesByTourEventID(uuid.UUID) ([]*entity.PricingRule, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
+	purchase, err := r.GetPurchaseByID(purchaseID)
+	if err != nil || purchase.UserID != userID || purchase.Status != entity.PurchaseStatusPaid {
+		return nil, fmt.Errorf("tour event have not been paid or not exists")
+	}
+	return purchase, nil
+}
+
+func (r *fakeRepo) PayTourEvent(purchase *entity.Purchase) *entity.Purchase {
+	if err := r.UpdatePurchaseStatus(purchase.ID, entity.PurchaseStatusProcessing, entity.PurchaseStatusPaid); err != nil {
+		return nil
+	}
+	purchase.Status = entity.PurchaseStatusPaid
+	return purchase
+}
+
+func (r *fakeRepo) GetPromoCodeByCode(code string) (*entity.PromoCode, error) {
+	return r.promoCodes[code], nil
+}
+
+func (r *fakeRepo) CountPromoCodeUses(promoCodeID, userID uuid.UUID) (int64, int64, error) {
+	var total, byUser int64
+	for _, purchase := range r.purchases {
+		if purchase.PromoCodeID != nil && *purchase.PromoCodeID == promoCodeID {
+			total++
+			if purchase.UserID == userID {
+				byUser++
+			}
+		}
+	}
+	return total, byUser, nil
+}
+
+func (r *fakeRepo) ApplyPromoCodeToPurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error) {
+	stored := r.purchases[purchase.ID]
+	stored.PromoCodeID = &promoCode.ID
+	stored.Discount = promoCode.Discount(stored.Amount)
+	stored.Amount -= stored.Discount
+	return r.GetPurchaseByID(purchase.ID)
+}
+
+func (r *fakeRepo) GetHeldSeats(tourEventID uuid.UUID) (float64, error) {
+	var held float64
+	for _, purchase := range r.purchases {
+		if purchase.TourEventID == tourEventID && (purchase.Status == entity.PurchaseStatusProcessing || purchase.Status == entity.PurchaseStatusPaid) {
+			held += float64(purchase.Quantity)
+		}
+	}
+	for _, entry := range r.waitlist {
+		if entry.TourEventID == tourEventID && entry.Status == entity.WaitlistStatusOffered {
+			held += float64(entry.Quantity)
+		}
+	}
+	return held, nil
+}
+
+func (r *fakeRepo) GetActiveWaitlistEntry(userID, tourEventID uuid.UUID) (*entity.WaitlistEntry, error) {
+	for _, entry := range r.waitlist {
+		if entry.UserID == userID && entry.TourEventID == tourEventID &&
+			(entry.Status == entity.WaitlistStatusWaiting || entry.Status == entity.WaitlistStatusOffered) {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepo) CreateWaitlistEntry(entry *entity.WaitlistEntry) (*entity.WaitlistEntry, error) {
+	entry.ID = uuid.New()
+	entry.CreatedAt = time.Unix(int64(len(r.waitlist)), 0)
+	r.waitlist[entry.ID] = entry
+	return entry, nil
+}
+
+func (r *fakeRepo) GetExpiredWaitlistOffers(now time.Time) ([]*entity.WaitlistEntry, error) {
+	var entries []*entity.WaitlistEntry
+	for _, entry := range r.waitlist {
+		if entry.Status == entity.WaitlistStatusOffered && entry.OfferExpiresAt.Before(now) {
+			loaded := *entry
+			entries = append(entries, &loaded)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeRepo) ReleaseWaitlistOffer(entryID uuid.UUID, to string) error {
+	entry := r.waitlist[entryID]
+	if entry.Status != entity.WaitlistStatusOffered {
+		return fmt.Errorf("waitlist entry %s has no open offer", entryID)
+	}
+	entry.Status = to
+	r.tourEvents[entry.TourEventID].AmountOfPlaces += float64(entry.Quantity)
+	return nil
+}
+
+// OfferWaitlistSeats offers the free places to the waiting entries in the
+// order they were added.
+func (r *fakeRepo) OfferWaitlistSeats(tourEventID uuid.UUID, offerExpiresAt time.Time) ([]*entity.WaitlistEntry, error) {
+	tourEvent := r.tourEvents[tourEventID]
+	if !tourEvent.IsOpened || tourEvent.AmountOfPlaces <= 0 {
+		return nil, nil
+	}
+	var waiting []*entity.WaitlistEntry
+	for _, entry := range r.waitlist {
+		if entry.TourEventID == tourEventID && entry.Status == entity.WaitlistStatusWaiting {
+			waiting = append(waiting, entry)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].CreatedAt.Before(waiting[j].CreatedAt) })
+
+	offered, places := entity.PickWaitlistOffers(waiting, tourEvent.AmountOfPlaces)
+	for _, entry := range offered {
+		entry.Status = entity.WaitlistStatusOffered
+		entry.OfferExpiresAt = &offerExpiresAt
+	}
+	tourEvent.AmountOfPlaces = places
+	return offered, nil
+}
+
+func (r *fakeRepo) HasAttendedTourEvent(userID, tourEventID uuid.UUID, now time.Time) (bool, error) {
+	for _, purchase := range r.purchases {
+		if purchase.UserID == userID && purchase.TourEventID == tourEventID &&
+			purchase.Status == entity.PurchaseStatusPaid && r.tourEvents[tourEventID].Date.Before(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) HasReviewedTourEvent(userID, tourEventID uuid.UUID) (bool, error) {
+	for _, review := range r.reviews {
+		if review.UserID == userID && review.TourEventID == tourEventID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) CreateReview(review *entity.Review, _ []*multipart.FileHeader) (*entity.Review, error) {
+	review.ID = uuid.New()
+	r.reviews = append(r.reviews, review)
+	return review, nil
+}
+
+func (r *fakeRepo) GetTourRatings(tourIDs []uuid.UUID) (map[uuid.UUID]*entity.TourRating, error) {
+	ratings := make(map[uuid.UUID]*entity.TourRating, len(tourIDs))
+	for _, tourID := range tourIDs {
+		ratings[tourID] = entity.NewTourRating()
+	}
+	for _, review := range r.reviews {
+		if rating, ok := ratings[review.TourID]; ok {
+			rating.Add(review.Rating, 1)
+		}
+	}
+	return ratings, nil
+}
+
+// fakeRefunder refunds every purchase unless err is set.
+type fakeRefunder struct {
+	err      error
+	refunded []uuid.UUID
+}
+
+func (f *fakeRefunder) Refund(purchase *entity.Purchase) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.refunded = append(f.refunded, purchase.ID)
+	return nil
+}
+
+// fakeProducer records the notifications published by the use case.
+type fakeProducer struct {
+	sarama.SyncProducer
+	notifications []entity.Notification
+}
+
+func (f *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	value, err := msg.Value.Encode()
+	if err != nil {
+		return 0, 0, err
+	}
+	var notification entity.Notification
+	if err := json.Unmarshal(value, &notification); err != nil {
+		return 0, 0, err
+	}
+	f.notifications = append(f.notifications, notification)
+	return 0, 0, nil
+}
+
+// topics returns the topic of every published notification, in order.
+func (f *fakeProducer) topics() []string {
+	topics := make([]string, 0, len(f.notifications))
+	for _, notification := range f.notifications {
+		topics = append(topics, notification.Topic)
+	}
+	return topics
+}
+
+func newTestUseCase(repo *fakeRepo) (*TourismUseCase, *fakeRefunder, *fakeProducer) {
+	refunder := &fakeRefunder{}
+	producer := &fakeProducer{}
+	return &TourismUseCase{repo: repo, producer: producer, refunder: refunder}, refunder, producer
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"tourism-backend/internal/entity"
+)
+
+const (
+	_defaultNearbyRadiusKm = 50
+	_maxNearbyRadiusKm     = 1000
+	_defaultNearbyLimit    = 20
+	_maxNearbyLimit        = 100
+)
+
+var (
+	ErrInvalidGeoQuery = errors.New("invalid location query")
+	ErrInvalidLocation = errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+)
+
+// GetToursNearby returns the tours within a radius or a map viewport, nearest
+// first, with their ratings.
+func (t *TourismUseCase) GetToursNearby(query *entity.GeoQuery) ([]*entity.TourNearby, error) {
+	if err := prepareGeoQuery(query); err != nil {
+		return nil, err
+	}
+
+	results, err := t.repo.GetToursNearby(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tours := make([]*entity.Tour, 0, len(results))
+	for _, result := range results {
+		tours = append(tours, &result.Tour)
+	}
+	if err := t.setTourRatings(tours); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// prepareGeoQuery validates the query and fills in the default radius, origin
+// and paging.
+func prepareGeoQuery(query *entity.GeoQuery) error {
+	if bounds := query.Bounds; bounds != nil {
+		if !validLatitude(bounds.North) || !validLatitude(bounds.South) || bounds.South > bounds.North ||
+			!validLongitude(bounds.East) || !validLongitude(bounds.West) {
+			return fmt.Errorf("%w: bounds must have south <= north within [-90, 90] and east, west within [-180, 180]", ErrInvalidGeoQuery)
+		}
+		if query.Origin == nil {
+			query.Origin = boundsCenter(bounds)
+		}
+	} else {
+		if query.Origin == nil {
+			return fmt.Errorf("%w: latitude and longitude are required", ErrInvalidGeoQuery)
+		}
+		if query.RadiusKm == 0 {
+			query.RadiusKm = _defaultNearbyRadiusKm
+		}
+		if query.RadiusKm < 0 || query.RadiusKm > _maxNearbyRadiusKm {
+			return fmt.Errorf("%w: radius must be between 0 and %d km", ErrInvalidGeoQuery, _maxNearbyRadiusKm)
+		}
+	}
+	if !validLatitude(query.Origin.Latitude) || !validLongitude(query.Origin.Longitude) {
+		return fmt.Errorf("%w: latitude must be within [-90, 90] and longitude within [-180, 180]", ErrInvalidGeoQuery)
+	}
+
+	if query.Limit < 1 {
+		query.Limit = _defaultNearbyLimit
+	}
+	if query.Limit > _maxNearbyLimit {
+		query.Limit = _maxNearbyLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	return nil
+}
+
+// boundsCenter returns the middle of a viewport, also when it crosses the antimeridian.
+func boundsCenter(bounds *entity.GeoBounds) *entity.GeoPoint {
+	east := bounds.East
+	if bounds.West > east {
+		east += 360
+	}
+	longitude := (bounds.West + east) / 2
+	if longitude > 180 {
+		longitude -= 360
+	}
+	return &entity.GeoPoint{Latitude: (bounds.North + bounds.South) / 2, Longitude: longitude}
+}
+
+func validLatitude(latitude float64) bool {
+	return latitude >= -90 && latitude <= 90
+}
+
+func validLongitude(longitude float64) bool {
+	return longitude >= -180 && longitude <= 180
+}
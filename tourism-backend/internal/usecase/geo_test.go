@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"tourism-backend/internal/entity"
+)
+
+func TestPrepareGeoQueryCentersBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds entity.GeoBounds
+		want   entity.GeoPoint
+	}{
+		{
+			name:   "regular viewport",
+			bounds: entity.GeoBounds{North: 44, South: 42, East: 78, West: 76},
+			want:   entity.GeoPoint{Latitude: 43, Longitude: 77},
+		},
+		{
+			name:   "viewport across the antimeridian",
+			bounds: entity.GeoBounds{North: 10, South: -10, East: -170, West: 170},
+			want:   entity.GeoPoint{Latitude: 0, Longitude: 180},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &entity.GeoQuery{Bounds: &tt.bounds}
+			if err := prepareGeoQuery(query); err != nil {
+				t.Fatal(err)
+			}
+			if *query.Origin != tt.want {
+				t.Fatalf("origin = %+v, want %+v", *query.Origin, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareGeoQueryRejectsInvalidArea(t *testing.T) {
+	tests := []struct {
+		name  string
+		query entity.GeoQuery
+	}{
+		{name: "radius without a point", query: entity.GeoQuery{RadiusKm: 10}},
+		{name: "latitude out of range", query: entity.GeoQuery{Origin: &entity.GeoPoint{Latitude: 91}}},
+		{name: "radius too large", query: entity.GeoQuery{Origin: &entity.GeoPoint{}, RadiusKm: 5000}},
+		{name: "south above north", query: entity.GeoQuery{Bounds: &entity.GeoBounds{North: 10, South: 20}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := prepareGeoQuery(&tt.query); !errors.Is(err, ErrInvalidGeoQuery) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidGeoQuery)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package usecase
 
 import (
 	"github.com/google/uuid"
+	"io"
 	"mime/multipart"
+	"time"
 	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/ical"
 )
 
 //go:generate mockgen -source=interfaces.go -destination=./mocks_test.go -package=usecase_test
@@ -14,33 +17,205 @@ type (
 	TourismInterface interface {
 		// CreateTour GetTourByID(ctx context.Context, id uuid.UUID) (entity.Tour, error)
 		CreateTour(tour *entity.Tour, imageFiles []*multipart.FileHeader, videFiles []*multipart.FileHeader) (*entity.Tour, error)
-		GetTours() ([]entity.Tour, error)
+		GetTours(page *entity.PageQuery) (*entity.TourPage, error)
 		GetTourByID(ID string) (*entity.Tour, error)
 		GetAllCategories() ([]entity.Category, error)
 		CreateTourEvent(tourEvent *entity.TourEvent) (*entity.TourEvent, error)
+		UpdateTourEvent(providerID, tourEventID uuid.UUID, dto *entity.UpdateTourEventDTO) (*entity.TourEvent, error)
+		CancelTourEvent(providerID, tourEventID uuid.UUID) (*entity.TourEvent, error)
+		CreateTourSchedule(tourID uuid.UUID, dto *entity.CreateTourScheduleDTO) (*entity.TourSchedule, error)
+		GetTourSchedules(tourID uuid.UUID) ([]*entity.TourSchedule, error)
+		UpdateTourSchedule(providerID, scheduleID uuid.UUID, dto *entity.UpdateTourScheduleDTO) (*entity.TourSchedule, error)
+		DeleteTourSchedule(providerID, scheduleID uuid.UUID) error
 		CheckTourOwner(tourID uuid.UUID, userID uuid.UUID) bool
 		PayTourEvent(purchase *entity.Purchase) error
-		CreatePurchase(purchase *entity.Purchase) (*entity.Purchase, error)
+		CreatePurchase(purchase *entity.Purchase, promoCode string) (*entity.Purchase, error)
 		CreateTourCategory(tourCategory *entity.CreateTourCategoryDTO) (*entity.TourCategory, error)
 		CreateTourLocation(tourLocation *entity.CreateTourLocationDTO) (*entity.TourLocation, error)
-		GetTourLocationByID(id uuid.UUID) (*entity.TourLocation, error)
-		GetFilteredTourEvents(*entity.TourEventFilter) ([]*entity.TourEvent, error)
+		GetTourLocationByTourID(tourID uuid.UUID) (*entity.TourLocation, error)
+		GetToursNearby(query *entity.GeoQuery) ([]*entity.TourNearby, error)
+		SetWaypoints(tourID uuid.UUID, waypoints []entity.WaypointDTO) ([]*entity.Waypoint, error)
+		ImportTourTrack(tourID uuid.UUID, filename string, data []byte) (*entity.TourTrack, error)
+		DeleteTourTrack(tourID uuid.UUID) error
+		GetTourRouteMap(tourID uuid.UUID) (*entity.FeatureCollection, error)
+		CreateUpload(userID, tourID uuid.UUID, dto *entity.CreateUploadDTO) (*entity.UploadSession, error)
+		GetUpload(userID, uploadID uuid.UUID) (*entity.UploadSession, error)
+		AppendUpload(userID, uploadID uuid.UUID, offset int64, checksum string, body io.Reader) (*entity.UploadSession, error)
+		CancelUpload(userID, uploadID uuid.UUID) error
+		DeleteTourMedia(tourID uuid.UUID, kind string, mediaID uuid.UUID) error
+		ReorderTourMedia(tourID uuid.UUID, kind string, ids []uuid.UUID) error
+		SetTourCover(tourID, imageID uuid.UUID) error
+		GetFilteredTourEvents(filter *entity.TourEventFilter, page *entity.PageQuery) (*entity.TourEventPage, error)
 		GetWeatherByTourEventID(tourEventID uuid.UUID) (*entity.WeatherInfo, error)
 		GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error)
-		AddFilesToTourByTourID(panoramasEntity []*entity.Panorama) ([]*entity.Panorama, error)
-		ChangeTour(tour *entity.Tour) (*entity.Tour, error)
+		AddFilesToTourByTourID(tourID uuid.UUID, panoramas []*multipart.FileHeader) ([]*entity.Panorama, error)
+		UpdateTour(tourID uuid.UUID, dto *entity.UpdateTourDTO) (*entity.Tour, error)
+		ArchiveTour(tourID uuid.UUID) error
+		RestoreTour(tourID uuid.UUID) error
+		DeleteTour(tourID uuid.UUID, refund bool) error
 		GetMe(id uuid.UUID) (*entity.User, error)
+		GetMyPurchases(userID uuid.UUID, page *entity.PageQuery) (*entity.PurchasePage, error)
 		LikeTour(userID uuid.UUID, tourID uuid.UUID) (*entity.UserFavorites, error)
 		TrackUserAction(userID uuid.UUID, tourEventID uuid.UUID)
 		GetMyAvatar(userID uuid.UUID) (string, error)
-		SaveMyAvatar(userID uuid.UUID, avatar string) error
-		GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.PurchaseQRDTO, error)
-		CheckPurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error)
+		GetCalendarToken(userID uuid.UUID) (string, error)
+		ResetCalendarToken(userID uuid.UUID) (string, error)
+		GetPurchaseCalendar(token string) (*ical.Calendar, error)
+		GetTourEventCalendar(token string) (*ical.Calendar, error)
+		SaveMyAvatar(userID uuid.UUID, avatar *multipart.FileHeader) (string, error)
+		GetPurchaseQR(userID, purchaseID uuid.UUID) ([]*entity.PurchaseQRDTO, error)
+		GetTicketKey() *entity.TicketKeyDTO
+		CheckTicket(providerID uuid.UUID, token string) (*entity.CheckedTicketDTO, error)
+		CheckInTicket(providerID uuid.UUID, token string) (*entity.CheckedTicketDTO, error)
+		GetTourEventAttendance(providerID, tourEventID uuid.UUID) (*entity.AttendanceDTO, error)
 		GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEvent, error)
+		CancelPurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error)
+		CancelPurchaseByProvider(providerID, purchaseID uuid.UUID) (*entity.Purchase, error)
+		GetPayablePurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error)
+		AttachPaymentIntent(userID, purchaseID uuid.UUID, paymentIntentID string) error
+		RecordPaymentIntent(purchaseID uuid.UUID, paymentIntentID string) error
+		HandlePaymentSucceeded(paymentIntentID string) error
+		HandlePaymentFailed(paymentIntentID string) error
+		FailPurchase(purchase *entity.Purchase) error
+		JoinWaitlist(userID uuid.UUID, dto *entity.JoinWaitlistDTO) (*entity.WaitlistEntry, error)
+		GetMyWaitlist(userID uuid.UUID) ([]*entity.WaitlistEntry, error)
+		LeaveWaitlist(userID, entryID uuid.UUID) error
+		ClaimWaitlistOffer(userID, entryID uuid.UUID, items []entity.PurchaseItem) (*entity.Purchase, error)
+		CreatePromoCode(creatorID uuid.UUID, role string, dto *entity.CreatePromoCodeDTO) (*entity.PromoCode, error)
+		GetMyPromoCodes(creatorID uuid.UUID) ([]*entity.PromoCode, error)
+		ApplyPromoCode(userID, purchaseID uuid.UUID, code string) (*entity.Purchase, error)
+		CreatePricingRule(providerID uuid.UUID, dto *entity.CreatePricingRuleDTO) (*entity.PricingRule, error)
+		DeletePricingRule(providerID, ruleID uuid.UUID) error
+		QuotePrice(userID uuid.UUID, purchase *entity.Purchase, promoCode string) (*entity.PriceQuote, error)
+		CreateReview(review *entity.Review, photoFiles []*multipart.FileHeader) (*entity.Review, error)
+		ReplyToReview(providerID, reviewID uuid.UUID, reply string) (*entity.Review, error)
+		GetTourReviews(filter *entity.ReviewFilter) (*entity.ReviewPage, error)
+		SearchTours(query string, limit, offset int) ([]*entity.TourSearchResult, error)
+	}
+
+	// TourismRepo -.
+	TourismRepo interface {
+		AddFileToTourByTourID(tourID uuid.UUID, files []*multipart.FileHeader) ([]*entity.Panorama, error)
+		AppendUploadChunk(session *entity.UploadSession, offset int64, data []byte) (bool, error)
+		ApplyPromoCodeToPurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error)
+		AttachUpload(session *entity.UploadSession) (bool, error)
+		CancelTourEvent(tourEventID uuid.UUID, cancelledAt time.Time) ([]*entity.WaitlistEntry, error)
+		CheckTourOwner(tourID uuid.UUID, userID uuid.UUID) bool
+		ClaimPaymentJob(lease time.Duration) (*entity.PaymentJob, error)
+		ClaimWaitlistOffer(entryID uuid.UUID, purchase *entity.Purchase) (*entity.Purchase, error)
+		CollectUnreferencedMedia(olderThan time.Time) (int, error)
+		CompletePaymentJob(job *entity.PaymentJob) (bool, error)
+		CountPromoCodeUses(promoCodeID, userID uuid.UUID) (int64, int64, error)
+		CreateCheckIn(checkIn *entity.CheckIn) (*entity.CheckIn, bool, error)
+		CreatePricingRule(rule *entity.PricingRule) (*entity.PricingRule, error)
+		CreatePromoCode(promoCode *entity.PromoCode) (*entity.PromoCode, error)
+		CreatePurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error)
+		CreateReview(review *entity.Review, photoFiles []*multipart.FileHeader) (*entity.Review, error)
+		CreateTour(tour *entity.Tour, imageFiles []*multipart.FileHeader, videoFiles []*multipart.FileHeader) (*entity.Tour, error)
+		CreateTourCategory(tourCategory *entity.CreateTourCategoryDTO) (*entity.TourCategory, error)
+		CreateTourEvent(tourEvent *entity.TourEvent) (*entity.TourEvent, error)
+		CreateTourLocation(tourLocation *entity.CreateTourLocationDTO) (*entity.TourLocation, error)
+		CreateTourSchedule(schedule *entity.TourSchedule) (*entity.TourSchedule, error)
+		CreateUploadSession(session *entity.UploadSession) error
+		CreateUserAction(userID, tourEventID uuid.UUID)
+		CreateWaitlistEntry(entry *entity.WaitlistEntry) (*entity.WaitlistEntry, error)
+		DeletePricingRule(ruleID uuid.UUID) error
+		DeleteTour(tourID uuid.UUID, now time.Time) (bool, error)
+		DeleteTourMedia(tourID uuid.UUID, kind string, mediaID uuid.UUID) (bool, error)
+		DeleteTourSchedule(scheduleID uuid.UUID, from time.Time) error
+		DeleteTourTrack(tourID uuid.UUID) error
+		DeleteUploadSession(session *entity.UploadSession) error
+		FailPaymentJob(job *entity.PaymentJob, cause error) (bool, error)
+		FailUpload(session *entity.UploadSession, reason string) error
+		GetActiveTourEventPurchases(tourEventID uuid.UUID) ([]*entity.Purchase, error)
+		GetActiveTourPurchases(tourID uuid.UUID, now time.Time) ([]*entity.Purchase, error)
+		GetActiveWaitlistEntry(userID, tourEventID uuid.UUID) (*entity.WaitlistEntry, error)
+		GetAllCategories() ([]entity.Category, error)
+		GetAttendance(tourEventID uuid.UUID) ([]*entity.Purchase, []*entity.CheckIn, error)
+		GetCalendarPurchases(userID uuid.UUID) ([]*entity.Purchase, error)
+		GetCalendarToken(userID uuid.UUID, newToken string) (string, error)
+		GetCheckIn(purchaseID uuid.UUID, seat int) (*entity.CheckIn, error)
+		GetExpiredSeatHolds(now time.Time, holdTTL time.Duration) ([]*entity.Purchase, error)
+		GetExpiredUploadSessions(now time.Time) ([]*entity.UploadSession, error)
+		GetExpiredWaitlistOffers(now time.Time) ([]*entity.WaitlistEntry, error)
+		GetHeldSeats(tourEventID uuid.UUID) (float64, error)
+		GetFilteredTourEvents(filter *entity.TourEventFilter, page *entity.PageQuery) ([]*entity.TourEvent, *entity.Cursor, error)
+		GetMe(id uuid.UUID, purchases int) (*entity.User, error)
+		GetMyAvatar(userID uuid.UUID) (string, error)
+		GetPaidPurchaserIDs(tourEventID uuid.UUID) ([]uuid.UUID, error)
+		GetPricingRuleByID(ruleID uuid.UUID) (*entity.PricingRule, error)
+		GetPricingRulesByTourEventID(tourEventID uuid.UUID) ([]*entity.PricingRule, error)
+		GetPromoCodeByCode(code string) (*entity.PromoCode, error)
+		GetPromoCodesByCreatorID(creatorID uuid.UUID) ([]*entity.PromoCode, error)
+		GetProviderTourEvents(ownerID uuid.UUID) ([]*entity.TourEvent, map[uuid.UUID]int, error)
+		GetPurchaseByID(purchaseID uuid.UUID) (*entity.Purchase, error)
+		GetPurchaseByPaymentIntentID(paymentIntentID string) (*entity.Purchase, error)
+		GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.Purchase, error)
+		GetPurchasesByUserID(userID uuid.UUID, page *entity.PageQuery) ([]*entity.Purchase, *entity.Cursor, error)
+		GetReviewByID(reviewID uuid.UUID) (*entity.Review, error)
+		GetReviews(filter *entity.ReviewFilter) ([]*entity.Review, int64, error)
+		GetSchedulesToGenerate(until time.Time) ([]*entity.TourSchedule, error)
+		GetTourByID(tourID string) (*entity.Tour, error)
+		GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error)
+		GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEvent, error)
+		GetTourLocationByTourID(tourID uuid.UUID) (*entity.TourLocation, error)
+		GetTourRatings(tourIDs []uuid.UUID) (map[uuid.UUID]*entity.TourRating, error)
+		GetTourSchedule(scheduleID uuid.UUID) (*entity.TourSchedule, error)
+		GetTourSchedules(tourID uuid.UUID) ([]*entity.TourSchedule, error)
+		GetTourTrack(tourID uuid.UUID) (*entity.TourTrack, error)
+		GetTours(page *entity.PageQuery) ([]*entity.Tour, *entity.Cursor, error)
+		GetToursNearby(query *entity.GeoQuery) ([]*entity.TourNearby, error)
+		GetUploadSession(id uuid.UUID) (*entity.UploadSession, error)
+		GetUserByCalendarToken(token string) (*entity.User, error)
+		GetWaitlistEntriesByUserID(userID uuid.UUID) ([]*entity.WaitlistEntry, error)
+		GetWaitlistEntryByID(entryID uuid.UUID) (*entity.WaitlistEntry, error)
+		GetWaypoints(tourID uuid.UUID) ([]*entity.Waypoint, error)
+		GetWeatherInfoByTourEventID(tourEventID uuid.UUID) (*entity.WeatherInfoRQ, error)
+		HasAttendedTourEvent(userID, tourEventID uuid.UUID, now time.Time) (bool, error)
+		HasReviewedTourEvent(userID, tourEventID uuid.UUID) (bool, error)
+		LikeTour(userID uuid.UUID, tourID uuid.UUID) (*entity.UserFavorites, error)
+		OfferWaitlistSeats(tourEventID uuid.UUID, offerExpiresAt time.Time) ([]*entity.WaitlistEntry, error)
+		OpenUpload(sessionID uuid.UUID) (io.ReadCloser, error)
+		PayTourEvent(purchase *entity.Purchase) *entity.Purchase
+		RecoverPaymentJobs() (int64, error)
+		ReleasePurchase(purchaseID uuid.UUID, from, to string) error
+		ReleaseWaitlistOffer(entryID uuid.UUID, to string) error
+		ReorderTourMedia(tourID uuid.UUID, kind string, ids []uuid.UUID) (bool, error)
+		ReplaceWaypoints(tourID uuid.UUID, waypoints []*entity.Waypoint) error
+		RetryPaymentJob(job *entity.PaymentJob, runAt time.Time, cause error) (bool, error)
+		SaveMyAvatar(userID uuid.UUID, avatar *multipart.FileHeader) (string, error)
+		SaveTourTrack(track *entity.TourTrack) error
+		SearchTours(query string, limit, offset int) ([]*entity.TourSearchResult, error)
+		SetCalendarToken(userID uuid.UUID, token string) error
+		SetPurchasePaymentIntent(purchaseID uuid.UUID, paymentIntentID string) error
+		SetReviewReply(reviewID uuid.UUID, reply string, repliedAt time.Time) error
+		SetTourArchived(tourID uuid.UUID, archivedAt *time.Time) (bool, error)
+		SetTourCover(tourID, imageID uuid.UUID) (bool, error)
+		SyncScheduleOccurrences(schedule *entity.TourSchedule, from time.Time, occurrences []time.Time, generatedUntil time.Time) (int, error)
+		TourHasCategory(tourID, categoryID uuid.UUID) (bool, error)
+		UpdatePurchaseStatus(purchaseID uuid.UUID, from, to string) error
+		UpdateTour(tourID uuid.UUID, fields map[string]interface{}) (*entity.Tour, error)
+		UpdateTourEvent(tourEventID uuid.UUID, fields map[string]interface{}, capacity *float64) (bool, error)
+		UpdateTourSchedule(schedule *entity.TourSchedule) error
+		UpdateWaitlistStatus(entryID uuid.UUID, from, to string) error
+	}
+
+	// PaymentQueue -.
+	PaymentQueue interface {
+		ClaimPaymentJob(lease time.Duration) (*entity.PaymentJob, error)
+		CompletePaymentJob(job *entity.PaymentJob) error
+		RetryPaymentJob(job *entity.PaymentJob, runAt time.Time, cause error) error
+		FailPaymentJob(job *entity.PaymentJob, cause error) error
+		RecoverPaymentJobs() (int64, error)
+	}
+
+	// PaymentRefunder -.
+	PaymentRefunder interface {
+		Refund(purchase *entity.Purchase) error
 	}
 
 	AdminInterface interface {
-		GetUsers() ([]*entity.User, error)
+		GetUsers(page *entity.PageQuery) (*entity.UserPage, error)
 	}
 	KafkaMessageProcessor interface {
 		ProcessMessage(key, value []byte) error
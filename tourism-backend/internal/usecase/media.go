@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrMediaNotFound     = errors.New("media not found")
+	ErrInvalidMediaKind  = errors.New("media kind must be images, videos or panoramas")
+	ErrInvalidMediaOrder = errors.New("invalid media order")
+)
+
+func validMediaKind(kind string) bool {
+	return kind == entity.MediaKindImages || kind == entity.MediaKindVideos || kind == entity.MediaKindPanoramas
+}
+
+// DeleteTourMedia deletes an image, video or panorama of a tour with its files.
+func (t *TourismUseCase) DeleteTourMedia(tourID uuid.UUID, kind string, mediaID uuid.UUID) error {
+	if !validMediaKind(kind) {
+		return ErrInvalidMediaKind
+	}
+	found, err := t.repo.DeleteTourMedia(tourID, kind, mediaID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// ReorderTourMedia orders the media of one kind of a tour. ids must list each
+// of them exactly once.
+func (t *TourismUseCase) ReorderTourMedia(tourID uuid.UUID, kind string, ids []uuid.UUID) error {
+	if !validMediaKind(kind) {
+		return ErrInvalidMediaKind
+	}
+	if err := validateMediaOrder(ids); err != nil {
+		return err
+	}
+	ok, err := t.repo.ReorderTourMedia(tourID, kind, ids)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: list every %s of the tour exactly once", ErrInvalidMediaOrder, kind)
+	}
+	return nil
+}
+
+func validateMediaOrder(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: no media listed", ErrInvalidMediaOrder)
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidMediaOrder, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// SetTourCover makes an image the cover of its tour, listed before the others.
+func (t *TourismUseCase) SetTourCover(tourID, imageID uuid.UUID) error {
+	found, err := t.repo.SetTourCover(tourID, imageID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// RunMediaCollector deletes stored files that no media refers to anymore every
+// interval until ctx is done. Files younger than minAge are kept.
+func (t *TourismUseCase) RunMediaCollector(ctx context.Context, interval, minAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := t.repo.CollectUnreferencedMedia(time.Now().Add(-minAge))
+		if err != nil {
+			log.Printf("Media collector error: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Media collector deleted %d unreferenced files", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
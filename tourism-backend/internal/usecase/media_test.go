@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestValidateMediaOrder(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name string
+		ids  []uuid.UUID
+		want error
+	}{
+		{name: "distinct media", ids: []uuid.UUID{second, first}},
+		{name: "no media", ids: nil, want: ErrInvalidMediaOrder},
+		{name: "media listed twice", ids: []uuid.UUID{first, second, first}, want: ErrInvalidMediaOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMediaOrder(tt.ids); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"tourism-backend/internal/entity"
+)
+
+const (
+	_defaultPageLimit = 20
+	_maxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Sort options of each listing, the first one is the default.
+var (
+	_tourSorts      = []string{entity.SortNewest, entity.SortDate, entity.SortPrice, entity.SortPopularity, entity.SortRating}
+	_tourEventSorts = []string{entity.SortDate, entity.SortPrice, entity.SortPopularity, entity.SortRating, entity.SortNewest}
+	_purchaseSorts  = []string{entity.SortNewest, entity.SortDate, entity.SortPrice}
+	_userSorts      = []string{entity.SortNewest}
+)
+
+// preparePage applies the defaults of a listing supporting sorts to the page
+// and decodes its cursor. A cursor only continues the sort it was issued for.
+func preparePage(page *entity.PageQuery, sorts []string) error {
+	if page.Sort == "" {
+		page.Sort = sorts[0]
+	}
+	if !slices.Contains(sorts, page.Sort) {
+		return fmt.Errorf("%w %q, use one of %s", ErrInvalidSort, page.Sort, strings.Join(sorts, ", "))
+	}
+	if page.Limit < 1 {
+		page.Limit = _defaultPageLimit
+	}
+	if page.Limit > _maxPageLimit {
+		page.Limit = _maxPageLimit
+	}
+
+	page.After = nil
+	if page.Cursor != "" {
+		cursor, err := entity.DecodeCursor(page.Cursor)
+		if err != nil || cursor.Sort != page.Sort {
+			return ErrInvalidCursor
+		}
+		page.After = cursor
+	}
+	return nil
+}
+
+// encodeCursor returns the cursor handed to clients, empty after the last page.
+func encodeCursor(cursor *entity.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	return cursor.Encode()
+}
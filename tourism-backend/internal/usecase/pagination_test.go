@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestPreparePageDefaults(t *testing.T) {
+	page := &entity.PageQuery{Limit: 1000}
+	if err := preparePage(page, _tourEventSorts); err != nil {
+		t.Fatal(err)
+	}
+	if page.Sort != entity.SortDate || page.Limit != _maxPageLimit || page.After != nil {
+		t.Fatalf("page = %+v, want date sort capped at %d without cursor", page, _maxPageLimit)
+	}
+}
+
+func TestPreparePageCursorRoundTrip(t *testing.T) {
+	issued := &entity.Cursor{Sort: entity.SortDate, Time: time.Date(2026, 7, 1, 9, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+	page := &entity.PageQuery{Sort: entity.SortDate, Cursor: issued.Encode()}
+
+	if err := preparePage(page, _tourEventSorts); err != nil {
+		t.Fatal(err)
+	}
+	if page.After == nil || page.After.ID != issued.ID || !page.After.Time.Equal(issued.Time) {
+		t.Fatalf("after = %+v, want %+v", page.After, issued)
+	}
+}
+
+func TestPreparePageRejectsInvalidPage(t *testing.T) {
+	priceCursor := (&entity.Cursor{Sort: entity.SortPrice, Value: 5000, ID: uuid.New()}).Encode()
+
+	tests := []struct {
+		name string
+		page entity.PageQuery
+		want error
+	}{
+		{name: "unsupported sort", page: entity.PageQuery{Sort: entity.SortRating}, want: ErrInvalidSort},
+		{name: "malformed cursor", page: entity.PageQuery{Cursor: "not a cursor"}, want: ErrInvalidCursor},
+		{name: "cursor of another sort", page: entity.PageQuery{Sort: entity.SortNewest, Cursor: priceCursor}, want: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := preparePage(&tt.page, _purchaseSorts); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// ErrPaymentJobLeaseLost is returned when a job is finished after its lease ran
+// out and another worker claimed it again.
+var ErrPaymentJobLeaseLost = errors.New("payment job lease was lost")
+
+func (t *TourismUseCase) ClaimPaymentJob(lease time.Duration) (*entity.PaymentJob, error) {
+	return t.repo.ClaimPaymentJob(lease)
+}
+
+func (t *TourismUseCase) CompletePaymentJob(job *entity.PaymentJob) error {
+	return leaseHeld(t.repo.CompletePaymentJob(job))
+}
+
+func (t *TourismUseCase) RetryPaymentJob(job *entity.PaymentJob, runAt time.Time, cause error) error {
+	return leaseHeld(t.repo.RetryPaymentJob(job, runAt, cause))
+}
+
+func (t *TourismUseCase) FailPaymentJob(job *entity.PaymentJob, cause error) error {
+	return leaseHeld(t.repo.FailPaymentJob(job, cause))
+}
+
+func (t *TourismUseCase) RecoverPaymentJobs() (int64, error) {
+	return t.repo.RecoverPaymentJobs()
+}
+
+func leaseHeld(held bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrPaymentJobLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// leaseRepo holds a single payment job, claims bump its attempts like the
+// Postgres queue does.
+type leaseRepo struct {
+	TourismRepo
+	job entity.PaymentJob
+}
+
+func (r *leaseRepo) ClaimPaymentJob(time.Duration) (*entity.PaymentJob, error) {
+	r.job.Status = entity.PaymentJobStatusRunning
+	r.job.Attempts++
+	claimed := r.job
+	return &claimed, nil
+}
+
+func (r *leaseRepo) CompletePaymentJob(job *entity.PaymentJob) (bool, error) {
+	if r.job.ID != job.ID || r.job.Status != entity.PaymentJobStatusRunning || r.job.Attempts != job.Attempts {
+		return false, nil
+	}
+	r.job.Status = entity.PaymentJobStatusDone
+	return true, nil
+}
+
+func TestCompletePaymentJobAfterLostLease(t *testing.T) {
+	repo := &leaseRepo{job: entity.PaymentJob{ID: uuid.New(), Status: entity.PaymentJobStatusPending}}
+	uc := &TourismUseCase{repo: repo}
+
+	slow, _ := uc.ClaimPaymentJob(time.Minute)
+	// The lease of the slow worker ran out and another worker claimed the job.
+	current, _ := uc.ClaimPaymentJob(time.Minute)
+
+	if err := uc.CompletePaymentJob(slow); !errors.Is(err, ErrPaymentJobLeaseLost) {
+		t.Fatalf("CompletePaymentJob() of the stale claim error = %v, want ErrPaymentJobLeaseLost", err)
+	}
+	if repo.job.Status != entity.PaymentJobStatusRunning {
+		t.Fatalf("job status = %s, want it still running for the new claim", repo.job.Status)
+	}
+	if err := uc.CompletePaymentJob(current); err != nil {
+		t.Fatal(err)
+	}
+	if repo.job.Status != entity.PaymentJobStatusDone {
+		t.Fatalf("job status = %s, want done", repo.job.Status)
+	}
+}
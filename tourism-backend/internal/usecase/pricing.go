@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrPricingRuleForbidden = errors.New("you are not the owner of this tour event")
+	ErrInvalidPricingRule   = errors.New("invalid pricing rule")
+)
+
+// _promoCodeAdjustment is the adjustment type of a promo code discount in a price quote.
+const _promoCodeAdjustment = "promo_code"
+
+// CreatePricingRule adds a pricing rule to a tour event of the provider.
+func (t *TourismUseCase) CreatePricingRule(providerID uuid.UUID, dto *entity.CreatePricingRuleDTO) (*entity.PricingRule, error) {
+	if dto.Percent <= 0 || dto.Percent > 100 {
+		return nil, fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidPricingRule)
+	}
+	switch dto.Type {
+	case entity.PricingRuleEarlyBird:
+		if dto.StartsBefore == nil {
+			return nil, fmt.Errorf("%w: early_bird needs starts_before", ErrInvalidPricingRule)
+		}
+	case entity.PricingRuleLastMinute:
+		if dto.HoursBefore <= 0 {
+			return nil, fmt.Errorf("%w: last_minute needs positive hours_before", ErrInvalidPricingRule)
+		}
+	case entity.PricingRuleGroup:
+		if dto.MinQuantity < 2 {
+			return nil, fmt.Errorf("%w: group needs min_quantity of at least 2", ErrInvalidPricingRule)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidPricingRule, dto.Type)
+	}
+
+	tourEvent, err := t.repo.GetTourEventByID(dto.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("create pricing rule: %w", err)
+	}
+	if tourEvent.Tour.OwnerID != providerID {
+		return nil, ErrPricingRuleForbidden
+	}
+
+	return t.repo.CreatePricingRule(&entity.PricingRule{
+		TourEventID:  dto.TourEventID,
+		Type:         dto.Type,
+		Percent:      dto.Percent,
+		StartsBefore: dto.StartsBefore,
+		HoursBefore:  dto.HoursBefore,
+		MinQuantity:  dto.MinQuantity,
+	})
+}
+
+func (t *TourismUseCase) DeletePricingRule(providerID, ruleID uuid.UUID) error {
+	rule, err := t.repo.GetPricingRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+	if rule.TourEvent.Tour.OwnerID != providerID {
+		return ErrPricingRuleForbidden
+	}
+	return t.repo.DeletePricingRule(ruleID)
+}
+
+// QuotePrice explains what the places a purchase asks for cost the user right now.
+// A promo code, if given, is checked and applied like it would be at checkout.
+func (t *TourismUseCase) QuotePrice(userID uuid.UUID, purchase *entity.Purchase, promoCode string) (*entity.PriceQuote, error) {
+	tourEvent, err := t.repo.GetTourEventByID(purchase.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("quote price: %w", err)
+	}
+	if err := resolveTicketItems(purchase, tourEvent); err != nil {
+		return nil, err
+	}
+
+	quote, err := t.priceTourEvent(tourEvent, purchase.Items, purchase.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if promoCode != "" {
+		promo, err := t.findPromoCode(userID, promoCode, tourEvent)
+		if err != nil {
+			return nil, err
+		}
+		discount := promo.Discount(quote.Total)
+		quote.Adjustments = append(quote.Adjustments, entity.PriceAdjustment{
+			Type:        _promoCodeAdjustment,
+			Description: fmt.Sprintf("Promo code %s", promo.Code),
+			Amount:      -discount,
+		})
+		quote.Total -= discount
+	}
+	return quote, nil
+}
+
+// priceTourEvent quotes places of the tour event with its pricing rules.
+func (t *TourismUseCase) priceTourEvent(tourEvent *entity.TourEvent, items []entity.PurchaseItem, quantity int) (*entity.PriceQuote, error) {
+	rules, err := t.repo.GetPricingRulesByTourEventID(tourEvent.ID)
+	if err != nil {
+		return nil, err
+	}
+	return quotePrice(tourEvent, rules, items, quantity, time.Now()), nil
+}
+
+// pricePurchase sets the amount of a purchase with resolved ticket items to its quoted total.
+func (t *TourismUseCase) pricePurchase(purchase *entity.Purchase, tourEvent *entity.TourEvent) error {
+	quote, err := t.priceTourEvent(tourEvent, purchase.Items, purchase.Quantity)
+	if err != nil {
+		return err
+	}
+	purchase.Amount = quote.Total
+	purchase.PricingDiscount = quote.Subtotal - quote.Total
+	return nil
+}
+
+// quotePrice computes the price of places of a tour event bought at now. Places
+// of ticket items cost the price of their ticket type, other places the price of
+// the tour event. Every rule that applies takes its percentage off the subtotal,
+// together they never take more than the subtotal.
+func quotePrice(tourEvent *entity.TourEvent, rules []*entity.PricingRule, items []entity.PurchaseItem, quantity int, now time.Time) *entity.PriceQuote {
+	quote := &entity.PriceQuote{
+		TourEventID: tourEvent.ID,
+		Quantity:    quantity,
+		Items:       items,
+		Adjustments: []entity.PriceAdjustment{},
+	}
+	if len(items) == 0 {
+		quote.UnitPrice = tourEvent.Price
+		quote.Subtotal = tourEvent.Price * float64(quantity)
+	}
+	for _, item := range items {
+		quote.Subtotal += item.UnitPrice * float64(item.Quantity)
+	}
+	subtotal := roundPrice(quote.Subtotal)
+	quote.Subtotal = subtotal
+	quote.Total = subtotal
+
+	for _, rule := range rules {
+		description, ok := pricingRuleApplies(rule, tourEvent, quantity, now)
+		if !ok {
+			continue
+		}
+		discount := math.Min(roundPrice(subtotal*rule.Percent/100), quote.Total)
+		quote.Total -= discount
+		quote.Adjustments = append(quote.Adjustments, entity.PriceAdjustment{
+			Type:        rule.Type,
+			Description: description,
+			Amount:      -discount,
+			RuleID:      &rule.ID,
+		})
+	}
+	return quote
+}
+
+// pricingRuleApplies reports whether the rule applies and describes it for the price breakdown.
+func pricingRuleApplies(rule *entity.PricingRule, tourEvent *entity.TourEvent, quantity int, now time.Time) (string, bool) {
+	switch rule.Type {
+	case entity.PricingRuleEarlyBird:
+		if rule.StartsBefore != nil && now.Before(*rule.StartsBefore) {
+			return fmt.Sprintf("Early-bird %g%% off until %s", rule.Percent, rule.StartsBefore.Format(time.RFC3339)), true
+		}
+	case entity.PricingRuleLastMinute:
+		window := time.Duration(rule.HoursBefore) * time.Hour
+		if now.Before(tourEvent.Date) && tourEvent.Date.Sub(now) <= window {
+			return fmt.Sprintf("Last-minute %g%% off within %d hours of the start", rule.Percent, rule.HoursBefore), true
+		}
+	case entity.PricingRuleGroup:
+		if quantity >= rule.MinQuantity {
+			return fmt.Sprintf("Group of %d or more %g%% off", rule.MinQuantity, rule.Percent), true
+		}
+	}
+	return "", false
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
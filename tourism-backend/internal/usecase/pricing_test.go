@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestQuotePrice(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	earlyBirdEnd := now.Add(24 * time.Hour)
+	earlyBirdOver := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		startsIn  time.Duration
+		quantity  int
+		rules     []*entity.PricingRule
+		wantTotal float64
+		wantLines int
+	}{
+		{
+			name:      "no rules",
+			startsIn:  30 * 24 * time.Hour,
+			quantity:  2,
+			wantTotal: 20000,
+		},
+		{
+			name:      "early bird",
+			startsIn:  30 * 24 * time.Hour,
+			quantity:  1,
+			rules:     []*entity.PricingRule{{Type: entity.PricingRuleEarlyBird, Percent: 20, StartsBefore: &earlyBirdEnd}},
+			wantTotal: 8000,
+			wantLines: 1,
+		},
+		{
+			name:      "early bird over",
+			startsIn:  30 * 24 * time.Hour,
+			quantity:  1,
+			rules:     []*entity.PricingRule{{Type: entity.PricingRuleEarlyBird, Percent: 20, StartsBefore: &earlyBirdOver}},
+			wantTotal: 10000,
+		},
+		{
+			name:      "last minute",
+			startsIn:  5 * time.Hour,
+			quantity:  1,
+			rules:     []*entity.PricingRule{{Type: entity.PricingRuleLastMinute, Percent: 30, HoursBefore: 6}},
+			wantTotal: 7000,
+			wantLines: 1,
+		},
+		{
+			name:      "too early for last minute",
+			startsIn:  7 * time.Hour,
+			quantity:  1,
+			rules:     []*entity.PricingRule{{Type: entity.PricingRuleLastMinute, Percent: 30, HoursBefore: 6}},
+			wantTotal: 10000,
+		},
+		{
+			name:     "group and early bird stack on the subtotal",
+			startsIn: 30 * 24 * time.Hour,
+			quantity: 4,
+			rules: []*entity.PricingRule{
+				{Type: entity.PricingRuleEarlyBird, Percent: 10, StartsBefore: &earlyBirdEnd},
+				{Type: entity.PricingRuleGroup, Percent: 15, MinQuantity: 4},
+			},
+			wantTotal: 30000,
+			wantLines: 2,
+		},
+		{
+			name:     "discounts never exceed the subtotal",
+			startsIn: 5 * time.Hour,
+			quantity: 4,
+			rules: []*entity.PricingRule{
+				{Type: entity.PricingRuleLastMinute, Percent: 80, HoursBefore: 6},
+				{Type: entity.PricingRuleGroup, Percent: 50, MinQuantity: 2},
+			},
+			wantTotal: 0,
+			wantLines: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tourEvent := &entity.TourEvent{ID: uuid.New(), Price: 10000, Date: now.Add(tt.startsIn)}
+			quote := quotePrice(tourEvent, tt.rules, nil, tt.quantity, now)
+
+			if quote.Total != tt.wantTotal {
+				t.Fatalf("total = %v, want %v", quote.Total, tt.wantTotal)
+			}
+			if len(quote.Adjustments) != tt.wantLines {
+				t.Fatalf("adjustments = %+v, want %d lines", quote.Adjustments, tt.wantLines)
+			}
+		})
+	}
+}
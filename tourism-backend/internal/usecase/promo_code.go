@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeExpired       = errors.New("promo code is not valid at this time")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this tour event")
+	ErrPromoCodeUsedUp        = errors.New("promo code usage limit reached")
+	ErrPromoCodeForbidden     = errors.New("providers can only create promo codes for their own tours and events")
+	ErrPromoCodeInvalid       = errors.New("invalid promo code")
+)
+
+// CreatePromoCode stores a promo code. Admins may create codes for any scope,
+// providers only for their own tours and tour events.
+func (t *TourismUseCase) CreatePromoCode(creatorID uuid.UUID, role string, dto *entity.CreatePromoCodeDTO) (*entity.PromoCode, error) {
+	code := normalizePromoCode(dto.Code)
+	if code == "" {
+		return nil, fmt.Errorf("%w: code is empty", ErrPromoCodeInvalid)
+	}
+	switch dto.DiscountType {
+	case entity.PromoCodeTypePercentage:
+		if dto.Value <= 0 || dto.Value > 100 {
+			return nil, fmt.Errorf("%w: percentage must be between 0 and 100", ErrPromoCodeInvalid)
+		}
+	case entity.PromoCodeTypeFixed:
+		if dto.Value <= 0 {
+			return nil, fmt.Errorf("%w: amount must be positive", ErrPromoCodeInvalid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown discount type %q", ErrPromoCodeInvalid, dto.DiscountType)
+	}
+	if dto.ValidFrom != nil && dto.ValidUntil != nil && dto.ValidUntil.Before(*dto.ValidFrom) {
+		return nil, fmt.Errorf("%w: valid_until is before valid_from", ErrPromoCodeInvalid)
+	}
+	if dto.MaxUses < 0 || dto.MaxUsesPerUser < 0 {
+		return nil, fmt.Errorf("%w: usage limits must not be negative", ErrPromoCodeInvalid)
+	}
+
+	if role != entity.UserRoleAdmin {
+		if err := t.checkPromoCodeScopeOwner(creatorID, dto); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.repo.CreatePromoCode(&entity.PromoCode{
+		Code:           code,
+		DiscountType:   dto.DiscountType,
+		Value:          dto.Value,
+		ValidFrom:      dto.ValidFrom,
+		ValidUntil:     dto.ValidUntil,
+		MaxUses:        dto.MaxUses,
+		MaxUsesPerUser: dto.MaxUsesPerUser,
+		TourID:         dto.TourID,
+		CategoryID:     dto.CategoryID,
+		TourEventID:    dto.TourEventID,
+		CreatedByID:    creatorID,
+	})
+}
+
+// checkPromoCodeScopeOwner makes sure a provider's code only discounts the provider's own tours.
+// Categories span tours of every provider, so they can only narrow a tour scope down.
+func (t *TourismUseCase) checkPromoCodeScopeOwner(providerID uuid.UUID, dto *entity.CreatePromoCodeDTO) error {
+	if dto.TourID == nil && dto.TourEventID == nil {
+		return ErrPromoCodeForbidden
+	}
+	if dto.TourID != nil && !t.repo.CheckTourOwner(*dto.TourID, providerID) {
+		return ErrPromoCodeForbidden
+	}
+	if dto.TourEventID != nil {
+		tourEvent, err := t.repo.GetTourEventByID(*dto.TourEventID)
+		if err != nil {
+			return fmt.Errorf("create promo code: %w", err)
+		}
+		if tourEvent.Tour.OwnerID != providerID {
+			return ErrPromoCodeForbidden
+		}
+	}
+	return nil
+}
+
+func (t *TourismUseCase) GetMyPromoCodes(creatorID uuid.UUID) ([]*entity.PromoCode, error) {
+	return t.repo.GetPromoCodesByCreatorID(creatorID)
+}
+
+// ApplyPromoCode discounts a purchase whose charge has not started yet. With
+// gateways the client confirms, the charge starts with CreatePaymentIntent.
+// Applying the code the purchase already uses again changes nothing, a code
+// that takes off the whole amount pays the purchase.
+func (t *TourismUseCase) ApplyPromoCode(userID, purchaseID uuid.UUID, code string) (*entity.Purchase, error) {
+	purchase, err := t.GetPayablePurchase(userID, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	promoCode, err := t.findPromoCode(userID, code, &purchase.TourEvent)
+	if err != nil {
+		return nil, err
+	}
+	if purchase.PromoCodeID != nil {
+		if *purchase.PromoCodeID == promoCode.ID {
+			return purchase, nil
+		}
+		return nil, fmt.Errorf("%w: purchase already uses another promo code", ErrPromoCodeNotApplicable)
+	}
+	if purchase.PaymentIntentID != "" {
+		return nil, fmt.Errorf("%w: payment of the purchase has already started", ErrPromoCodeNotApplicable)
+	}
+
+	discounted, err := t.repo.ApplyPromoCodeToPurchase(purchase, promoCode)
+	if err != nil {
+		return nil, err
+	}
+	return t.payFreePurchase(discounted)
+}
+
+// findPromoCode returns the promo code if the user may use it on the tour event now.
+func (t *TourismUseCase) findPromoCode(userID uuid.UUID, code string, tourEvent *entity.TourEvent) (*entity.PromoCode, error) {
+	promoCode, err := t.repo.GetPromoCodeByCode(normalizePromoCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if promoCode == nil {
+		return nil, ErrPromoCodeNotFound
+	}
+	if !promoCode.IsValidAt(time.Now()) {
+		return nil, ErrPromoCodeExpired
+	}
+
+	if promoCode.TourEventID != nil && *promoCode.TourEventID != tourEvent.ID {
+		return nil, ErrPromoCodeNotApplicable
+	}
+	if promoCode.TourID != nil && *promoCode.TourID != tourEvent.TourID {
+		return nil, ErrPromoCodeNotApplicable
+	}
+	if promoCode.CategoryID != nil {
+		ok, err := t.repo.TourHasCategory(tourEvent.TourID, *promoCode.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrPromoCodeNotApplicable
+		}
+	}
+
+	total, byUser, err := t.repo.CountPromoCodeUses(promoCode.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if (promoCode.MaxUses > 0 && total >= int64(promoCode.MaxUses)) ||
+		(promoCode.MaxUsesPerUser > 0 && byUser >= int64(promoCode.MaxUsesPerUser)) {
+		return nil, ErrPromoCodeUsedUp
+	}
+	return promoCode, nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestApplyPromoCodeOfWholeAmountPaysPurchase(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 3)
+	purchase := repo.addPurchase(tourEvent, entity.PurchaseStatusProcessing, 2)
+	repo.promoCodes["FREE"] = &entity.PromoCode{ID: uuid.New(), Code: "FREE", DiscountType: entity.PromoCodeTypePercentage, Value: 100}
+	uc, _, producer := newTestUseCase(repo)
+
+	paid, err := uc.ApplyPromoCode(purchase.UserID, purchase.ID, " free ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.Status != entity.PurchaseStatusPaid || paid.Amount != 0 || paid.Discount != 20000 {
+		t.Fatalf("purchase = %s for %v with a discount of %v, want Paid for 0 with a discount of 20000", paid.Status, paid.Amount, paid.Discount)
+	}
+	if status := repo.purchases[purchase.ID].Status; status != entity.PurchaseStatusPaid {
+		t.Fatalf("stored purchase status = %s, want Paid", status)
+	}
+	if topics := producer.topics(); len(topics) != 1 || topics[0] != "PAYMENT" {
+		t.Errorf("notifications = %v, want one PAYMENT", topics)
+	}
+}
+
+func TestApplyPromoCodeOfPartialAmountKeepsPurchaseProcessing(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 3)
+	purchase := repo.addPurchase(tourEvent, entity.PurchaseStatusProcessing, 2)
+	repo.promoCodes["HALF"] = &entity.PromoCode{ID: uuid.New(), Code: "HALF", DiscountType: entity.PromoCodeTypePercentage, Value: 50}
+	uc, _, producer := newTestUseCase(repo)
+
+	discounted, err := uc.ApplyPromoCode(purchase.UserID, purchase.ID, "HALF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discounted.Status != entity.PurchaseStatusProcessing || discounted.Amount != 10000 {
+		t.Fatalf("purchase = %s for %v, want Processing for 10000", discounted.Status, discounted.Amount)
+	}
+	if len(producer.notifications) != 0 {
+		t.Errorf("notifications = %v, want none before the charge", producer.topics())
+	}
+}
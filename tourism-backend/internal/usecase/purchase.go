@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrPurchaseForbidden         = errors.New("you are not allowed to manage this purchase")
+	ErrInvalidPurchaseTransition = errors.New("invalid purchase status transition")
+	ErrPurchaseNotFound          = errors.New("purchase not found")
+)
+
+// purchaseTransitions lists the statuses a purchase may move to from each status.
+// Refunded, Failed and Expired are terminal.
+var purchaseTransitions = map[string][]string{
+	entity.PurchaseStatusProcessing: {
+		entity.PurchaseStatusPaid,
+		entity.PurchaseStatusFailed,
+		entity.PurchaseStatusExpired,
+		entity.PurchaseStatusCancelRequested,
+	},
+	entity.PurchaseStatusPaid: {
+		entity.PurchaseStatusCancelRequested,
+	},
+	entity.PurchaseStatusCancelRequested: {
+		entity.PurchaseStatusRefunded,
+	},
+}
+
+func canTransitionPurchase(from, to string) bool {
+	for _, status := range purchaseTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPurchaseTransition(from, to string) error {
+	if !canTransitionPurchase(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidPurchaseTransition, from, to)
+	}
+	return nil
+}
+
+// GetMyPurchases returns a page of the purchases of the user.
+func (t *TourismUseCase) GetMyPurchases(userID uuid.UUID, page *entity.PageQuery) (*entity.PurchasePage, error) {
+	if err := preparePage(page, _purchaseSorts); err != nil {
+		return nil, err
+	}
+	purchases, next, err := t.repo.GetPurchasesByUserID(userID, page)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.PurchasePage{Purchases: purchases, NextCursor: encodeCursor(next)}, nil
+}
+
+// GetPayablePurchase returns a purchase of the user that still waits for its payment.
+func (t *TourismUseCase) GetPayablePurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
+	purchase, err := t.repo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("get payable purchase: %w", err)
+	}
+	if purchase.UserID != userID {
+		return nil, ErrPurchaseForbidden
+	}
+	if err := checkPurchaseTransition(purchase.Status, entity.PurchaseStatusPaid); err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
+
+// AttachPaymentIntent stores the PaymentIntent that will charge a processing purchase
+// so that Stripe webhook events can be matched back to it.
+func (t *TourismUseCase) AttachPaymentIntent(userID, purchaseID uuid.UUID, paymentIntentID string) error {
+	if _, err := t.GetPayablePurchase(userID, purchaseID); err != nil {
+		return err
+	}
+	return t.repo.SetPurchasePaymentIntent(purchaseID, paymentIntentID)
+}
+
+// RecordPaymentIntent stores the PaymentIntent that charged a purchase whatever
+// its status, so that the charge of a purchase cancelled while it was being
+// charged can still be refunded.
+func (t *TourismUseCase) RecordPaymentIntent(purchaseID uuid.UUID, paymentIntentID string) error {
+	return t.repo.SetPurchasePaymentIntent(purchaseID, paymentIntentID)
+}
+
+// HandlePaymentSucceeded marks the purchase charged by the PaymentIntent as Paid.
+// Repeated deliveries of the same event are ignored.
+func (t *TourismUseCase) HandlePaymentSucceeded(paymentIntentID string) error {
+	purchase, err := t.repo.GetPurchaseByPaymentIntentID(paymentIntentID)
+	if err != nil {
+		return err
+	}
+	if purchase == nil {
+		return ErrPurchaseNotFound
+	}
+	if purchase.Status == entity.PurchaseStatusPaid {
+		return nil
+	}
+	return t.PayTourEvent(purchase)
+}
+
+// HandlePaymentFailed marks the purchase charged by the PaymentIntent as Failed.
+// Repeated deliveries of the same event are ignored.
+func (t *TourismUseCase) HandlePaymentFailed(paymentIntentID string) error {
+	purchase, err := t.repo.GetPurchaseByPaymentIntentID(paymentIntentID)
+	if err != nil {
+		return err
+	}
+	if purchase == nil {
+		return ErrPurchaseNotFound
+	}
+	if purchase.Status == entity.PurchaseStatusFailed {
+		return nil
+	}
+	return t.FailPurchase(purchase)
+}
+
+// FailPurchase marks a processing purchase as Failed, returns its seats, offers
+// them to the waitlist and notifies the user.
+func (t *TourismUseCase) FailPurchase(purchase *entity.Purchase) error {
+	if err := checkPurchaseTransition(purchase.Status, entity.PurchaseStatusFailed); err != nil {
+		return err
+	}
+	if err := t.repo.ReleasePurchase(purchase.ID, purchase.Status, entity.PurchaseStatusFailed); err != nil {
+		return fmt.Errorf("fail purchase: %w", err)
+	}
+	purchase.Status = entity.PurchaseStatusFailed
+	t.offerFreedSeats(purchase.TourEventID)
+
+	kafkaMessage := entity.Notification{
+		Topic: "PAYMENT",
+		Data: map[string]interface{}{
+			"Text":    "Your payment failed",
+			"Payment": purchase,
+		},
+		Recipients: []uuid.UUID{purchase.UserID},
+	}
+
+	t.PublishMessage("notifications", kafkaMessage)
+
+	return nil
+}
+
+// CancelPurchase cancels a purchase on behalf of the user who made it.
+func (t *TourismUseCase) CancelPurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
+	purchase, err := t.repo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel purchase: %w", err)
+	}
+	if purchase.UserID != userID {
+		return nil, ErrPurchaseForbidden
+	}
+	return t.cancelPurchase(purchase)
+}
+
+// CancelPurchaseByProvider cancels a purchase of a tour event owned by the provider.
+func (t *TourismUseCase) CancelPurchaseByProvider(providerID, purchaseID uuid.UUID) (*entity.Purchase, error) {
+	purchase, err := t.repo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel purchase: %w", err)
+	}
+	if purchase.TourEvent.Tour.OwnerID != providerID {
+		return nil, ErrPurchaseForbidden
+	}
+	return t.cancelPurchase(purchase)
+}
+
+// cancelPurchase returns the seats to the tour event, offers them to the waitlist,
+// refunds the purchase and notifies the user. A purchase left in CancelRequested by a failed refund can
+// be cancelled again to retry the refund.
+func (t *TourismUseCase) cancelPurchase(purchase *entity.Purchase) (*entity.Purchase, error) {
+	if err := t.refundPurchase(purchase); err != nil {
+		return nil, err
+	}
+
+	kafkaMessage := entity.Notification{
+		Topic: "PAYMENT",
+		Data: map[string]interface{}{
+			"Text":    "Your purchase has been cancelled and refunded",
+			"Payment": purchase,
+		},
+		Recipients: []uuid.UUID{purchase.UserID},
+	}
+
+	t.PublishMessage("notifications", kafkaMessage)
+
+	return purchase, nil
+}
+
+// refundPurchase cancels and refunds a purchase like cancelPurchase without
+// notifying the user, for callers that tell the user why themselves.
+func (t *TourismUseCase) refundPurchase(purchase *entity.Purchase) error {
+	if purchase.Status != entity.PurchaseStatusCancelRequested {
+		if err := checkPurchaseTransition(purchase.Status, entity.PurchaseStatusCancelRequested); err != nil {
+			return err
+		}
+		if err := t.repo.ReleasePurchase(purchase.ID, purchase.Status, entity.PurchaseStatusCancelRequested); err != nil {
+			return fmt.Errorf("cancel purchase: %w", err)
+		}
+		purchase.Status = entity.PurchaseStatusCancelRequested
+		t.offerFreedSeats(purchase.TourEventID)
+	}
+
+	if err := t.refunder.Refund(purchase); err != nil {
+		log.Printf("Refund of purchase %s failed: %v", purchase.ID, err)
+		return fmt.Errorf("refund purchase: %w", err)
+	}
+
+	if err := t.repo.UpdatePurchaseStatus(purchase.ID, entity.PurchaseStatusCancelRequested, entity.PurchaseStatusRefunded); err != nil {
+		return fmt.Errorf("cancel purchase: %w", err)
+	}
+	purchase.Status = entity.PurchaseStatusRefunded
+	return nil
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestCanTransitionPurchase(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{entity.PurchaseStatusProcessing, entity.PurchaseStatusPaid, true},
+		{entity.PurchaseStatusProcessing, entity.PurchaseStatusFailed, true},
+		{entity.PurchaseStatusProcessing, entity.PurchaseStatusExpired, true},
+		{entity.PurchaseStatusProcessing, entity.PurchaseStatusCancelRequested, true},
+		{entity.PurchaseStatusProcessing, entity.PurchaseStatusRefunded, false},
+		{entity.PurchaseStatusPaid, entity.PurchaseStatusCancelRequested, true},
+		{entity.PurchaseStatusPaid, entity.PurchaseStatusRefunded, false},
+		{entity.PurchaseStatusPaid, entity.PurchaseStatusFailed, false},
+		{entity.PurchaseStatusPaid, entity.PurchaseStatusProcessing, false},
+		{entity.PurchaseStatusCancelRequested, entity.PurchaseStatusRefunded, true},
+		{entity.PurchaseStatusCancelRequested, entity.PurchaseStatusPaid, false},
+		{entity.PurchaseStatusRefunded, entity.PurchaseStatusCancelRequested, false},
+		{entity.PurchaseStatusFailed, entity.PurchaseStatusPaid, false},
+		{entity.PurchaseStatusExpired, entity.PurchaseStatusPaid, false},
+	}
+	for _, tt := range tests {
+		if got := canTransitionPurchase(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransitionPurchase(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+	if err := checkPurchaseTransition(entity.PurchaseStatusRefunded, entity.PurchaseStatusPaid); !errors.Is(err, ErrInvalidPurchaseTransition) {
+		t.Errorf("checkPurchaseTransition() error = %v, want ErrInvalidPurchaseTransition", err)
+	}
+}
+
+func TestCancelPurchaseRefundsAndReturnsSeats(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 3)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc, refunder, producer := newTestUseCase(repo)
+
+	purchase, err := uc.CancelPurchase(paid.UserID, paid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purchase.Status != entity.PurchaseStatusRefunded || repo.purchases[paid.ID].Status != entity.PurchaseStatusRefunded {
+		t.Fatalf("status = %s, want Refunded", repo.purchases[paid.ID].Status)
+	}
+	if places := repo.tourEvents[tourEvent.ID].AmountOfPlaces; places != 5 {
+		t.Errorf("amount of places = %v, want the 2 seats returned", places)
+	}
+	if len(refunder.refunded) != 1 {
+		t.Errorf("refunded = %v, want the purchase", refunder.refunded)
+	}
+	if topics := producer.topics(); len(topics) != 1 || topics[0] != "PAYMENT" {
+		t.Errorf("notifications = %v, want one PAYMENT", topics)
+	}
+}
+
+func TestCancelPurchaseFailedRefundCanBeRetried(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 1)
+	uc, refunder, producer := newTestUseCase(repo)
+
+	refunder.err = errors.New("gateway unavailable")
+	if _, err := uc.CancelPurchase(paid.UserID, paid.ID); err == nil {
+		t.Fatal("cancel succeeded although the refund failed")
+	}
+	if status := repo.purchases[paid.ID].Status; status != entity.PurchaseStatusCancelRequested {
+		t.Fatalf("status = %s, want CancelRequested until the refund succeeds", status)
+	}
+	if places := repo.tourEvents[tourEvent.ID].AmountOfPlaces; places != 1 {
+		t.Errorf("amount of places = %v, want the seat returned", places)
+	}
+	if len(producer.notifications) != 0 {
+		t.Errorf("notifications = %v, want none before the refund", producer.topics())
+	}
+
+	refunder.err = nil
+	if _, err := uc.CancelPurchase(paid.UserID, paid.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status := repo.purchases[paid.ID].Status; status != entity.PurchaseStatusRefunded {
+		t.Fatalf("status = %s, want Refunded after the retry", status)
+	}
+	if places := repo.tourEvents[tourEvent.ID].AmountOfPlaces; places != 1 {
+		t.Errorf("amount of places = %v, want the seat returned only once", places)
+	}
+}
+
+func TestCancelPurchaseRejectsOtherUsersAndFinishedPurchases(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 1)
+	expired := repo.addPurchase(tourEvent, entity.PurchaseStatusExpired, 1)
+	uc, refunder, _ := newTestUseCase(repo)
+
+	if _, err := uc.CancelPurchase(expired.UserID, paid.ID); !errors.Is(err, ErrPurchaseForbidden) {
+		t.Errorf("cancel by another user error = %v, want ErrPurchaseForbidden", err)
+	}
+	if _, err := uc.CancelPurchase(expired.UserID, expired.ID); !errors.Is(err, ErrInvalidPurchaseTransition) {
+		t.Errorf("cancel of an expired purchase error = %v, want ErrInvalidPurchaseTransition", err)
+	}
+	if len(refunder.refunded) != 0 {
+		t.Errorf("refunded = %v, want nothing", refunder.refunded)
+	}
+}
+
+func TestCreatePurchaseOfSeveralPlaces(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 5)
+	tourEvent.Price = 10000
+	uc, _, _ := newTestUseCase(repo)
+	uc.purchaseCfg.SeatHoldTTL = 15 * time.Minute
+
+	purchase, err := uc.CreatePurchase(&entity.Purchase{
+		TourEventID: tourEvent.ID,
+		UserID:      uuid.New(),
+		Status:      entity.PurchaseStatusProcessing,
+		Quantity:    3,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purchase.Quantity != 3 || purchase.Amount != 30000 || purchase.HoldExpiresAt == nil {
+		t.Fatalf("purchase = %d places for %v held until %v, want 3 places for 30000 with a seat hold",
+			purchase.Quantity, purchase.Amount, purchase.HoldExpiresAt)
+	}
+	if places := tourEvent.AmountOfPlaces; places != 2 {
+		t.Fatalf("free places = %v, want 2 after taking 3", places)
+	}
+
+	if err := uc.FailPurchase(repo.purchases[purchase.ID]); err != nil {
+		t.Fatal(err)
+	}
+	if places := tourEvent.AmountOfPlaces; places != 5 {
+		t.Errorf("free places = %v, want all 3 places returned", places)
+	}
+}
+
+func TestCreatePurchaseOfMorePlacesThanFree(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 3)
+	uc, _, _ := newTestUseCase(repo)
+
+	_, err := uc.CreatePurchase(&entity.Purchase{
+		TourEventID: tourEvent.ID,
+		UserID:      uuid.New(),
+		Status:      entity.PurchaseStatusProcessing,
+		Quantity:    4,
+	}, "")
+	if err == nil {
+		t.Fatal("CreatePurchase() of 4 places out of 3 succeeded")
+	}
+	if places := tourEvent.AmountOfPlaces; places != 3 || len(repo.purchases) != 0 {
+		t.Errorf("free places = %v, purchases = %d, want nothing taken", places, len(repo.purchases))
+	}
+}
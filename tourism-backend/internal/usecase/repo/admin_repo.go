@@ -2,6 +2,7 @@ package repo
 
 import (
 	"fmt"
+	"sort"
 	"tourism-backend/internal/entity"
 	"tourism-backend/pkg/postgres"
 )
@@ -15,12 +16,20 @@ func NewAdminRepo(pg *postgres.Postgres) *AdminRepo {
 	return &AdminRepo{pg}
 }
 
-func (r *AdminRepo) GetUsers() ([]*entity.User, error) {
-	users := make([]*entity.User, 0)
-
-	err := r.PG.Conn.Find(&users).Error
+// GetUsers returns a page of the registered users.
+func (r *AdminRepo) GetUsers(page *entity.PageQuery) ([]*entity.User, *entity.Cursor, error) {
+	ids, next, err := pageIDs(r.PG.Conn.Model(&entity.User{}), "tourism.users.id", _userOrders[page.Sort], page)
 	if err != nil {
-		return nil, fmt.Errorf("get users: %w", err)
+		return nil, nil, fmt.Errorf("get users: %w", err)
+	}
+
+	users := make([]*entity.User, 0, len(ids))
+	if err := r.PG.Conn.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, nil, fmt.Errorf("get users: %w", err)
 	}
-	return users, nil
+	positions := pagePositions(ids)
+	sort.Slice(users, func(i, j int) bool {
+		return positions[users[i].ID] < positions[users[j].ID]
+	})
+	return users, next, nil
 }
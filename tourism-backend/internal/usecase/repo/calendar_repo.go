@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"tourism-backend/internal/entity"
+)
+
+// GetCalendarToken returns the calendar token of a user, setting it to
+// newToken first if the user has none yet.
+func (r *TourismRepo) GetCalendarToken(userID uuid.UUID, newToken string) (string, error) {
+	err := r.PG.Conn.Model(&entity.User{}).
+		Where("id = ? AND calendar_token IS NULL", userID).
+		Update("calendar_token", newToken).Error
+	if err != nil {
+		return "", fmt.Errorf("set calendar token: %w", err)
+	}
+
+	var user entity.User
+	if err := r.PG.Conn.Select("calendar_token").First(&user, "id = ?", userID).Error; err != nil {
+		return "", fmt.Errorf("get calendar token: %w", err)
+	}
+	if user.CalendarToken == nil {
+		return "", fmt.Errorf("user %s has no calendar token", userID)
+	}
+	return *user.CalendarToken, nil
+}
+
+// SetCalendarToken replaces the calendar token of a user, which stops the
+// feeds under the old one.
+func (r *TourismRepo) SetCalendarToken(userID uuid.UUID, token string) error {
+	err := r.PG.Conn.Model(&entity.User{}).Where("id = ?", userID).Update("calendar_token", token).Error
+	if err != nil {
+		return fmt.Errorf("set calendar token: %w", err)
+	}
+	return nil
+}
+
+// GetUserByCalendarToken returns the user with the calendar token, or nil if
+// there is none.
+func (r *TourismRepo) GetUserByCalendarToken(token string) (*entity.User, error) {
+	var user entity.User
+	err := r.PG.Conn.Select("id", "username").First(&user, "calendar_token = ?", token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user by calendar token: %w", err)
+	}
+	return &user, nil
+}
+
+// GetCalendarPurchases returns the paid purchases of a user with their tour
+// event, tour and its location.
+func (r *TourismRepo) GetCalendarPurchases(userID uuid.UUID) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	err := r.PG.Conn.
+		Preload("TourEvent.Tour.TourLocation").
+		Where("user_id = ? AND status = ?", userID, entity.PurchaseStatusPaid).
+		Find(&purchases).Error
+	if err != nil {
+		return nil, fmt.Errorf("get calendar purchases: %w", err)
+	}
+	return purchases, nil
+}
+
+// GetProviderTourEvents returns the events of the tours of a provider with
+// their tour and its location, and the number of paid places of every event.
+func (r *TourismRepo) GetProviderTourEvents(ownerID uuid.UUID) ([]*entity.TourEvent, map[uuid.UUID]int, error) {
+	var tourEvents []*entity.TourEvent
+	err := r.PG.Conn.
+		Preload("Tour.TourLocation").
+		Joins("JOIN tourism.tours ON tourism.tours.id = tourism.tour_events.tour_id AND tourism.tours.deleted_at IS NULL").
+		Where("tourism.tours.owner_id = ?", ownerID).
+		Order("tourism.tour_events.date").
+		Find(&tourEvents).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("get provider tour events: %w", err)
+	}
+
+	var rows []struct {
+		TourEventID uuid.UUID
+		Sold        int
+	}
+	err = r.PG.Conn.Model(&entity.Purchase{}).
+		Select("tourism.purchases.tour_event_id, SUM(tourism.purchases.quantity) AS sold").
+		Joins("JOIN tourism.tour_events ON tourism.tour_events.id = tourism.purchases.tour_event_id").
+		Joins("JOIN tourism.tours ON tourism.tours.id = tourism.tour_events.tour_id").
+		Where("tourism.tours.owner_id = ? AND tourism.purchases.status = ?", ownerID, entity.PurchaseStatusPaid).
+		Group("tourism.purchases.tour_event_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("count sold places: %w", err)
+	}
+	sold := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		sold[row.TourEventID] = row.Sold
+	}
+	return tourEvents, sold, nil
+}
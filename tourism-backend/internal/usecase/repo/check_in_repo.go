@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"tourism-backend/internal/entity"
+)
+
+// CreateCheckIn stores the check-in unless its seat was checked in before. It
+// returns the check-in of the seat and whether it is the new one, so that two
+// scans of the same ticket cannot both succeed.
+func (r *TourismRepo) CreateCheckIn(checkIn *entity.CheckIn) (*entity.CheckIn, bool, error) {
+	result := r.PG.Conn.Clauses(clause.OnConflict{DoNothing: true}).Create(checkIn)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("create check-in: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return checkIn, true, nil
+	}
+
+	existing, err := r.GetCheckIn(checkIn.PurchaseID, checkIn.Seat)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return nil, false, fmt.Errorf("create check-in: seat %d of purchase %s was not stored", checkIn.Seat, checkIn.PurchaseID)
+	}
+	return existing, false, nil
+}
+
+// GetCheckIn returns the check-in of a seat, or nil when it was not used yet.
+func (r *TourismRepo) GetCheckIn(purchaseID uuid.UUID, seat int) (*entity.CheckIn, error) {
+	var checkIn entity.CheckIn
+	err := r.PG.Conn.Where("purchase_id = ? AND seat = ?", purchaseID, seat).First(&checkIn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get check-in: %w", err)
+	}
+	return &checkIn, nil
+}
+
+// GetAttendance returns the paid purchases of a tour event with their users
+// and the check-ins of the tour event.
+func (r *TourismRepo) GetAttendance(tourEventID uuid.UUID) ([]*entity.Purchase, []*entity.CheckIn, error) {
+	var purchases []*entity.Purchase
+	err := r.PG.Conn.Preload("User").
+		Where("tour_event_id = ? AND status = ?", tourEventID, entity.PurchaseStatusPaid).
+		Order("created_at").
+		Find(&purchases).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("get attendance purchases: %w", err)
+	}
+
+	var checkIns []*entity.CheckIn
+	if err := r.PG.Conn.Where("tour_event_id = ?", tourEventID).Find(&checkIns).Error; err != nil {
+		return nil, nil, fmt.Errorf("get attendance check-ins: %w", err)
+	}
+	return purchases, checkIns, nil
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"tourism-backend/internal/entity"
+)
+
+// GetToursNearby returns the tours with a location inside the area of the query,
+// nearest first. Distances are great-circle distances computed by earthdistance.
+func (r *TourismRepo) GetToursNearby(query *entity.GeoQuery) ([]*entity.TourNearby, error) {
+	origin := query.Origin
+
+	var area string
+	var args []interface{}
+	switch {
+	case query.Bounds == nil:
+		// earth_box may hold points slightly farther than the radius, earth_distance drops them.
+		area = `earth_box(ll_to_earth(?, ?), ?) @> ll_to_earth(l.latitude, l.longitude)
+			AND earth_distance(ll_to_earth(?, ?), ll_to_earth(l.latitude, l.longitude)) <= ?`
+		radius := query.RadiusKm * 1000
+		args = append(args, origin.Latitude, origin.Longitude, radius, origin.Latitude, origin.Longitude, radius)
+	case query.Bounds.West <= query.Bounds.East:
+		area = "l.latitude BETWEEN ? AND ? AND l.longitude BETWEEN ? AND ?"
+		args = append(args, query.Bounds.South, query.Bounds.North, query.Bounds.West, query.Bounds.East)
+	default:
+		area = "l.latitude BETWEEN ? AND ? AND (l.longitude >= ? OR l.longitude <= ?)"
+		args = append(args, query.Bounds.South, query.Bounds.North, query.Bounds.West, query.Bounds.East)
+	}
+
+	if query.Filter != nil && !query.Filter.IsEmpty() {
+		conditions, filterArgs := tourEventFilterSQL(query.Filter)
+		area += ` AND EXISTS (SELECT 1 FROM tourism.tour_events
+			WHERE tourism.tour_events.tour_id = l.tour_id AND tourism.tour_events.deleted_at IS NULL AND ` + conditions + ")"
+		args = append(args, filterArgs...)
+	}
+
+	args = append([]interface{}{origin.Latitude, origin.Longitude}, args...)
+	args = append(args, query.Limit, query.Offset)
+
+	var rows []struct {
+		ID         uuid.UUID
+		DistanceKm float64
+	}
+	err := r.PG.Conn.Raw(`
+		SELECT l.tour_id AS id,
+		       MIN(earth_distance(ll_to_earth(?, ?), ll_to_earth(l.latitude, l.longitude))) / 1000 AS distance_km
+		FROM tourism.tour_locations l
+		JOIN tourism.tours t ON t.id = l.tour_id AND t.deleted_at IS NULL AND t.archived_at IS NULL
+		WHERE l.deleted_at IS NULL
+		  AND `+area+`
+		GROUP BY l.tour_id
+		ORDER BY distance_km, l.tour_id
+		LIMIT ? OFFSET ?`,
+		args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("get tours nearby: %w", err)
+	}
+	if len(rows) == 0 {
+		return []*entity.TourNearby{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	var tours []entity.Tour
+	if err := r.PG.Conn.Preload("TourImages", orderedImages).Preload("TourLocation").Where("id IN ?", ids).Find(&tours).Error; err != nil {
+		return nil, fmt.Errorf("get tours nearby: %w", err)
+	}
+	toursByID := make(map[uuid.UUID]entity.Tour, len(tours))
+	for _, tour := range tours {
+		toursByID[tour.ID] = tour
+	}
+
+	results := make([]*entity.TourNearby, 0, len(rows))
+	for _, row := range rows {
+		tour, ok := toursByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, &entity.TourNearby{Tour: tour, DistanceKm: row.DistanceKm})
+	}
+	return results, nil
+}
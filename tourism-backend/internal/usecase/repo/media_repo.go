@@ -0,0 +1,306 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/imaging"
+	"tourism-backend/pkg/media"
+)
+
+// Renditions of uploaded images. List pages show thumbnails and cards, the
+// full rendition replaces the original, which is never stored.
+var (
+	_imageRenditions = []imaging.Rendition{
+		{Name: "thumb", MaxWidth: 320, MaxHeight: 320},
+		{Name: "card", MaxWidth: 800, MaxHeight: 600},
+		{Name: "full", MaxWidth: 1920, MaxHeight: 1920},
+	}
+	_reviewPhotoRenditions = []imaging.Rendition{
+		{Name: "thumb", MaxWidth: 320, MaxHeight: 320},
+		{Name: "full", MaxWidth: 1920, MaxHeight: 1920},
+	}
+	_avatarRenditions = []imaging.Rendition{
+		{Name: "full", MaxWidth: 512, MaxHeight: 512},
+	}
+	_panoramaRenditions = []imaging.Rendition{
+		{Name: "thumb", MaxWidth: 640, MaxHeight: 320},
+		{Name: "full", MaxWidth: 8192, MaxHeight: 4096},
+	}
+)
+
+// savedImage holds the keys and URLs of the renditions of an image by name,
+// and the size of its full rendition.
+type savedImage struct {
+	Keys   map[string]string
+	URLs   map[string]string
+	Width  int
+	Height int
+}
+
+// saveImage validates an uploaded image of at most maxPixels pixels, renders
+// it and puts the renditions into the media store under dir.
+func (r *TourismRepo) saveImage(file *multipart.FileHeader, dir string, renditions []imaging.Rendition, maxPixels int) (*savedImage, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	return r.putImage(file.Filename, data, dir, renditions, maxPixels)
+}
+
+func (r *TourismRepo) putImage(filename string, data []byte, dir string, renditions []imaging.Rendition, maxPixels int) (*savedImage, error) {
+	outputs, err := imaging.Process(data, renditions, maxPixels)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	saved := &savedImage{Keys: make(map[string]string, len(outputs)), URLs: make(map[string]string, len(outputs))}
+	name := uuid.New().String()
+	for _, output := range outputs {
+		key := dir + "/" + name + "-" + output.Name + imaging.Ext
+		if err := r.Media.Put(context.Background(), key, bytes.NewReader(output.Data), int64(len(output.Data)), imaging.ContentType); err != nil {
+			return nil, err
+		}
+		saved.Keys[output.Name] = key
+		saved.URLs[output.Name] = r.Media.URL(key)
+		if output.Name == "full" {
+			saved.Width, saved.Height = output.Width, output.Height
+		}
+	}
+	return saved, nil
+}
+
+// saveVideo validates an uploaded video by its content and puts it into the
+// media store under dir.
+func (r *TourismRepo) saveVideo(file *multipart.FileHeader, dir string) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	key, err := r.putVideo(file.Filename, src, file.Size, dir)
+	if err != nil {
+		return "", err
+	}
+	return r.Media.URL(key), nil
+}
+
+// putVideo validates a video by its content and puts it into the media store
+// under dir, returning its key.
+func (r *TourismRepo) putVideo(filename string, src io.Reader, size int64, dir string) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	contentType, ext, err := media.DetectVideo(head[:n])
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+
+	key := dir + "/" + uuid.New().String() + ext
+	body := io.MultiReader(bytes.NewReader(head[:n]), src)
+	if err := r.Media.Put(context.Background(), key, body, size, contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// orderedImages orders the preloaded images of a tour, the cover image first.
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("is_cover DESC, position, created_at")
+}
+
+// orderedMedia orders the preloaded videos or panoramas of a tour.
+func orderedMedia(db *gorm.DB) *gorm.DB {
+	return db.Order("position, created_at")
+}
+
+// nextMediaPosition returns the position after the last media of a tour of the
+// same kind as model.
+func nextMediaPosition(tx *gorm.DB, model interface{}, tourID uuid.UUID) (int, error) {
+	var position int
+	err := tx.Model(model).Where("tour_id = ?", tourID).Select("COALESCE(MAX(position) + 1, 0)").Scan(&position).Error
+	if err != nil {
+		return 0, fmt.Errorf("get media position: %w", err)
+	}
+	return position, nil
+}
+
+// tourMediaKind describes the table of a kind of tour media and the columns
+// holding the URLs of its files.
+type tourMediaKind struct {
+	model      func() interface{}
+	urlColumns []string
+}
+
+var _tourMedia = map[string]tourMediaKind{
+	entity.MediaKindImages: {
+		model:      func() interface{} { return &entity.Image{} },
+		urlColumns: []string{"image_url", "thumbnail_url", "card_url"},
+	},
+	entity.MediaKindVideos: {
+		model:      func() interface{} { return &entity.Video{} },
+		urlColumns: []string{"video_url"},
+	},
+	entity.MediaKindPanoramas: {
+		model:      func() interface{} { return &entity.Panorama{} },
+		urlColumns: []string{"panorama_url", "thumbnail_url"},
+	},
+}
+
+// DeleteTourMedia deletes an image, video or panorama of a tour with its files.
+// It returns false when the tour has no such media.
+func (r *TourismRepo) DeleteTourMedia(tourID uuid.UUID, kind string, mediaID uuid.UUID) (bool, error) {
+	mediaKind := _tourMedia[kind]
+
+	row := map[string]interface{}{}
+	err := r.PG.Conn.Model(mediaKind.model()).Select(mediaKind.urlColumns).
+		Where("id = ? AND tour_id = ?", mediaID, tourID).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get tour media: %w", err)
+	}
+
+	err = r.PG.Conn.Unscoped().Where("id = ? AND tour_id = ?", mediaID, tourID).Delete(mediaKind.model()).Error
+	if err != nil {
+		return false, fmt.Errorf("delete tour media: %w", err)
+	}
+
+	// Files left behind by a failed delete are collected as garbage later.
+	for _, value := range row {
+		if url, ok := value.(string); ok && url != "" {
+			if key, ok := r.Media.KeyFromURL(url); ok {
+				r.deleteMedia(key)
+			}
+		}
+	}
+	return true, nil
+}
+
+var errMediaOrderMismatch = errors.New("media order does not list the media of the tour")
+
+// ReorderTourMedia positions the media of one kind of a tour in the order of
+// ids. It returns false when ids are not exactly the media of the tour.
+func (r *TourismRepo) ReorderTourMedia(tourID uuid.UUID, kind string, ids []uuid.UUID) (bool, error) {
+	mediaKind := _tourMedia[kind]
+
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(mediaKind.model()).Where("tour_id = ?", tourID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return errMediaOrderMismatch
+		}
+		for position, id := range ids {
+			result := tx.Model(mediaKind.model()).Where("id = ? AND tour_id = ?", id, tourID).Update("position", position)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errMediaOrderMismatch
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errMediaOrderMismatch) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reorder tour media: %w", err)
+	}
+	return true, nil
+}
+
+// SetTourCover makes an image the cover of its tour. It returns false when the
+// tour has no such image.
+func (r *TourismRepo) SetTourCover(tourID, imageID uuid.UUID) (bool, error) {
+	found := false
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.Image{}).Where("id = ? AND tour_id = ?", imageID, tourID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		found = true
+		return tx.Model(&entity.Image{}).Where("tour_id = ?", tourID).Update("is_cover", gorm.Expr("id = ?", imageID)).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("set tour cover: %w", err)
+	}
+	return found, nil
+}
+
+// _mediaReferences selects every media URL or key in use. Media of deleted
+// tours, reviews and users is not in use anymore.
+const _mediaReferences = `
+	SELECT url FROM (
+		SELECT unnest(ARRAY[m.image_url, m.thumbnail_url, m.card_url]) AS url FROM tourism.images m
+			JOIN tourism.tours t ON t.id = m.tour_id AND t.deleted_at IS NULL WHERE m.deleted_at IS NULL
+		UNION ALL
+		SELECT m.video_url FROM tourism.videos m
+			JOIN tourism.tours t ON t.id = m.tour_id AND t.deleted_at IS NULL WHERE m.deleted_at IS NULL
+		UNION ALL
+		SELECT unnest(ARRAY[m.panorama_url, m.thumbnail_url]) FROM tourism.panoramas m
+			JOIN tourism.tours t ON t.id = m.tour_id AND t.deleted_at IS NULL WHERE m.deleted_at IS NULL
+		UNION ALL
+		SELECT unnest(ARRAY[p.photo_url, p.thumbnail_url]) FROM tourism.review_photos p
+			JOIN tourism.reviews rv ON rv.id = p.review_id AND rv.deleted_at IS NULL WHERE p.deleted_at IS NULL
+		UNION ALL
+		SELECT avatar_url FROM tourism.users WHERE deleted_at IS NULL
+		UNION ALL
+		SELECT key FROM tourism.upload_chunks WHERE deleted_at IS NULL
+	) refs
+	WHERE url IS NOT NULL AND url <> ''`
+
+// CollectUnreferencedMedia deletes the stored files no media in use refers to,
+// returning how many were deleted. Files modified after olderThan are kept, they
+// may belong to an upload whose rows are not committed yet.
+func (r *TourismRepo) CollectUnreferencedMedia(olderThan time.Time) (int, error) {
+	var urls []string
+	if err := r.PG.Conn.Raw(_mediaReferences).Scan(&urls).Error; err != nil {
+		return 0, fmt.Errorf("get media references: %w", err)
+	}
+	// Private media and upload chunks are referenced by their key, the rest by URL.
+	referenced := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		referenced[url] = true
+		if key, ok := r.Media.KeyFromURL(url); ok {
+			referenced[key] = true
+		}
+	}
+
+	deleted := 0
+	err := r.Media.List(context.Background(), func(key string, modTime time.Time) error {
+		if referenced[key] || modTime.After(olderThan) {
+			return nil
+		}
+		if err := r.Media.Delete(context.Background(), key); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("collect unreferenced media: %w", err)
+	}
+	return deleted, nil
+}
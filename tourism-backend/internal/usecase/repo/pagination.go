@@ -0,0 +1,135 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// pageOrder is a keyset ordering of a listing. Rows are ordered by expr and then
+// by their ID, so a page continues right after the (key, ID) pair of its cursor.
+// Keys must never be NULL, expressions fall back to a sentinel with COALESCE.
+type pageOrder struct {
+	expr   string
+	desc   bool
+	isTime bool
+}
+
+// Sold places of a tour event and of a tour, ranking listings by popularity.
+const (
+	_eventSoldPlaces = `(SELECT COALESCE(SUM(p.quantity), 0) FROM tourism.purchases p
+		WHERE p.tour_event_id = tourism.tour_events.id AND p.status = '` + entity.PurchaseStatusPaid + `' AND p.deleted_at IS NULL)::float8`
+	_tourSoldPlaces = `(SELECT COALESCE(SUM(p.quantity), 0) FROM tourism.purchases p
+		JOIN tourism.tour_events e ON e.id = p.tour_event_id
+		WHERE e.tour_id = tourism.tours.id AND p.status = '` + entity.PurchaseStatusPaid + `' AND p.deleted_at IS NULL)::float8`
+)
+
+// _tourOrders ranks tours by their next open event, their cheapest open event,
+// places sold, average rating and creation time.
+var _tourOrders = map[string]pageOrder{
+	entity.SortDate: {
+		expr: `COALESCE((SELECT MIN(e.date) FROM tourism.tour_events e
+			WHERE e.tour_id = tourism.tours.id AND e.is_opened AND e.date > now() AND e.deleted_at IS NULL), '9999-12-31')`,
+		isTime: true,
+	},
+	entity.SortPrice: {
+		expr: `COALESCE((SELECT MIN(e.price) FROM tourism.tour_events e
+			WHERE e.tour_id = tourism.tours.id AND e.is_opened AND e.date > now() AND e.deleted_at IS NULL), 1e15)::float8`,
+	},
+	entity.SortPopularity: {expr: _tourSoldPlaces, desc: true},
+	entity.SortRating: {
+		expr: `COALESCE((SELECT AVG(r.rating) FROM tourism.reviews r
+			WHERE r.tour_id = tourism.tours.id AND r.deleted_at IS NULL), 0)::float8`,
+		desc: true,
+	},
+	entity.SortNewest: {expr: "tourism.tours.created_at", desc: true, isTime: true},
+}
+
+var _tourEventOrders = map[string]pageOrder{
+	entity.SortDate:       {expr: "tourism.tour_events.date", isTime: true},
+	entity.SortPrice:      {expr: "tourism.tour_events.price::float8"},
+	entity.SortPopularity: {expr: _eventSoldPlaces, desc: true},
+	entity.SortRating: {
+		expr: `COALESCE((SELECT AVG(r.rating) FROM tourism.reviews r
+			WHERE r.tour_id = tourism.tour_events.tour_id AND r.deleted_at IS NULL), 0)::float8`,
+		desc: true,
+	},
+	entity.SortNewest: {expr: "tourism.tour_events.created_at", desc: true, isTime: true},
+}
+
+var _purchaseOrders = map[string]pageOrder{
+	entity.SortDate: {
+		expr:   "(SELECT e.date FROM tourism.tour_events e WHERE e.id = tourism.purchases.tour_event_id)",
+		isTime: true,
+	},
+	entity.SortPrice:  {expr: "tourism.purchases.amount::float8"},
+	entity.SortNewest: {expr: "tourism.purchases.created_at", desc: true, isTime: true},
+}
+
+var _userOrders = map[string]pageOrder{
+	entity.SortNewest: {expr: "tourism.users.created_at", desc: true, isTime: true},
+}
+
+type pageKey struct {
+	ID        uuid.UUID
+	SortTime  time.Time
+	SortValue float64
+}
+
+// pageIDs returns the IDs of the rows of query on the requested page, and the
+// cursor of the following page or nil when this is the last one. idColumn is
+// the qualified primary key of the listed table.
+func pageIDs(query *gorm.DB, idColumn string, order pageOrder, page *entity.PageQuery) ([]uuid.UUID, *entity.Cursor, error) {
+	keyColumn := "sort_value"
+	if order.isTime {
+		keyColumn = "sort_time"
+	}
+	direction, compare := "ASC", ">"
+	if order.desc {
+		direction, compare = "DESC", "<"
+	}
+
+	if after := page.After; after != nil {
+		var key interface{} = after.Value
+		if order.isTime {
+			key = after.Time
+		}
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", order.expr, idColumn, compare), key, after.ID)
+	}
+
+	// One row more than requested tells whether another page follows.
+	var keys []pageKey
+	err := query.
+		Select(fmt.Sprintf("%s AS id, %s AS %s", idColumn, order.expr, keyColumn)).
+		Order(fmt.Sprintf("%s %s, %s %s", order.expr, direction, idColumn, direction)).
+		Limit(page.Limit + 1).
+		Scan(&keys).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *entity.Cursor
+	if len(keys) > page.Limit {
+		keys = keys[:page.Limit]
+		last := keys[len(keys)-1]
+		next = &entity.Cursor{Sort: page.Sort, Time: last.SortTime, Value: last.SortValue, ID: last.ID}
+	}
+
+	ids := make([]uuid.UUID, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	return ids, next, nil
+}
+
+// pagePositions maps the IDs of a page to their position, so that the rows
+// loaded for it can be sorted back into page order.
+func pagePositions(ids []uuid.UUID) map[uuid.UUID]int {
+	positions := make(map[uuid.UUID]int, len(ids))
+	for i, id := range ids {
+		positions[id] = i
+	}
+	return positions
+}
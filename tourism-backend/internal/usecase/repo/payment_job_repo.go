@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// ClaimPaymentJob locks the next due payment job for the duration of the lease
+// and loads its purchase. Jobs whose lease ran out are claimed again. It returns
+// nil when there is nothing to do.
+func (r *TourismRepo) ClaimPaymentJob(lease time.Duration) (*entity.PaymentJob, error) {
+	var job entity.PaymentJob
+	now := time.Now()
+
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_run_at <= ?) OR (status = ? AND locked_until < ?)",
+				entity.PaymentJobStatusPending, now, entity.PaymentJobStatusRunning, now).
+			Order("next_run_at").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.PaymentJob{}).
+			Where("id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"status":       entity.PaymentJobStatusRunning,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"locked_until": now.Add(lease),
+			}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim payment job: %w", err)
+	}
+	job.Status = entity.PaymentJobStatusRunning
+	job.Attempts++
+
+	if err := r.PG.Conn.Preload("TourEvent").First(&job.Purchase, "id = ?", job.PurchaseID).Error; err != nil {
+		return nil, fmt.Errorf("claim payment job: load purchase: %w", err)
+	}
+	return &job, nil
+}
+
+// CompletePaymentJob, RetryPaymentJob and FailPaymentJob finish a claimed job.
+// They report false when the lease of the claim ran out and the job was claimed
+// again, the newer claim owns the job then.
+func (r *TourismRepo) CompletePaymentJob(job *entity.PaymentJob) (bool, error) {
+	return r.finishPaymentJob(job, map[string]interface{}{
+		"status":     entity.PaymentJobStatusDone,
+		"last_error": "",
+	})
+}
+
+func (r *TourismRepo) RetryPaymentJob(job *entity.PaymentJob, runAt time.Time, cause error) (bool, error) {
+	return r.finishPaymentJob(job, map[string]interface{}{
+		"status":      entity.PaymentJobStatusPending,
+		"next_run_at": runAt,
+		"last_error":  cause.Error(),
+	})
+}
+
+func (r *TourismRepo) FailPaymentJob(job *entity.PaymentJob, cause error) (bool, error) {
+	return r.finishPaymentJob(job, map[string]interface{}{
+		"status":     entity.PaymentJobStatusFailed,
+		"last_error": cause.Error(),
+	})
+}
+
+// finishPaymentJob updates a job only while it is held by the given claim.
+// Every claim increments the attempts, so they tell the claims apart.
+func (r *TourismRepo) finishPaymentJob(job *entity.PaymentJob, fields map[string]interface{}) (bool, error) {
+	result := r.PG.Conn.Model(&entity.PaymentJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, entity.PaymentJobStatusRunning, job.Attempts).
+		Updates(fields)
+	if result.Error != nil {
+		return false, fmt.Errorf("update payment job: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecoverPaymentJobs queues a payment job for every processing purchase that has none,
+// e.g. purchases that were waiting in memory when the service stopped.
+func (r *TourismRepo) RecoverPaymentJobs() (int64, error) {
+	result := r.PG.Conn.Exec(`
+		INSERT INTO tourism.payment_jobs (purchase_id, status, next_run_at, created_at, updated_at)
+		SELECT p.id, ?, NOW(), NOW(), NOW()
+		FROM tourism.purchases AS p
+		WHERE p.status = ? AND p.deleted_at IS NULL
+		ON CONFLICT (purchase_id) DO NOTHING`,
+		entity.PaymentJobStatusPending, entity.PurchaseStatusProcessing)
+	if result.Error != nil {
+		return 0, fmt.Errorf("recover payment jobs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"tourism-backend/internal/entity"
+)
+
+func (r *TourismRepo) CreatePricingRule(rule *entity.PricingRule) (*entity.PricingRule, error) {
+	if err := r.PG.Conn.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("create pricing rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *TourismRepo) GetPricingRuleByID(ruleID uuid.UUID) (*entity.PricingRule, error) {
+	var rule entity.PricingRule
+	if err := r.PG.Conn.Preload("TourEvent.Tour").First(&rule, "id = ?", ruleID).Error; err != nil {
+		return nil, fmt.Errorf("get pricing rule by id: %w", err)
+	}
+	return &rule, nil
+}
+
+func (r *TourismRepo) GetPricingRulesByTourEventID(tourEventID uuid.UUID) ([]*entity.PricingRule, error) {
+	var rules []*entity.PricingRule
+	err := r.PG.Conn.Where("tour_event_id = ?", tourEventID).Order("created_at").Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("get pricing rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *TourismRepo) DeletePricingRule(ruleID uuid.UUID) error {
+	if err := r.PG.Conn.Delete(&entity.PricingRule{}, "id = ?", ruleID).Error; err != nil {
+		return fmt.Errorf("delete pricing rule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"tourism-backend/internal/entity"
+)
+
+// _promoCodeUseStatuses are the purchase statuses that count as a use of a promo code.
+var _promoCodeUseStatuses = []string{
+	entity.PurchaseStatusProcessing,
+	entity.PurchaseStatusPaid,
+	entity.PurchaseStatusCancelRequested,
+}
+
+func (r *TourismRepo) CreatePromoCode(promoCode *entity.PromoCode) (*entity.PromoCode, error) {
+	if err := r.PG.Conn.Create(promoCode).Error; err != nil {
+		return nil, fmt.Errorf("create promo code: %w", err)
+	}
+	return promoCode, nil
+}
+
+// GetPromoCodeByCode returns nil when there is no such code.
+func (r *TourismRepo) GetPromoCodeByCode(code string) (*entity.PromoCode, error) {
+	var promoCode entity.PromoCode
+	err := r.PG.Conn.Where("code = ?", code).First(&promoCode).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get promo code: %w", err)
+	}
+	return &promoCode, nil
+}
+
+func (r *TourismRepo) GetPromoCodesByCreatorID(creatorID uuid.UUID) ([]*entity.PromoCode, error) {
+	var promoCodes []*entity.PromoCode
+	err := r.PG.Conn.Where("created_by_id = ?", creatorID).Order("created_at DESC").Find(&promoCodes).Error
+	if err != nil {
+		return nil, fmt.Errorf("get promo codes: %w", err)
+	}
+	return promoCodes, nil
+}
+
+func (r *TourismRepo) TourHasCategory(tourID, categoryID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.PG.Conn.Model(&entity.TourCategory{}).
+		Where("tour_id = ? AND category_id = ?", tourID, categoryID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("check tour category: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CountPromoCodeUses returns how many purchases use the promo code in total and by the user.
+func (r *TourismRepo) CountPromoCodeUses(promoCodeID, userID uuid.UUID) (int64, int64, error) {
+	return countPromoCodeUses(r.PG.Conn, promoCodeID, userID)
+}
+
+func countPromoCodeUses(db *gorm.DB, promoCodeID, userID uuid.UUID) (int64, int64, error) {
+	var total, byUser int64
+	uses := func() *gorm.DB {
+		return db.Model(&entity.Purchase{}).
+			Where("promo_code_id = ? AND status IN ?", promoCodeID, _promoCodeUseStatuses)
+	}
+	if err := uses().Count(&total).Error; err != nil {
+		return 0, 0, fmt.Errorf("count promo code uses: %w", err)
+	}
+	if err := uses().Where("user_id = ?", userID).Count(&byUser).Error; err != nil {
+		return 0, 0, fmt.Errorf("count promo code uses: %w", err)
+	}
+	return total, byUser, nil
+}
+
+// applyPromoCode discounts the purchase amount. The promo code row stays locked
+// until the transaction ends so concurrent purchases cannot exceed its limits.
+func applyPromoCode(tx *gorm.DB, purchase *entity.Purchase, promoCode *entity.PromoCode) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&entity.PromoCode{}, "id = ?", promoCode.ID).Error; err != nil {
+		return fmt.Errorf("promo code not found: %w", err)
+	}
+
+	total, byUser, err := countPromoCodeUses(tx, promoCode.ID, purchase.UserID)
+	if err != nil {
+		return err
+	}
+	if (promoCode.MaxUses > 0 && total >= int64(promoCode.MaxUses)) ||
+		(promoCode.MaxUsesPerUser > 0 && byUser >= int64(promoCode.MaxUsesPerUser)) {
+		return fmt.Errorf("promo code %s usage limit reached", promoCode.Code)
+	}
+
+	purchase.PromoCodeID = &promoCode.ID
+	purchase.Discount = promoCode.Discount(purchase.Amount)
+	purchase.Amount -= purchase.Discount
+	return nil
+}
+
+// ApplyPromoCodeToPurchase discounts a processing purchase that has no promo
+// code yet and whose charge has not started.
+func (r *TourismRepo) ApplyPromoCodeToPurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error) {
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := applyPromoCode(tx, purchase, promoCode); err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.Purchase{}).
+			Where("id = ? AND status = ? AND promo_code_id IS NULL AND payment_intent_id = ?",
+				purchase.ID, entity.PurchaseStatusProcessing, "").
+			Updates(map[string]interface{}{
+				"promo_code_id": purchase.PromoCodeID,
+				"discount":      purchase.Discount,
+				"amount":        purchase.Amount,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("update purchase amount: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("purchase %s can no longer be discounted", purchase.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apply promo code: %w", err)
+	}
+	return purchase, nil
+}
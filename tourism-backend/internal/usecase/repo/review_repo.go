@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"mime/multipart"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/imaging"
+)
+
+// _reviewOrders maps the sort options of reviews to their ORDER BY clauses.
+var _reviewOrders = map[string]string{
+	"newest":      "tourism.reviews.created_at DESC",
+	"oldest":      "tourism.reviews.created_at ASC",
+	"rating_desc": "tourism.reviews.rating DESC, tourism.reviews.created_at DESC",
+	"rating_asc":  "tourism.reviews.rating ASC, tourism.reviews.created_at DESC",
+}
+
+// HasAttendedTourEvent reports whether the user paid for the tour event and it took place before now.
+func (r *TourismRepo) HasAttendedTourEvent(userID, tourEventID uuid.UUID, now time.Time) (bool, error) {
+	var count int64
+	err := r.PG.Conn.Model(&entity.Purchase{}).
+		Joins("JOIN tourism.tour_events ON tourism.tour_events.id = tourism.purchases.tour_event_id").
+		Where("tourism.purchases.user_id = ? AND tourism.purchases.tour_event_id = ?", userID, tourEventID).
+		Where("tourism.purchases.status = ? AND tourism.tour_events.date < ?", entity.PurchaseStatusPaid, now).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("check tour event attendance: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *TourismRepo) HasReviewedTourEvent(userID, tourEventID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.PG.Conn.Model(&entity.Review{}).
+		Where("user_id = ? AND tour_event_id = ?", userID, tourEventID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("check review: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *TourismRepo) CreateReview(review *entity.Review, photoFiles []*multipart.FileHeader) (*entity.Review, error) {
+	photos := make([]entity.ReviewPhoto, 0, len(photoFiles))
+	for _, file := range photoFiles {
+		saved, err := r.saveImage(file, "images", _reviewPhotoRenditions, imaging.MaxPixels)
+		if err != nil {
+			return nil, fmt.Errorf("save review photo: %w", err)
+		}
+		photos = append(photos, entity.ReviewPhoto{PhotoURL: saved.URLs["full"], ThumbnailURL: saved.URLs["thumb"]})
+	}
+
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return fmt.Errorf("create review: %w", err)
+		}
+
+		for _, photo := range photos {
+			photo.ReviewID = review.ID
+			if err := tx.Create(&photo).Error; err != nil {
+				return fmt.Errorf("create review photo: %w", err)
+			}
+			review.ReviewPhotos = append(review.ReviewPhotos, photo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (r *TourismRepo) GetReviewByID(reviewID uuid.UUID) (*entity.Review, error) {
+	var review entity.Review
+	err := r.PG.Conn.Preload("Tour").Preload("ReviewPhotos").First(&review, "id = ?", reviewID).Error
+	if err != nil {
+		return nil, fmt.Errorf("get review by id: %w", err)
+	}
+	return &review, nil
+}
+
+func (r *TourismRepo) SetReviewReply(reviewID uuid.UUID, reply string, repliedAt time.Time) error {
+	err := r.PG.Conn.Model(&entity.Review{}).
+		Where("id = ?", reviewID).
+		Updates(map[string]interface{}{"reply": reply, "replied_at": repliedAt}).Error
+	if err != nil {
+		return fmt.Errorf("set review reply: %w", err)
+	}
+	return nil
+}
+
+// GetReviews returns a page of the reviews of a tour with the username of
+// every reviewer, and how many reviews the tour has in total.
+func (r *TourismRepo) GetReviews(filter *entity.ReviewFilter) ([]*entity.Review, int64, error) {
+	var total int64
+	if err := r.PG.Conn.Model(&entity.Review{}).Where("tour_id = ?", filter.TourID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count reviews: %w", err)
+	}
+
+	order, ok := _reviewOrders[filter.Sort]
+	if !ok {
+		order = _reviewOrders["newest"]
+	}
+
+	var reviews []*entity.Review
+	err := r.PG.Conn.Model(&entity.Review{}).
+		Select("tourism.reviews.*, tourism.users.username").
+		Joins("LEFT JOIN tourism.users ON tourism.users.id = tourism.reviews.user_id").
+		Where("tourism.reviews.tour_id = ?", filter.TourID).
+		Preload("ReviewPhotos").
+		Order(order).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("get reviews: %w", err)
+	}
+	return reviews, total, nil
+}
+
+// GetTourRatings aggregates the reviews of the tours. Tours without reviews
+// get an empty rating.
+func (r *TourismRepo) GetTourRatings(tourIDs []uuid.UUID) (map[uuid.UUID]*entity.TourRating, error) {
+	var rows []struct {
+		TourID uuid.UUID
+		Rating int
+		Count  int64
+	}
+	err := r.PG.Conn.Model(&entity.Review{}).
+		Select("tour_id, rating, COUNT(*) AS count").
+		Where("tour_id IN ?", tourIDs).
+		Group("tour_id, rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("get tour ratings: %w", err)
+	}
+
+	ratings := make(map[uuid.UUID]*entity.TourRating, len(tourIDs))
+	for _, tourID := range tourIDs {
+		ratings[tourID] = entity.NewTourRating()
+	}
+	for _, row := range rows {
+		ratings[row.TourID].Add(row.Rating, row.Count)
+	}
+	return ratings, nil
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"tourism-backend/internal/entity"
+)
+
+// ReplaceWaypoints swaps the route of a tour for the given waypoints and moves
+// the location of the tour to the meeting point, which the first waypoint is.
+func (r *TourismRepo) ReplaceWaypoints(tourID uuid.UUID, waypoints []*entity.Waypoint) error {
+	return r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("tour_id = ?", tourID).Delete(&entity.Waypoint{}).Error; err != nil {
+			return fmt.Errorf("replace waypoints: %w", err)
+		}
+		if len(waypoints) == 0 {
+			return nil
+		}
+		if err := tx.Create(&waypoints).Error; err != nil {
+			return fmt.Errorf("replace waypoints: %w", err)
+		}
+
+		meeting := waypoints[0]
+		var location entity.TourLocation
+		err := tx.Where("tour_id = ?", tourID).First(&location).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			location = entity.TourLocation{TourID: tourID, Latitude: meeting.Latitude, Longitude: meeting.Longitude}
+			err = tx.Create(&location).Error
+		case err == nil:
+			err = tx.Model(&location).Updates(map[string]interface{}{
+				"latitude":  meeting.Latitude,
+				"longitude": meeting.Longitude,
+			}).Error
+		}
+		if err != nil {
+			return fmt.Errorf("move tour location: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TourismRepo) GetWaypoints(tourID uuid.UUID) ([]*entity.Waypoint, error) {
+	var waypoints []*entity.Waypoint
+	if err := r.PG.Conn.Where("tour_id = ?", tourID).Order("position").Find(&waypoints).Error; err != nil {
+		return nil, fmt.Errorf("get waypoints: %w", err)
+	}
+	return waypoints, nil
+}
+
+// SaveTourTrack replaces the track of a tour.
+func (r *TourismRepo) SaveTourTrack(track *entity.TourTrack) error {
+	return r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("tour_id = ?", track.TourID).Delete(&entity.TourTrack{}).Error; err != nil {
+			return fmt.Errorf("save tour track: %w", err)
+		}
+		if err := tx.Create(track).Error; err != nil {
+			return fmt.Errorf("save tour track: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetTourTrack returns nil when the tour has no track.
+func (r *TourismRepo) GetTourTrack(tourID uuid.UUID) (*entity.TourTrack, error) {
+	var track entity.TourTrack
+	err := r.PG.Conn.Where("tour_id = ?", tourID).First(&track).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tour track: %w", err)
+	}
+	return &track, nil
+}
+
+func (r *TourismRepo) DeleteTourTrack(tourID uuid.UUID) error {
+	if err := r.PG.Conn.Unscoped().Where("tour_id = ?", tourID).Delete(&entity.TourTrack{}).Error; err != nil {
+		return fmt.Errorf("delete tour track: %w", err)
+	}
+	return nil
+}
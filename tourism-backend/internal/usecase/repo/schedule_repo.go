@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func (r *TourismRepo) CreateTourSchedule(schedule *entity.TourSchedule) (*entity.TourSchedule, error) {
+	if err := r.PG.Conn.Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("create tour schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// GetTourSchedule returns a schedule with its tour, or nil if there is none.
+func (r *TourismRepo) GetTourSchedule(scheduleID uuid.UUID) (*entity.TourSchedule, error) {
+	var schedule entity.TourSchedule
+	err := r.PG.Conn.Preload("Tour").First(&schedule, "id = ?", scheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tour schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+func (r *TourismRepo) GetTourSchedules(tourID uuid.UUID) ([]*entity.TourSchedule, error) {
+	var schedules []*entity.TourSchedule
+	err := r.PG.Conn.Where("tour_id = ?", tourID).Order("created_at").Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("get tour schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// UpdateTourSchedule saves the settings of a schedule.
+func (r *TourismRepo) UpdateTourSchedule(schedule *entity.TourSchedule) error {
+	err := r.PG.Conn.Model(schedule).
+		Select("rrule", "time_zone", "starts_at", "price", "place", "amount_of_places", "exception_dates").
+		Updates(schedule).Error
+	if err != nil {
+		return fmt.Errorf("update tour schedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedulesToGenerate returns the schedules of listed tours whose events
+// are not generated up to until yet.
+func (r *TourismRepo) GetSchedulesToGenerate(until time.Time) ([]*entity.TourSchedule, error) {
+	var schedules []*entity.TourSchedule
+	err := r.PG.Conn.
+		Joins("JOIN tourism.tours ON tourism.tours.id = tourism.tour_schedules.tour_id").
+		Where("tourism.tours.deleted_at IS NULL AND tourism.tours.archived_at IS NULL").
+		Where("tourism.tour_schedules.generated_until IS NULL OR tourism.tour_schedules.generated_until < ?", until).
+		Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("get schedules to generate: %w", err)
+	}
+	return schedules, nil
+}
+
+// unsoldOccurrences selects the events of a schedule from a date on that
+// were neither cancelled nor detached from the schedule, were never purchased,
+// even by purchases that ended since, and have nobody on their waitlist.
+func unsoldOccurrences(tx *gorm.DB, scheduleID uuid.UUID, from time.Time) *gorm.DB {
+	return tx.Model(&entity.TourEvent{}).
+		Where("schedule_id = ? AND date >= ? AND cancelled_at IS NULL AND NOT detached", scheduleID, from).
+		Where(`NOT EXISTS (SELECT 1 FROM tourism.purchases p
+			WHERE p.tour_event_id = tourism.tour_events.id AND p.deleted_at IS NULL)`).
+		Where(`NOT EXISTS (SELECT 1 FROM tourism.waitlist_entries w
+			WHERE w.tour_event_id = tourism.tour_events.id AND w.deleted_at IS NULL)`)
+}
+
+// SyncScheduleOccurrences makes the events of a schedule from a date on match
+// the given occurrences. Unsold events at an occurrence take the defaults of
+// the schedule, other unsold events are deleted and missing occurrences are
+// created. Sold, cancelled and detached events are left alone, and the
+// occurrence a detached event was created for is not created again. It
+// returns how many events were created.
+func (r *TourismRepo) SyncScheduleOccurrences(schedule *entity.TourSchedule, from time.Time, occurrences []time.Time, generatedUntil time.Time) (int, error) {
+	created := 0
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		wanted := make(map[int64]time.Time, len(occurrences))
+		for _, occurrence := range occurrences {
+			wanted[occurrence.Unix()] = occurrence
+		}
+
+		var detached []time.Time
+		if err := tx.Model(&entity.TourEvent{}).
+			Where("schedule_id = ? AND detached", schedule.ID).
+			Pluck("COALESCE(scheduled_date, date)", &detached).Error; err != nil {
+			return fmt.Errorf("get detached occurrences: %w", err)
+		}
+		for _, occurrence := range detached {
+			delete(wanted, occurrence.Unix())
+		}
+
+		var unsold []*entity.TourEvent
+		if err := unsoldOccurrences(tx, schedule.ID, from).Find(&unsold).Error; err != nil {
+			return fmt.Errorf("get unsold occurrences: %w", err)
+		}
+		for _, tourEvent := range unsold {
+			if _, ok := wanted[tourEvent.Date.Unix()]; !ok {
+				if err := tx.Delete(tourEvent).Error; err != nil {
+					return fmt.Errorf("delete occurrence: %w", err)
+				}
+				continue
+			}
+			delete(wanted, tourEvent.Date.Unix())
+
+			held, err := heldSeats(tx, tourEvent.ID)
+			if err != nil {
+				return err
+			}
+			err = tx.Model(tourEvent).Updates(map[string]interface{}{
+				"price":            schedule.Price,
+				"place":            schedule.Place,
+				"amount_of_places": max(schedule.AmountOfPlaces-held, 0),
+			}).Error
+			if err != nil {
+				return fmt.Errorf("update occurrence: %w", err)
+			}
+		}
+
+		for _, occurrence := range occurrences {
+			if _, ok := wanted[occurrence.Unix()]; !ok {
+				continue
+			}
+			scheduleID := schedule.ID
+			scheduledDate := occurrence
+			// Sold and cancelled events already hold their date.
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.TourEvent{
+				TourID:         schedule.TourID,
+				ScheduleID:     &scheduleID,
+				ScheduledDate:  &scheduledDate,
+				Date:           occurrence,
+				Price:          schedule.Price,
+				Place:          schedule.Place,
+				AmountOfPlaces: schedule.AmountOfPlaces,
+				IsOpened:       true,
+			})
+			if result.Error != nil {
+				return fmt.Errorf("create occurrence: %w", result.Error)
+			}
+			created += int(result.RowsAffected)
+		}
+
+		err := tx.Model(schedule).Update("generated_until", generatedUntil).Error
+		if err != nil {
+			return fmt.Errorf("update generated until: %w", err)
+		}
+		schedule.GeneratedUntil = &generatedUntil
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// DeleteTourSchedule deletes a schedule and its unsold events from a date on.
+// Sold and detached events stay.
+func (r *TourismRepo) DeleteTourSchedule(scheduleID uuid.UUID, from time.Time) error {
+	return r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		if err := unsoldOccurrences(tx, scheduleID, from).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("get unsold occurrences: %w", err)
+		}
+		if len(ids) > 0 {
+			if err := tx.Where("id IN ?", ids).Delete(&entity.TourEvent{}).Error; err != nil {
+				return fmt.Errorf("delete occurrences: %w", err)
+			}
+		}
+		if err := tx.Where("id = ?", scheduleID).Delete(&entity.TourSchedule{}).Error; err != nil {
+			return fmt.Errorf("delete tour schedule: %w", err)
+		}
+		return nil
+	})
+}
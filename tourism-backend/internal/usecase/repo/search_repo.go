@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"tourism-backend/internal/entity"
+)
+
+// _searchHeadlineOptions configures ts_headline for search snippets.
+const _searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+// SearchTours ranks tours by how well their search_vector matches the query.
+// The query uses web search syntax: quoted phrases, OR and -word.
+func (r *TourismRepo) SearchTours(query string, limit, offset int) ([]*entity.TourSearchResult, error) {
+	var rows []struct {
+		ID            uuid.UUID
+		Rank          float64
+		NameHighlight string
+		Snippet       string
+	}
+	err := r.PG.Conn.Raw(`
+		SELECT t.id,
+		       ts_rank(t.search_vector, q.query) AS rank,
+		       ts_headline('simple', coalesce(t.name, ''), q.query, 'StartSel=<b>, StopSel=</b>, HighlightAll=true') AS name_highlight,
+		       ts_headline('simple', coalesce(t.description, '') || ' ' || coalesce(t.route, ''), q.query, ?) AS snippet
+		FROM tourism.tours t,
+		     websearch_to_tsquery('simple', ?) AS q(query)
+		WHERE t.search_vector @@ q.query
+		  AND t.deleted_at IS NULL
+		  AND t.archived_at IS NULL
+		ORDER BY rank DESC, t.created_at DESC
+		LIMIT ? OFFSET ?`,
+		_searchHeadlineOptions, query, limit, offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("search tours: %w", err)
+	}
+	if len(rows) == 0 {
+		return []*entity.TourSearchResult{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	var tours []entity.Tour
+	if err := r.PG.Conn.Preload("TourImages", orderedImages).Where("id IN ?", ids).Find(&tours).Error; err != nil {
+		return nil, fmt.Errorf("search tours: %w", err)
+	}
+	toursByID := make(map[uuid.UUID]entity.Tour, len(tours))
+	for _, tour := range tours {
+		toursByID[tour.ID] = tour
+	}
+
+	results := make([]*entity.TourSearchResult, 0, len(rows))
+	for _, row := range rows {
+		tour, ok := toursByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, &entity.TourSearchResult{
+			Tour:          tour,
+			Rank:          row.Rank,
+			NameHighlight: row.NameHighlight,
+			Snippet:       row.Snippet,
+		})
+	}
+	return results, nil
+}
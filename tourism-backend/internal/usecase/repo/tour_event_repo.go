@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// UpdateTourEvent sets the given columns of a tour event. A non-nil capacity
+// becomes the new number of places: the free places are what is left after
+// the seats held by purchases and waitlist offers. It changes nothing and
+// returns false if capacity is below those held seats. A scheduled event whose
+// date, price, place or places change is detached from its schedule.
+func (r *TourismRepo) UpdateTourEvent(tourEventID uuid.UUID, fields map[string]interface{}, capacity *float64) (bool, error) {
+	updated := false
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var tourEvent entity.TourEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&tourEvent, "id = ?", tourEventID).Error; err != nil {
+			return fmt.Errorf("tour event not found: %w", err)
+		}
+
+		if capacity != nil {
+			held, err := heldSeats(tx, tourEventID)
+			if err != nil {
+				return err
+			}
+			if *capacity < held {
+				return nil
+			}
+			fields["amount_of_places"] = *capacity - held
+		}
+
+		if tourEvent.ScheduleID != nil && !tourEvent.Detached && detachesFromSchedule(fields) {
+			fields["detached"] = true
+			if tourEvent.ScheduledDate == nil {
+				fields["scheduled_date"] = tourEvent.Date
+			}
+		}
+
+		if len(fields) > 0 {
+			if err := tx.Model(&tourEvent).Updates(fields).Error; err != nil {
+				return fmt.Errorf("update tour event: %w", err)
+			}
+		}
+		updated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+// detachesFromSchedule reports whether fields change a column that the
+// schedule of a tour event sets.
+func detachesFromSchedule(fields map[string]interface{}) bool {
+	for _, column := range []string{"date", "price", "place", "amount_of_places"} {
+		if _, ok := fields[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetHeldSeats counts the places of a tour event that are not free, see heldSeats.
+func (r *TourismRepo) GetHeldSeats(tourEventID uuid.UUID) (float64, error) {
+	return heldSeats(r.PG.Conn, tourEventID)
+}
+
+// heldSeats counts the places of a tour event taken by unpaid and paid
+// purchases and by open waitlist offers.
+func heldSeats(tx *gorm.DB, tourEventID uuid.UUID) (float64, error) {
+	var purchased, offered float64
+	err := tx.Model(&entity.Purchase{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("tour_event_id = ? AND status IN ?", tourEventID,
+			[]string{entity.PurchaseStatusProcessing, entity.PurchaseStatusPaid}).
+		Scan(&purchased).Error
+	if err != nil {
+		return 0, fmt.Errorf("count purchased seats: %w", err)
+	}
+	err = tx.Model(&entity.WaitlistEntry{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("tour_event_id = ? AND status = ?", tourEventID, entity.WaitlistStatusOffered).
+		Scan(&offered).Error
+	if err != nil {
+		return 0, fmt.Errorf("count offered seats: %w", err)
+	}
+	return purchased + offered, nil
+}
+
+// GetPaidPurchaserIDs returns the users with a paid purchase of a tour event.
+func (r *TourismRepo) GetPaidPurchaserIDs(tourEventID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.PG.Conn.Model(&entity.Purchase{}).
+		Distinct("user_id").
+		Where("tour_event_id = ? AND status = ?", tourEventID, entity.PurchaseStatusPaid).
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("get paid purchasers: %w", err)
+	}
+	return userIDs, nil
+}
+
+// GetActiveTourEventPurchases returns the purchases of a tour event that still
+// hold seats or wait for a refund.
+func (r *TourismRepo) GetActiveTourEventPurchases(tourEventID uuid.UUID) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	err := r.PG.Conn.Preload("TourEvent.Tour").
+		Where("tour_event_id = ? AND status IN ?", tourEventID, _activePurchaseStatuses).
+		Find(&purchases).Error
+	if err != nil {
+		return nil, fmt.Errorf("get active tour event purchases: %w", err)
+	}
+	return purchases, nil
+}
+
+// CancelTourEvent closes a tour event for good and ends its waitlist: waiting
+// entries and open offers expire, and the offered places go back to the tour
+// event. It returns the entries it ended. Purchases and waitlist claims lock
+// the tour event too, so none of them slips past the cancellation.
+func (r *TourismRepo) CancelTourEvent(tourEventID uuid.UUID, cancelledAt time.Time) ([]*entity.WaitlistEntry, error) {
+	var ended []*entity.WaitlistEntry
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var tourEvent entity.TourEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&tourEvent, "id = ?", tourEventID).Error; err != nil {
+			return fmt.Errorf("tour event not found: %w", err)
+		}
+
+		if err := tx.Where("tour_event_id = ? AND status IN ?", tourEventID,
+			[]string{entity.WaitlistStatusWaiting, entity.WaitlistStatusOffered}).
+			Find(&ended).Error; err != nil {
+			return fmt.Errorf("get waitlist entries: %w", err)
+		}
+		var offered int
+		ids := make([]uuid.UUID, 0, len(ended))
+		for _, entry := range ended {
+			if entry.Status == entity.WaitlistStatusOffered {
+				offered += entry.Quantity
+			}
+			entry.Status = entity.WaitlistStatusExpired
+			ids = append(ids, entry.ID)
+		}
+		if len(ids) > 0 {
+			if err := tx.Model(&entity.WaitlistEntry{}).
+				Where("id IN ?", ids).
+				Update("status", entity.WaitlistStatusExpired).Error; err != nil {
+				return fmt.Errorf("expire waitlist entries: %w", err)
+			}
+		}
+
+		return tx.Model(&tourEvent).Updates(map[string]interface{}{
+			"is_opened":        false,
+			"cancelled_at":     cancelledAt,
+			"amount_of_places": gorm.Expr("amount_of_places + ?", offered),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cancel tour event: %w", err)
+	}
+	return ended, nil
+}
+
+// lockOpenTourEvent locks a tour event that is open for sale, so that it
+// cannot be cancelled before the purchase that is being stored commits.
+// Events of archived tours are not for sale.
+func lockOpenTourEvent(tx *gorm.DB, tourEventID uuid.UUID) error {
+	var tourEvent entity.TourEvent
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND is_opened = ? AND cancelled_at IS NULL", tourEventID, true).
+		Where("tour_id IN (SELECT id FROM tourism.tours WHERE deleted_at IS NULL AND archived_at IS NULL)").
+		First(&tourEvent).Error
+	if err != nil {
+		return fmt.Errorf("tour event not found or closed: %w", err)
+	}
+	return nil
+}
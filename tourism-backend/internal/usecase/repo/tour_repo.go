@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// _activePurchaseStatuses are the statuses of purchases that still hold seats
+// or wait for a refund.
+var _activePurchaseStatuses = []string{
+	entity.PurchaseStatusProcessing,
+	entity.PurchaseStatusPaid,
+	entity.PurchaseStatusCancelRequested,
+}
+
+// UpdateTour sets the given columns of a tour and returns the updated tour,
+// or nil if there is no such tour.
+func (r *TourismRepo) UpdateTour(tourID uuid.UUID, fields map[string]interface{}) (*entity.Tour, error) {
+	if len(fields) > 0 {
+		err := r.PG.Conn.Model(&entity.Tour{}).Where("id = ?", tourID).Updates(fields).Error
+		if err != nil {
+			return nil, fmt.Errorf("update tour: %w", err)
+		}
+	}
+
+	var tour entity.Tour
+	err := r.PG.Conn.First(&tour, "id = ?", tourID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update tour: %w", err)
+	}
+	return &tour, nil
+}
+
+// SetTourArchived archives a tour at archivedAt, or restores it when archivedAt
+// is nil. It reports whether the tour exists.
+func (r *TourismRepo) SetTourArchived(tourID uuid.UUID, archivedAt *time.Time) (bool, error) {
+	result := r.PG.Conn.Model(&entity.Tour{}).Where("id = ?", tourID).Update("archived_at", archivedAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("archive tour: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetActiveTourPurchases returns the purchases of the events of a tour after
+// now that still hold seats or wait for a refund.
+func (r *TourismRepo) GetActiveTourPurchases(tourID uuid.UUID, now time.Time) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	err := r.PG.Conn.Preload("TourEvent.Tour").
+		Joins("JOIN tourism.tour_events ON tourism.tour_events.id = tourism.purchases.tour_event_id").
+		Where("tourism.tour_events.tour_id = ? AND tourism.tour_events.date > ?", tourID, now).
+		Where("tourism.purchases.status IN ?", _activePurchaseStatuses).
+		Find(&purchases).Error
+	if err != nil {
+		return nil, fmt.Errorf("get active tour purchases: %w", err)
+	}
+	return purchases, nil
+}
+
+// DeleteTour deletes a tour with its schedules and events. It deletes nothing
+// and returns false if an event after now still has an active purchase.
+func (r *TourismRepo) DeleteTour(tourID uuid.UUID, now time.Time) (bool, error) {
+	deleted := false
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		err := tx.Model(&entity.Purchase{}).
+			Joins("JOIN tourism.tour_events ON tourism.tour_events.id = tourism.purchases.tour_event_id").
+			Where("tourism.tour_events.tour_id = ? AND tourism.tour_events.date > ?", tourID, now).
+			Where("tourism.purchases.status IN ?", _activePurchaseStatuses).
+			Count(&active).Error
+		if err != nil {
+			return fmt.Errorf("count active purchases: %w", err)
+		}
+		if active > 0 {
+			return nil
+		}
+
+		if err := tx.Where("tour_id = ?", tourID).Delete(&entity.TourSchedule{}).Error; err != nil {
+			return fmt.Errorf("delete tour schedules: %w", err)
+		}
+		if err := tx.Where("tour_id = ?", tourID).Delete(&entity.TourEvent{}).Error; err != nil {
+			return fmt.Errorf("delete tour events: %w", err)
+		}
+		if err := tx.Where("id = ?", tourID).Delete(&entity.Tour{}).Error; err != nil {
+			return fmt.Errorf("delete tour: %w", err)
+		}
+		deleted = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
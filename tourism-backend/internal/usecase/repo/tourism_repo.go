@@ -5,13 +5,14 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"io"
 	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/imaging"
+	"tourism-backend/pkg/media"
 	"tourism-backend/pkg/postgres"
 )
 
@@ -19,18 +20,19 @@ const _defaultEntityCap = 64
 
 // TourismRepo -.
 type TourismRepo struct {
-	PG *postgres.Postgres
+	PG    *postgres.Postgres
+	Media media.Store
 }
 
 // New -.
-func NewTourismRepo(pg *postgres.Postgres) *TourismRepo {
-	return &TourismRepo{pg}
+func NewTourismRepo(pg *postgres.Postgres, store media.Store) *TourismRepo {
+	return &TourismRepo{pg, store}
 }
 
 func (r *TourismRepo) GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEvent, error) {
 	var res []*entity.TourEvent
 
-	err := r.PG.Conn.Model(&entity.TourEvent{}).Where("tour_id = ?", tourID).Find(&res).Error
+	err := r.PG.Conn.Model(&entity.TourEvent{}).Preload("TicketTypes").Where("tour_id = ?", tourID).Find(&res).Error
 	if err != nil {
 		log.Println(err)
 		return nil, fmt.Errorf("get tour events by tour id")
@@ -38,26 +40,10 @@ func (r *TourismRepo) GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEve
 	return res, nil
 }
 
-func (r *TourismRepo) CheckPurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
-	var purchase entity.Purchase
-
-	err := r.PG.Conn.Preload("TourEvent.Tour").
-		First(&purchase, "id = ?", purchaseID).Error
-	if err != nil {
-		return nil, err
-	}
-
-	if purchase.TourEvent.Tour.OwnerID == userID {
-		return &purchase, nil
-	}
-
-	return nil, nil
-}
-
 func (r *TourismRepo) GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
 	var purchase entity.Purchase
-	err := r.PG.Conn.Model(&purchase).
-		Where("id = ? AND user_id = ? AND status = ?", purchaseID, userID, "Paid").
+	err := r.PG.Conn.Model(&purchase).Preload("TourEvent").
+		Where("id = ? AND user_id = ? AND status = ?", purchaseID, userID, entity.PurchaseStatusPaid).
 		First(&purchase).Error
 	if err != nil {
 		log.Println("GetPurchaseQR err:", err)
@@ -66,13 +52,20 @@ func (r *TourismRepo) GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.Purch
 	return &purchase, nil
 }
 
-func (r *TourismRepo) SaveMyAvatar(userID uuid.UUID, avatar string) error {
-	err := r.PG.Conn.Model(&entity.User{}).Where("id = ?", userID).Update("avatar_url", avatar).Error
+// SaveMyAvatar stores the avatar as private media and returns a signed URL of
+// it. The user keeps the media key, URLs are signed whenever it is read.
+func (r *TourismRepo) SaveMyAvatar(userID uuid.UUID, avatar *multipart.FileHeader) (string, error) {
+	saved, err := r.saveImage(avatar, media.PrivatePrefix+"avatars", _avatarRenditions, imaging.MaxPixels)
+	if err != nil {
+		return "", fmt.Errorf("save user avatar: %w", err)
+	}
+	key := saved.Keys["full"]
+	err = r.PG.Conn.Model(&entity.User{}).Where("id = ?", userID).Update("avatar_url", key).Error
 	if err != nil {
 		log.Println(err)
-		return fmt.Errorf("save user avatar failed")
+		return "", fmt.Errorf("save user avatar failed")
 	}
-	return nil
+	return r.mediaURL(key), nil
 }
 
 func (r *TourismRepo) GetMyAvatar(userID uuid.UUID) (string, error) {
@@ -82,7 +75,7 @@ func (r *TourismRepo) GetMyAvatar(userID uuid.UUID) (string, error) {
 		log.Println("GetMyAvatar err: ", err)
 		return "", fmt.Errorf("error getting avatar: %w", err)
 	}
-	return result, nil
+	return r.mediaURL(result), nil
 }
 
 func (r *TourismRepo) CreateUserAction(userID, tourEventID uuid.UUID) {
@@ -114,41 +107,59 @@ func (r *TourismRepo) LikeTour(userID uuid.UUID, tourID uuid.UUID) (*entity.User
 	return &tourFavorite, nil
 }
 
-func (r *TourismRepo) GetMe(id uuid.UUID) (*entity.User, error) {
+// GetMe returns the user with their latest purchases, older ones are paged
+// through GetPurchasesByUserID.
+func (r *TourismRepo) GetMe(id uuid.UUID, purchases int) (*entity.User, error) {
 	var user entity.User
 	err := r.PG.Conn.
 		Preload("CreatedTours").
 		Preload("PurchasedTourEvents", func(db *gorm.DB) *gorm.DB {
-			return db.Order("tourism.purchases.created_at desc")
+			return db.Order("tourism.purchases.created_at desc").Limit(purchases)
 		}).
 		Preload("FavoriteTours").
 		Preload("PurchasedTourEvents.TourEvent").
 		Preload("PurchasedTourEvents.TourEvent.Tour").
-		Preload("PurchasedTourEvents.TourEvent.Tour.TourImages").
+		Preload("PurchasedTourEvents.TourEvent.Tour.TourImages", orderedImages).
 		First(&user, id).
 		Error
 	if err != nil {
 		log.Println("TourismRepo.GetMe: ", err)
 		return nil, fmt.Errorf("user not found")
 	}
+	user.AvatarURL = r.mediaURL(user.AvatarURL)
 	return &user, nil
 }
 
-func (r *TourismRepo) AddFileToTourByTourID(panoramaEntity []*entity.Panorama) ([]*entity.Panorama, error) {
-	err := r.PG.Conn.Create(&panoramaEntity).Error
-	if err != nil {
-		return nil, err
+func (r *TourismRepo) AddFileToTourByTourID(tourID uuid.UUID, files []*multipart.FileHeader) ([]*entity.Panorama, error) {
+	panoramaEntity := make([]*entity.Panorama, 0, len(files))
+	for _, file := range files {
+		saved, err := r.saveImage(file, "panoramas", _panoramaRenditions, imaging.MaxPanoramaPixels)
+		if err != nil {
+			return nil, fmt.Errorf("save panorama: %w", err)
+		}
+		panoramaEntity = append(panoramaEntity, &entity.Panorama{
+			PanoramaURL:  saved.URLs["full"],
+			ThumbnailURL: saved.URLs["thumb"],
+			Width:        saved.Width,
+			Height:       saved.Height,
+			TourID:       tourID,
+		})
 	}
-	return panoramaEntity, nil
-}
 
-func (r *TourismRepo) ChangeTour(tour *entity.Tour) (*entity.Tour, error) {
-	err := r.PG.Conn.Model(&tour).Updates(tour).Error
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		position, err := nextMediaPosition(tx, &entity.Panorama{}, tourID)
+		if err != nil {
+			return err
+		}
+		for i, panorama := range panoramaEntity {
+			panorama.Position = position + i
+		}
+		return tx.Create(&panoramaEntity).Error
+	})
 	if err != nil {
-		log.Println("TourismRepo ChangeTour err: ", err)
-		return nil, fmt.Errorf("TourismRepo ChangeTour Error")
+		return nil, err
 	}
-	return tour, nil
+	return panoramaEntity, nil
 }
 
 func (r *TourismRepo) GetWeatherInfoByTourEventID(tourEventID uuid.UUID) (*entity.WeatherInfoRQ, error) {
@@ -183,7 +194,7 @@ func (r *TourismRepo) GetWeatherInfoByTourEventID(tourEventID uuid.UUID) (*entit
 
 func (r *TourismRepo) GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error) {
 	var tourEvent entity.TourEvent
-	err := r.PG.Conn.Preload("Tour").First(&tourEvent, id).Error
+	err := r.PG.Conn.Preload("Tour").Preload("TicketTypes").First(&tourEvent, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -191,51 +202,74 @@ func (r *TourismRepo) GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error)
 	return &tourEvent, nil
 }
 
-func (r *TourismRepo) GetFilteredTourEvents(filter *entity.TourEventFilter) ([]*entity.TourEvent, error) {
-	var tourEvents []*entity.TourEvent
-
-	query := r.PG.Conn.
-		Joins("JOIN tourism.tours ON tourism.tours.id = tourism.tour_events.tour_id").
-		Joins("LEFT JOIN tourism.tour_categories ON tourism.tour_categories.tour_id = tourism.tours.id").
-		Where("tourism.tour_events.is_opened = ?", true)
+// tourEventFilterSQL returns the condition an open row of tourism.tour_events
+// has to meet to match the filter, and its arguments.
+func tourEventFilterSQL(filter *entity.TourEventFilter) (string, []interface{}) {
+	conditions := []string{"tourism.tour_events.is_opened"}
+	var args []interface{}
 
 	// Filter by categories
 	if len(filter.CategoryIDs) > 0 {
-		query = query.Where("tourism.tour_categories.category_id IN ?", filter.CategoryIDs)
-	}
-
-	if !filter.StartDate.IsZero() {
-		query = query.Where("tourism.tour_events.date >= ?", filter.StartDate)
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM tourism.tour_categories
+			WHERE tourism.tour_categories.tour_id = tourism.tour_events.tour_id AND tourism.tour_categories.category_id IN ?)`)
+		args = append(args, filter.CategoryIDs)
 	}
 
 	// Filter by start date
-	if !filter.EndDate.IsZero() {
-		query = query.Where("DATE(tourism.tour_events.date) <= ?", filter.EndDate.Format("2006-01-02"))
+	if !filter.StartDate.IsZero() {
+		conditions = append(conditions, "tourism.tour_events.date >= ?")
+		args = append(args, filter.StartDate)
 	}
 
 	// Filter by end date
 	if !filter.EndDate.IsZero() {
-		query = query.Where("DATE(tourism.tour_events.date) <= ?", filter.EndDate.Format("2006-01-02"))
+		conditions = append(conditions, "DATE(tourism.tour_events.date) <= ?")
+		args = append(args, filter.EndDate.Format("2006-01-02"))
 	}
 
 	// Filter by budget
 	if filter.MinPrice > 0 {
-		query = query.Where("tourism.tour_events.price >= ?", filter.MinPrice)
+		conditions = append(conditions, "tourism.tour_events.price >= ?")
+		args = append(args, filter.MinPrice)
 	}
 	if filter.MaxPrice > 0 {
-		query = query.Where("tourism.tour_events.price <= ?", filter.MaxPrice)
+		conditions = append(conditions, "tourism.tour_events.price <= ?")
+		args = append(args, filter.MaxPrice)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetFilteredTourEvents returns a page of the open tour events matching the filter.
+func (r *TourismRepo) GetFilteredTourEvents(filter *entity.TourEventFilter, page *entity.PageQuery) ([]*entity.TourEvent, *entity.Cursor, error) {
+	conditions, args := tourEventFilterSQL(filter)
+	query := r.PG.Conn.Model(&entity.TourEvent{}).
+		Joins("JOIN tourism.tours ON tourism.tours.id = tourism.tour_events.tour_id").
+		Where("tourism.tours.deleted_at IS NULL AND tourism.tours.archived_at IS NULL").
+		Where(conditions, args...)
+
+	ids, next, err := pageIDs(query, "tourism.tour_events.id", _tourEventOrders[page.Sort], page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get filtered tour events: %w", err)
 	}
 
-	// Execute the query
-	err := query.Preload("Tour").Preload("Tour.TourImages").Order("date desc").Find(&tourEvents).Error
-	return tourEvents, err
+	var tourEvents []*entity.TourEvent
+	err = r.PG.Conn.Preload("Tour").Preload("Tour.TourImages", orderedImages).Preload("TicketTypes").
+		Where("id IN ?", ids).Find(&tourEvents).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("get filtered tour events: %w", err)
+	}
+	positions := pagePositions(ids)
+	sort.Slice(tourEvents, func(i, j int) bool {
+		return positions[tourEvents[i].ID] < positions[tourEvents[j].ID]
+	})
+	return tourEvents, next, nil
 }
 
-func (r *TourismRepo) GetTourLocationByID(tourLocationID uuid.UUID) (*entity.TourLocation, error) {
+func (r *TourismRepo) GetTourLocationByTourID(tourID uuid.UUID) (*entity.TourLocation, error) {
 	var tourLocation entity.TourLocation
-	err := r.PG.Conn.Where("tour_id = ?", tourLocationID).First(&tourLocation).Error
+	err := r.PG.Conn.Where("tour_id = ?", tourID).First(&tourLocation).Error
 	if err != nil {
-		return nil, fmt.Errorf("get tour location by id: %w", err)
+		return nil, fmt.Errorf("get tour location by tour id: %w", err)
 	}
 	return &tourLocation, nil
 }
@@ -287,78 +321,245 @@ func (r *TourismRepo) CreateTourCategory(tourCategory *entity.CreateTourCategory
 	return category, nil
 }
 
-func (r *TourismRepo) CreatePurchase(purchase *entity.Purchase) (*entity.Purchase, error) {
+// CreatePurchase takes the seats from the tour event and stores the purchase at
+// the amount it was priced at. A non-nil promoCode discounts the purchase when its usage limits allow it.
+func (r *TourismRepo) CreatePurchase(purchase *entity.Purchase, promoCode *entity.PromoCode) (*entity.Purchase, error) {
 	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
-		// Check tour event record in the database
-		var tourEvent entity.TourEvent
-
-		// Fetch the tour event to verify conditions before updating
-		if err := tx.Where("id = ? AND is_opened = ? AND amount_of_places > 0",
-			purchase.TourEventID, true).First(&tourEvent).Error; err != nil {
-			return fmt.Errorf("tour event not found or closed: %w", err)
+		// Lock the tour event to verify conditions before updating
+		if err := lockOpenTourEvent(tx, purchase.TourEventID); err != nil {
+			return err
 		}
 
 		// Decrease the available places count
-		if err := tx.Model(&entity.TourEvent{}).
-			Where("id = ?", purchase.TourEventID).
-			UpdateColumn("amount_of_places", gorm.Expr("amount_of_places - 1")).Error; err != nil {
-			return fmt.Errorf("failed to update amount_of_places: %w", err)
+		result := tx.Model(&entity.TourEvent{}).
+			Where("id = ? AND amount_of_places >= ?", purchase.TourEventID, purchase.Quantity).
+			UpdateColumn("amount_of_places", gorm.Expr("amount_of_places - ?", purchase.Quantity))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update amount_of_places: %w", result.Error)
 		}
-
-		// Create the purchase record in the database
-		if err := tx.Create(purchase).Error; err != nil {
-			return fmt.Errorf("create purchase failed: %w", err)
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("not enough places left for %d travelers", purchase.Quantity)
 		}
 
-		return nil
+		return createPurchaseRecord(tx, purchase, promoCode)
 	})
 
 	if err != nil {
 		return nil, fmt.Errorf("create purchase transaction failed: %w", err)
 	}
 
-	// Reload purchase with related data
-	err = r.PG.Conn.Preload("User").Preload("TourEvent.Tour").
+	return r.reloadPurchase(purchase)
+}
+
+// createPurchaseRecord stores a priced purchase whose seats were already taken from
+// the tour event and queues its payment in the same transaction so it survives a restart.
+func createPurchaseRecord(tx *gorm.DB, purchase *entity.Purchase, promoCode *entity.PromoCode) error {
+	if err := holdTicketTypes(tx, purchase); err != nil {
+		return err
+	}
+	if promoCode != nil {
+		if err := applyPromoCode(tx, purchase, promoCode); err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(purchase).Error; err != nil {
+		return fmt.Errorf("create purchase failed: %w", err)
+	}
+
+	job := &entity.PaymentJob{
+		PurchaseID: purchase.ID,
+		Status:     entity.PaymentJobStatusPending,
+		NextRunAt:  time.Now(),
+	}
+	if err := tx.Create(job).Error; err != nil {
+		return fmt.Errorf("queue purchase payment failed: %w", err)
+	}
+	return nil
+}
+
+// holdTicketTypes counts the places of the purchase against the limits of its ticket types.
+func holdTicketTypes(tx *gorm.DB, purchase *entity.Purchase) error {
+	for _, item := range purchase.Items {
+		result := tx.Model(&entity.TicketType{}).
+			Where("id = ? AND tour_event_id = ?", item.TicketTypeID, purchase.TourEventID).
+			Where("max_places = 0 OR held_places + ? <= max_places", item.Quantity).
+			UpdateColumn("held_places", gorm.Expr("held_places + ?", item.Quantity))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update held_places: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("not enough places of ticket type %s left for %d travelers", item.TicketTypeID, item.Quantity)
+		}
+	}
+	return nil
+}
+
+// releaseTicketTypes gives the places of the purchase back to its ticket types.
+func releaseTicketTypes(tx *gorm.DB, purchaseID uuid.UUID) error {
+	var items []entity.PurchaseItem
+	if err := tx.Where("purchase_id = ?", purchaseID).Find(&items).Error; err != nil {
+		return fmt.Errorf("get purchase items: %w", err)
+	}
+	for _, item := range items {
+		if err := tx.Model(&entity.TicketType{}).
+			Where("id = ?", item.TicketTypeID).
+			UpdateColumn("held_places", gorm.Expr("held_places - ?", item.Quantity)).Error; err != nil {
+			return fmt.Errorf("failed to update held_places: %w", err)
+		}
+	}
+	return nil
+}
+
+// reloadPurchase loads the purchase again with its related data.
+func (r *TourismRepo) reloadPurchase(purchase *entity.Purchase) (*entity.Purchase, error) {
+	err := r.PG.Conn.Preload("User").Preload("TourEvent.Tour").Preload("PromoCode").Preload("Items.TicketType").
 		First(purchase, "id = ?", purchase.ID).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to preload purchase data: %w", err)
 	}
-
 	return purchase, nil
 }
 
 func (r *TourismRepo) PayTourEvent(purchase *entity.Purchase) *entity.Purchase {
 
-	result := r.PG.Conn.Model(&purchase).
-		Where("id = ? AND status = ?", purchase.ID, "Processing").
-		Update("status", "Paid")
+	result := r.PG.Conn.Model(&entity.Purchase{}).
+		Where("id = ? AND status = ?", purchase.ID, entity.PurchaseStatusProcessing).
+		Update("status", entity.PurchaseStatusPaid)
 
 	if result.Error != nil {
-		log.Printf("Error Processing tour event PayTourEvent: %s, \n ERROR:  %w \n", result, result.Error)
+		log.Printf("Error Processing tour event PayTourEvent: %v \n", result.Error)
 
-		err := r.PG.Conn.Model(&purchase).
-			Where("id = ? AND status = ?", purchase.ID, "Paid").
-			Update("status", "Failed").Error
+		err := r.ReleasePurchase(purchase.ID, entity.PurchaseStatusProcessing, entity.PurchaseStatusFailed)
 		if err != nil {
 			log.Println("Error Failing Purchase: ", err)
 		}
-		err = r.PG.Conn.Model(&purchase).
-			Where("id = ?", purchase.TourEventID).
-			UpdateColumn("amount_of_places", gorm.Expr("amount_of_places + 1")).Error
-		if err != nil {
-			log.Println("Error Increasing Amount of Places: ", err)
-		}
+		return nil
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("PayTourEvent: purchase %s is no longer processing\n", purchase.ID)
 		return nil
 	}
 
+	purchase.Status = entity.PurchaseStatusPaid
 	return purchase
 }
 
+// GetPurchasesByUserID returns a page of the purchases of the user.
+func (r *TourismRepo) GetPurchasesByUserID(userID uuid.UUID, page *entity.PageQuery) ([]*entity.Purchase, *entity.Cursor, error) {
+	query := r.PG.Conn.Model(&entity.Purchase{}).Where("tourism.purchases.user_id = ?", userID)
+	ids, next, err := pageIDs(query, "tourism.purchases.id", _purchaseOrders[page.Sort], page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get purchases by user id: %w", err)
+	}
+
+	var purchases []*entity.Purchase
+	err = r.PG.Conn.Preload("TourEvent.Tour.TourImages", orderedImages).Preload("Items.TicketType").
+		Where("id IN ?", ids).Find(&purchases).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("get purchases by user id: %w", err)
+	}
+	positions := pagePositions(ids)
+	sort.Slice(purchases, func(i, j int) bool {
+		return positions[purchases[i].ID] < positions[purchases[j].ID]
+	})
+	return purchases, next, nil
+}
+
+func (r *TourismRepo) GetPurchaseByID(purchaseID uuid.UUID) (*entity.Purchase, error) {
+	var purchase entity.Purchase
+	err := r.PG.Conn.Preload("TourEvent.Tour").First(&purchase, "id = ?", purchaseID).Error
+	if err != nil {
+		return nil, fmt.Errorf("get purchase by id: %w", err)
+	}
+	return &purchase, nil
+}
+
+// GetPurchaseByPaymentIntentID returns nil when no purchase was charged by the PaymentIntent.
+func (r *TourismRepo) GetPurchaseByPaymentIntentID(paymentIntentID string) (*entity.Purchase, error) {
+	var purchase entity.Purchase
+	err := r.PG.Conn.Where("payment_intent_id = ?", paymentIntentID).First(&purchase).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get purchase by payment intent id: %w", err)
+	}
+	return &purchase, nil
+}
+
+func (r *TourismRepo) SetPurchasePaymentIntent(purchaseID uuid.UUID, paymentIntentID string) error {
+	err := r.PG.Conn.Model(&entity.Purchase{}).
+		Where("id = ?", purchaseID).
+		Update("payment_intent_id", paymentIntentID).Error
+	if err != nil {
+		return fmt.Errorf("set purchase payment intent: %w", err)
+	}
+	return nil
+}
+
+// GetExpiredSeatHolds returns processing purchases whose seat hold ran out.
+// Purchases created before holds existed expire holdTTL after creation.
+func (r *TourismRepo) GetExpiredSeatHolds(now time.Time, holdTTL time.Duration) ([]*entity.Purchase, error) {
+	var purchases []*entity.Purchase
+	err := r.PG.Conn.
+		Where("status = ?", entity.PurchaseStatusProcessing).
+		Where("hold_expires_at < ? OR (hold_expires_at IS NULL AND created_at < ?)", now, now.Add(-holdTTL)).
+		Find(&purchases).Error
+	if err != nil {
+		return nil, fmt.Errorf("get expired seat holds: %w", err)
+	}
+	return purchases, nil
+}
+
+// UpdatePurchaseStatus moves a purchase from one status to another. It fails
+// when the purchase is no longer in the expected status.
+func (r *TourismRepo) UpdatePurchaseStatus(purchaseID uuid.UUID, from, to string) error {
+	result := r.PG.Conn.Model(&entity.Purchase{}).
+		Where("id = ? AND status = ?", purchaseID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("update purchase status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("purchase %s is not in status %s", purchaseID, from)
+	}
+	return nil
+}
+
+// ReleasePurchase moves a purchase to a status that no longer holds seats
+// and returns its seats to the tour event in the same transaction.
+func (r *TourismRepo) ReleasePurchase(purchaseID uuid.UUID, from, to string) error {
+	return r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var purchase entity.Purchase
+		if err := tx.First(&purchase, "id = ?", purchaseID).Error; err != nil {
+			return fmt.Errorf("purchase not found: %w", err)
+		}
+
+		result := tx.Model(&entity.Purchase{}).
+			Where("id = ? AND status = ?", purchaseID, from).
+			Update("status", to)
+		if result.Error != nil {
+			return fmt.Errorf("update purchase status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("purchase %s is not in status %s", purchaseID, from)
+		}
+
+		if err := tx.Model(&entity.TourEvent{}).
+			Where("id = ?", purchase.TourEventID).
+			UpdateColumn("amount_of_places", gorm.Expr("amount_of_places + ?", purchase.Quantity)).Error; err != nil {
+			return fmt.Errorf("failed to update amount_of_places: %w", err)
+		}
+
+		return releaseTicketTypes(tx, purchaseID)
+	})
+}
+
 func (r *TourismRepo) CheckTourOwner(tourID uuid.UUID, userID uuid.UUID) bool {
 	var tourOwnerID string
 	err := r.PG.Conn.Table("tourism.tours").
 		Select("owner_id").
-		Where("id = ?", tourID).
+		Where("id = ? AND deleted_at IS NULL", tourID).
 		Scan(&tourOwnerID).Error
 
 	if err != nil {
@@ -394,7 +595,7 @@ func (r *TourismRepo) CreateTourEvent(tourEvent *entity.TourEvent) (*entity.Tour
 
 		return nil
 	})
-	err = r.PG.Conn.Preload("Tour").First(tourEvent, "id = ?", tourEvent.ID).Error
+	err = r.PG.Conn.Preload("Tour").Preload("TicketTypes").First(tourEvent, "id = ?", tourEvent.ID).Error
 
 	if err != nil {
 		return nil, err
@@ -406,7 +607,7 @@ func (r *TourismRepo) CreateTourEvent(tourEvent *entity.TourEvent) (*entity.Tour
 func (r *TourismRepo) GetTourByID(tourID string) (*entity.Tour, error) {
 	var tour entity.Tour
 
-	err := r.PG.Conn.Preload("TourImages").Preload("TourVideos").Preload("TourPanoramas").First(&tour, "id = ?", tourID).Error
+	err := r.PG.Conn.Preload("TourImages", orderedImages).Preload("TourVideos", orderedMedia).Preload("TourPanoramas", orderedMedia).First(&tour, "id = ?", tourID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -414,49 +615,72 @@ func (r *TourismRepo) GetTourByID(tourID string) (*entity.Tour, error) {
 	return &tour, nil
 }
 
-func (r *TourismRepo) GetTours() ([]entity.Tour, error) {
-	var tours []entity.Tour
-	err := r.PG.Conn.Preload("TourImages").Preload("TourVideos").Preload("TourPanoramas").Preload("TourEvents").Find(&tours).Error
+// GetTours returns a page of tours. Only what a listing shows is preloaded: the
+// images and the upcoming open events of every tour.
+func (r *TourismRepo) GetTours(page *entity.PageQuery) ([]*entity.Tour, *entity.Cursor, error) {
+	ids, next, err := pageIDs(r.PG.Conn.Model(&entity.Tour{}).Where("archived_at IS NULL"), "tourism.tours.id", _tourOrders[page.Sort], page)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("get tours: %w", err)
 	}
-	return tours, nil
+
+	var tours []*entity.Tour
+	err = r.PG.Conn.Preload("TourImages", orderedImages).
+		Preload("TourEvents", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_opened = ? AND date > ?", true, time.Now()).Order("date")
+		}).
+		Where("id IN ?", ids).Find(&tours).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("get tours: %w", err)
+	}
+	positions := pagePositions(ids)
+	sort.Slice(tours, func(i, j int) bool {
+		return positions[tours[i].ID] < positions[tours[j].ID]
+	})
+	return tours, next, nil
 }
 
+// CreateTour saves the media of a tour before creating it, so that an invalid
+// file is rejected before anything is written to the database.
 func (r *TourismRepo) CreateTour(tour *entity.Tour, imageFiles []*multipart.FileHeader, videoFiles []*multipart.FileHeader) (*entity.Tour, error) {
+	images := make([]*entity.Image, 0, len(imageFiles))
+	for _, file := range imageFiles {
+		saved, err := r.saveImage(file, "images", _imageRenditions, imaging.MaxPixels)
+		if err != nil {
+			return nil, fmt.Errorf("save tour image: %w", err)
+		}
+		images = append(images, &entity.Image{
+			ImageURL:     saved.URLs["full"],
+			ThumbnailURL: saved.URLs["thumb"],
+			CardURL:      saved.URLs["card"],
+			Width:        saved.Width,
+			Height:       saved.Height,
+		})
+	}
+
+	videos := make([]*entity.Video, 0, len(videoFiles))
+	for _, file := range videoFiles {
+		url, err := r.saveVideo(file, "videos")
+		if err != nil {
+			return nil, fmt.Errorf("save tour video: %w", err)
+		}
+		videos = append(videos, &entity.Video{VideoURL: url})
+	}
+
 	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
 		// Create the tour record in the database
 		if err := tx.Create(&tour).Error; err != nil {
 			return err
 		}
 
-		// Save images inside the transaction
-		for _, file := range imageFiles {
-			filename := uuid.New().String() + filepath.Ext(file.Filename)
-			filespath := "./uploads/images/" + filename
-			filespathToDB := "./v1/tours/uploads/images/" + filename
-			// Save the image file
-			if err := r.saveFile(file, filespath); err != nil {
-				return err
-			}
-			image := &entity.Image{ImageURL: filespathToDB, TourID: tour.ID}
-			if err := tx.Create(&image).Error; err != nil {
+		for i, image := range images {
+			image.TourID, image.Position = tour.ID, i
+			if err := tx.Create(image).Error; err != nil {
 				return err
 			}
 		}
-
-		// Save videos inside the transaction
-		for _, file := range videoFiles {
-			filename := uuid.New().String() + filepath.Ext(file.Filename)
-			filespath := "./uploads/videos/" + filename
-			filespathToDB := "./v1/tours/uploads/videos/" + filename
-			// Save the video file
-			if err := r.saveFile(file, filespath); err != nil {
-				return err
-			}
-			// Append the video record to the list
-			video := &entity.Video{VideoURL: filespathToDB, TourID: tour.ID}
-			if err := tx.Create(&video).Error; err != nil {
+		for i, video := range videos {
+			video.TourID, video.Position = tour.ID, i
+			if err := tx.Create(video).Error; err != nil {
 				return err
 			}
 		}
@@ -470,28 +694,18 @@ func (r *TourismRepo) CreateTour(tour *entity.Tour, imageFiles []*multipart.File
 	return tour, nil
 }
 
-// Helper function to save the file to the disk
-func (r *TourismRepo) saveFile(file *multipart.FileHeader, path string) error {
-	// Open the file
-	src, err := file.Open()
-	if err != nil {
-		return err
+// mediaURL turns a stored media reference into a URL. Private media is stored
+// as its key and gets a signed URL, anything else is already a URL.
+func (r *TourismRepo) mediaURL(stored string) string {
+	if !media.IsPrivate(stored) {
+		return stored
 	}
-	defer src.Close()
-
-	// Create the destination file
-	dst, err := os.Create(path)
+	url, err := r.Media.SignedURL(stored)
 	if err != nil {
-		return err
+		log.Println("sign media url: ", err)
+		return ""
 	}
-	defer dst.Close()
-
-	// Copy the contents of the source file to the destination file
-	if _, err := io.Copy(dst, src); err != nil {
-		return err
-	}
-
-	return nil
+	return url
 }
 
 //
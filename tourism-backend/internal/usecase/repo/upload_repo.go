@@ -0,0 +1,292 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/imaging"
+	"tourism-backend/pkg/media"
+)
+
+func (r *TourismRepo) CreateUploadSession(session *entity.UploadSession) error {
+	if err := r.PG.Conn.Create(session).Error; err != nil {
+		return fmt.Errorf("create upload session: %w", err)
+	}
+	return nil
+}
+
+// GetUploadSession returns the upload session with the given ID, or nil when
+// there is none.
+func (r *TourismRepo) GetUploadSession(id uuid.UUID) (*entity.UploadSession, error) {
+	var session entity.UploadSession
+	err := r.PG.Conn.First(&session, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get upload session: %w", err)
+	}
+	return &session, nil
+}
+
+// AppendUploadChunk stores a chunk received at offset and moves the offset of
+// the session past it. It returns false and stores nothing when the session
+// is no longer at offset, because another chunk was appended first.
+func (r *TourismRepo) AppendUploadChunk(session *entity.UploadSession, offset int64, data []byte) (bool, error) {
+	key := fmt.Sprintf("tmp/uploads/%s/%s", session.ID, uuid.New())
+	if err := r.Media.Put(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return false, fmt.Errorf("store upload chunk: %w", err)
+	}
+
+	appended := false
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.UploadSession{}).
+			Where("id = ? AND upload_offset = ? AND status = ?", session.ID, offset, entity.UploadStatusOpen).
+			Update("upload_offset", gorm.Expr("upload_offset + ?", len(data)))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		chunk := &entity.UploadChunk{UploadSessionID: session.ID, Offset: offset, Size: int64(len(data)), Key: key}
+		if err := tx.Create(chunk).Error; err != nil {
+			return err
+		}
+		appended = true
+		return nil
+	})
+	if err != nil || !appended {
+		r.deleteMedia(key)
+	}
+	if err != nil {
+		return false, fmt.Errorf("append upload chunk: %w", err)
+	}
+	if appended {
+		session.Offset = offset + int64(len(data))
+	}
+	return appended, nil
+}
+
+// OpenUpload returns the data received by an upload session so far.
+func (r *TourismRepo) OpenUpload(sessionID uuid.UUID) (io.ReadCloser, error) {
+	var keys []string
+	err := r.PG.Conn.Model(&entity.UploadChunk{}).
+		Where("upload_session_id = ?", sessionID).
+		Order("chunk_offset").
+		Pluck("key", &keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("get upload chunks: %w", err)
+	}
+	return &chunkReader{store: r.Media, keys: keys}, nil
+}
+
+var errUploadNotOpen = errors.New("upload session is no longer open")
+
+// AttachUpload puts the file of a complete upload session into the media store
+// and attaches it to the tour of the session as a video or panorama. Files with
+// invalid content return the error of the media package. It returns false and
+// drops the stored file when the session is no longer open, because a
+// concurrent request completed or failed it first.
+func (r *TourismRepo) AttachUpload(session *entity.UploadSession) (bool, error) {
+	data, err := r.OpenUpload(session.ID)
+	if err != nil {
+		return false, err
+	}
+	defer data.Close()
+
+	var video *entity.Video
+	var panorama *entity.Panorama
+	var keys []string
+	switch session.Kind {
+	case entity.UploadKindVideo:
+		key, err := r.putVideo(session.Filename, data, session.Size, "videos")
+		if err != nil {
+			return false, err
+		}
+		keys = append(keys, key)
+		video = &entity.Video{TourID: session.TourID, VideoURL: r.Media.URL(key)}
+	case entity.UploadKindPanorama:
+		content, err := io.ReadAll(data)
+		if err != nil {
+			return false, fmt.Errorf("read upload: %w", err)
+		}
+		saved, err := r.putImage(session.Filename, content, "panoramas", _panoramaRenditions, imaging.MaxPanoramaPixels)
+		if err != nil {
+			return false, err
+		}
+		for _, key := range saved.Keys {
+			keys = append(keys, key)
+		}
+		panorama = &entity.Panorama{
+			TourID:       session.TourID,
+			PanoramaURL:  saved.URLs["full"],
+			ThumbnailURL: saved.URLs["thumb"],
+			Width:        saved.Width,
+			Height:       saved.Height,
+		}
+	default:
+		return false, fmt.Errorf("unknown upload kind %q", session.Kind)
+	}
+
+	var mediaID uuid.UUID
+	var mediaURL string
+	err = r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if video != nil {
+			position, err := nextMediaPosition(tx, video, session.TourID)
+			if err != nil {
+				return err
+			}
+			video.Position = position
+			if err := tx.Create(video).Error; err != nil {
+				return err
+			}
+			mediaID, mediaURL = video.ID, video.VideoURL
+		} else {
+			position, err := nextMediaPosition(tx, panorama, session.TourID)
+			if err != nil {
+				return err
+			}
+			panorama.Position = position
+			if err := tx.Create(panorama).Error; err != nil {
+				return err
+			}
+			mediaID, mediaURL = panorama.ID, panorama.PanoramaURL
+		}
+		result := tx.Model(&entity.UploadSession{}).
+			Where("id = ? AND status = ?", session.ID, entity.UploadStatusOpen).
+			Updates(map[string]interface{}{
+				"status":    entity.UploadStatusCompleted,
+				"media_id":  mediaID,
+				"media_url": mediaURL,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUploadNotOpen
+		}
+		return nil
+	})
+	if err != nil {
+		for _, key := range keys {
+			r.deleteMedia(key)
+		}
+	}
+	if errors.Is(err, errUploadNotOpen) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("attach upload: %w", err)
+	}
+	session.Status, session.MediaID, session.MediaURL = entity.UploadStatusCompleted, &mediaID, mediaURL
+	r.deleteUploadChunks(session.ID)
+	return true, nil
+}
+
+// FailUpload closes an upload session whose file was refused and drops its
+// chunks. A session that is no longer open is left as it is.
+func (r *TourismRepo) FailUpload(session *entity.UploadSession, reason string) error {
+	result := r.PG.Conn.Model(&entity.UploadSession{}).
+		Where("id = ? AND status = ?", session.ID, entity.UploadStatusOpen).
+		Updates(map[string]interface{}{"status": entity.UploadStatusFailed, "error": reason})
+	if result.Error != nil {
+		return fmt.Errorf("fail upload: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	session.Status, session.Error = entity.UploadStatusFailed, reason
+	r.deleteUploadChunks(session.ID)
+	return nil
+}
+
+// DeleteUploadSession drops an upload session with its chunks.
+func (r *TourismRepo) DeleteUploadSession(session *entity.UploadSession) error {
+	r.deleteUploadChunks(session.ID)
+	if err := r.PG.Conn.Delete(session).Error; err != nil {
+		return fmt.Errorf("delete upload session: %w", err)
+	}
+	return nil
+}
+
+// GetExpiredUploadSessions returns open upload sessions that expired before now.
+func (r *TourismRepo) GetExpiredUploadSessions(now time.Time) ([]*entity.UploadSession, error) {
+	var sessions []*entity.UploadSession
+	err := r.PG.Conn.
+		Where("status = ? AND expires_at < ?", entity.UploadStatusOpen, now).
+		Limit(_defaultEntityCap).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("get expired upload sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *TourismRepo) deleteUploadChunks(sessionID uuid.UUID) {
+	var chunks []entity.UploadChunk
+	if err := r.PG.Conn.Where("upload_session_id = ?", sessionID).Find(&chunks).Error; err != nil {
+		log.Println("delete upload chunks: ", err)
+		return
+	}
+	for _, chunk := range chunks {
+		r.deleteMedia(chunk.Key)
+	}
+	if err := r.PG.Conn.Unscoped().Where("upload_session_id = ?", sessionID).Delete(&entity.UploadChunk{}).Error; err != nil {
+		log.Println("delete upload chunks: ", err)
+	}
+}
+
+func (r *TourismRepo) deleteMedia(key string) {
+	if err := r.Media.Delete(context.Background(), key); err != nil {
+		log.Println("delete media: ", err)
+	}
+}
+
+// chunkReader reads the chunks of an upload one after the other, opening each
+// only when the previous one is exhausted.
+type chunkReader struct {
+	store   media.Store
+	keys    []string
+	current io.ReadCloser
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.keys) == 0 {
+				return 0, io.EOF
+			}
+			current, err := c.store.Open(context.Background(), c.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("open upload chunk: %w", err)
+			}
+			c.current, c.keys = current, c.keys[1:]
+		}
+
+		n, err := c.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			c.current.Close()
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.current == nil {
+		return nil
+	}
+	return c.current.Close()
+}
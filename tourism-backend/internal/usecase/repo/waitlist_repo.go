@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func (r *TourismRepo) CreateWaitlistEntry(entry *entity.WaitlistEntry) (*entity.WaitlistEntry, error) {
+	if err := r.PG.Conn.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("create waitlist entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *TourismRepo) GetWaitlistEntryByID(entryID uuid.UUID) (*entity.WaitlistEntry, error) {
+	var entry entity.WaitlistEntry
+	if err := r.PG.Conn.First(&entry, "id = ?", entryID).Error; err != nil {
+		return nil, fmt.Errorf("get waitlist entry by id: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetActiveWaitlistEntry returns the waiting or offered entry of the user for
+// the tour event, or nil when the user is not in line.
+func (r *TourismRepo) GetActiveWaitlistEntry(userID, tourEventID uuid.UUID) (*entity.WaitlistEntry, error) {
+	var entry entity.WaitlistEntry
+	err := r.PG.Conn.
+		Where("user_id = ? AND tour_event_id = ?", userID, tourEventID).
+		Where("status IN ?", []string{entity.WaitlistStatusWaiting, entity.WaitlistStatusOffered}).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get active waitlist entry: %w", err)
+	}
+	return &entry, nil
+}
+
+func (r *TourismRepo) GetWaitlistEntriesByUserID(userID uuid.UUID) ([]*entity.WaitlistEntry, error) {
+	var entries []*entity.WaitlistEntry
+	err := r.PG.Conn.Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("get waitlist entries: %w", err)
+	}
+	return entries, nil
+}
+
+// UpdateWaitlistStatus moves an entry from one status to another. It fails
+// when the entry is no longer in the expected status.
+func (r *TourismRepo) UpdateWaitlistStatus(entryID uuid.UUID, from, to string) error {
+	result := r.PG.Conn.Model(&entity.WaitlistEntry{}).
+		Where("id = ? AND status = ?", entryID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("update waitlist status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("waitlist entry %s is not in status %s", entryID, from)
+	}
+	return nil
+}
+
+// OfferWaitlistSeats hands the free places of a tour event to the waiting
+// entries in the order they joined. The offered places are taken from the tour
+// event so nobody else can buy them while the offer lasts. Entries that ask for
+// more places than are left keep waiting. It returns the new offers.
+func (r *TourismRepo) OfferWaitlistSeats(tourEventID uuid.UUID, offerExpiresAt time.Time) ([]*entity.WaitlistEntry, error) {
+	var offered []*entity.WaitlistEntry
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var tourEvent entity.TourEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&tourEvent, "id = ?", tourEventID).Error; err != nil {
+			return fmt.Errorf("tour event not found: %w", err)
+		}
+		if !tourEvent.IsOpened || tourEvent.AmountOfPlaces <= 0 {
+			return nil
+		}
+
+		var waiting []*entity.WaitlistEntry
+		if err := tx.Where("tour_event_id = ? AND status = ?", tourEventID, entity.WaitlistStatusWaiting).
+			Order("created_at").Find(&waiting).Error; err != nil {
+			return fmt.Errorf("get waiting entries: %w", err)
+		}
+
+		var places float64
+		offered, places = entity.PickWaitlistOffers(waiting, tourEvent.AmountOfPlaces)
+		for _, entry := range offered {
+			if err := tx.Model(entry).Updates(map[string]interface{}{
+				"status":           entity.WaitlistStatusOffered,
+				"offer_expires_at": offerExpiresAt,
+			}).Error; err != nil {
+				return fmt.Errorf("offer waitlist entry: %w", err)
+			}
+			entry.Status = entity.WaitlistStatusOffered
+			entry.OfferExpiresAt = &offerExpiresAt
+		}
+
+		if places == tourEvent.AmountOfPlaces {
+			return nil
+		}
+		if err := tx.Model(&entity.TourEvent{}).
+			Where("id = ?", tourEventID).
+			UpdateColumn("amount_of_places", places).Error; err != nil {
+			return fmt.Errorf("failed to update amount_of_places: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("offer waitlist seats: %w", err)
+	}
+	return offered, nil
+}
+
+// ReleaseWaitlistOffer ends an offer that was not claimed and returns its
+// places to the tour event in the same transaction.
+func (r *TourismRepo) ReleaseWaitlistOffer(entryID uuid.UUID, to string) error {
+	return r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		var entry entity.WaitlistEntry
+		if err := tx.First(&entry, "id = ?", entryID).Error; err != nil {
+			return fmt.Errorf("waitlist entry not found: %w", err)
+		}
+
+		result := tx.Model(&entity.WaitlistEntry{}).
+			Where("id = ? AND status = ?", entryID, entity.WaitlistStatusOffered).
+			Update("status", to)
+		if result.Error != nil {
+			return fmt.Errorf("update waitlist status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("waitlist entry %s has no open offer", entryID)
+		}
+
+		if err := tx.Model(&entity.TourEvent{}).
+			Where("id = ?", entry.TourEventID).
+			UpdateColumn("amount_of_places", gorm.Expr("amount_of_places + ?", entry.Quantity)).Error; err != nil {
+			return fmt.Errorf("failed to update amount_of_places: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TourismRepo) GetExpiredWaitlistOffers(now time.Time) ([]*entity.WaitlistEntry, error) {
+	var entries []*entity.WaitlistEntry
+	err := r.PG.Conn.
+		Where("status = ? AND offer_expires_at < ?", entity.WaitlistStatusOffered, now).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("get expired waitlist offers: %w", err)
+	}
+	return entries, nil
+}
+
+// ClaimWaitlistOffer turns an open offer into a processing purchase of the
+// offered places. The places were already taken from the tour event by the
+// offer. Offers of a closed or cancelled tour event cannot be claimed.
+func (r *TourismRepo) ClaimWaitlistOffer(entryID uuid.UUID, purchase *entity.Purchase) (*entity.Purchase, error) {
+	err := r.PG.Conn.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenTourEvent(tx, purchase.TourEventID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.WaitlistEntry{}).
+			Where("id = ? AND status = ? AND offer_expires_at > ?", entryID, entity.WaitlistStatusOffered, time.Now()).
+			Update("status", entity.WaitlistStatusClaimed)
+		if result.Error != nil {
+			return fmt.Errorf("update waitlist status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("waitlist entry %s has no open offer", entryID)
+		}
+
+		return createPurchaseRecord(tx, purchase, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim waitlist offer: %w", err)
+	}
+
+	return r.reloadPurchase(purchase)
+}
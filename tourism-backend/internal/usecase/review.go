@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"mime/multipart"
+	"strings"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+const (
+	_defaultReviewPageSize = 10
+	_maxReviewPageSize     = 50
+)
+
+var (
+	ErrInvalidReview        = errors.New("invalid review")
+	ErrReviewNotAllowed     = errors.New("only travelers who paid for a tour event that already took place can review it")
+	ErrAlreadyReviewed      = errors.New("you have already reviewed this tour event")
+	ErrReviewReplyForbidden = errors.New("you are not the owner of the reviewed tour")
+)
+
+// CreateReview stores the review of a tour event the user paid for and attended.
+func (t *TourismUseCase) CreateReview(review *entity.Review, photoFiles []*multipart.FileHeader) (*entity.Review, error) {
+	if review.Rating < 1 || review.Rating > 5 {
+		return nil, fmt.Errorf("%w: rating must be between 1 and 5", ErrInvalidReview)
+	}
+
+	tourEvent, err := t.repo.GetTourEventByID(review.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	attended, err := t.repo.HasAttendedTourEvent(review.UserID, review.TourEventID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !attended {
+		return nil, ErrReviewNotAllowed
+	}
+
+	reviewed, err := t.repo.HasReviewedTourEvent(review.UserID, review.TourEventID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewed {
+		return nil, ErrAlreadyReviewed
+	}
+
+	review.TourID = tourEvent.TourID
+	review.Text = strings.TrimSpace(review.Text)
+	return t.repo.CreateReview(review, photoFiles)
+}
+
+// ReplyToReview posts the public reply of the tour owner, replacing an earlier one.
+func (t *TourismUseCase) ReplyToReview(providerID, reviewID uuid.UUID, reply string) (*entity.Review, error) {
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return nil, fmt.Errorf("%w: reply is empty", ErrInvalidReview)
+	}
+
+	review, err := t.repo.GetReviewByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Tour.OwnerID != providerID {
+		return nil, ErrReviewReplyForbidden
+	}
+
+	repliedAt := time.Now()
+	if err := t.repo.SetReviewReply(reviewID, reply, repliedAt); err != nil {
+		return nil, err
+	}
+	review.Reply = reply
+	review.RepliedAt = &repliedAt
+	return review, nil
+}
+
+// GetTourReviews returns a page of the reviews of a tour.
+func (t *TourismUseCase) GetTourReviews(filter *entity.ReviewFilter) (*entity.ReviewPage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = _defaultReviewPageSize
+	}
+	if filter.PageSize > _maxReviewPageSize {
+		filter.PageSize = _maxReviewPageSize
+	}
+
+	reviews, total, err := t.repo.GetReviews(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.ReviewPage{
+		Reviews:  reviews,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// setTourRatings fills the rating aggregate of every tour.
+func (t *TourismUseCase) setTourRatings(tours []*entity.Tour) error {
+	if len(tours) == 0 {
+		return nil
+	}
+	tourIDs := make([]uuid.UUID, 0, len(tours))
+	for _, tour := range tours {
+		tourIDs = append(tourIDs, tour.ID)
+	}
+
+	ratings, err := t.repo.GetTourRatings(tourIDs)
+	if err != nil {
+		return err
+	}
+	for _, tour := range tours {
+		tour.Rating = ratings[tour.ID]
+	}
+	return nil
+}
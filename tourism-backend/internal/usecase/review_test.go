@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestCreateReviewOfAttendedTourEvent(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(-24*time.Hour), 0)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc, _, _ := newTestUseCase(repo)
+
+	review, err := uc.CreateReview(&entity.Review{TourEventID: tourEvent.ID, UserID: paid.UserID, Rating: 5, Text: "  Great lakes  "}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.TourID != tourEvent.TourID || review.Text != "Great lakes" {
+		t.Fatalf("review of tour %s with text %q, want tour %s with trimmed text", review.TourID, review.Text, tourEvent.TourID)
+	}
+
+	// One review per user and tour event.
+	again := &entity.Review{TourEventID: tourEvent.ID, UserID: paid.UserID, Rating: 1}
+	if _, err := uc.CreateReview(again, nil); !errors.Is(err, ErrAlreadyReviewed) {
+		t.Fatalf("second CreateReview() error = %v, want ErrAlreadyReviewed", err)
+	}
+	if len(repo.reviews) != 1 {
+		t.Errorf("reviews = %d, want 1", len(repo.reviews))
+	}
+}
+
+func TestCreateReviewRejects(t *testing.T) {
+	repo := newFakeRepo()
+	past := repo.addTourEvent(uuid.New(), time.Now().Add(-24*time.Hour), 0)
+	upcoming := repo.addTourEvent(uuid.New(), time.Now().Add(24*time.Hour), 0)
+	paidPast := repo.addPurchase(past, entity.PurchaseStatusPaid, 1)
+	paidUpcoming := repo.addPurchase(upcoming, entity.PurchaseStatusPaid, 1)
+	refunded := repo.addPurchase(past, entity.PurchaseStatusRefunded, 1)
+	processing := repo.addPurchase(past, entity.PurchaseStatusProcessing, 1)
+	uc, _, _ := newTestUseCase(repo)
+
+	tests := []struct {
+		name   string
+		review entity.Review
+		want   error
+	}{
+		{name: "tour event not over", review: entity.Review{TourEventID: upcoming.ID, UserID: paidUpcoming.UserID, Rating: 4}, want: ErrReviewNotAllowed},
+		{name: "refunded purchase", review: entity.Review{TourEventID: past.ID, UserID: refunded.UserID, Rating: 4}, want: ErrReviewNotAllowed},
+		{name: "unpaid purchase", review: entity.Review{TourEventID: past.ID, UserID: processing.UserID, Rating: 4}, want: ErrReviewNotAllowed},
+		{name: "no purchase", review: entity.Review{TourEventID: past.ID, UserID: uuid.New(), Rating: 4}, want: ErrReviewNotAllowed},
+		{name: "purchase of another event", review: entity.Review{TourEventID: upcoming.ID, UserID: paidPast.UserID, Rating: 4}, want: ErrReviewNotAllowed},
+		{name: "rating below 1", review: entity.Review{TourEventID: past.ID, UserID: paidPast.UserID, Rating: 0}, want: ErrInvalidReview},
+		{name: "rating above 5", review: entity.Review{TourEventID: past.ID, UserID: paidPast.UserID, Rating: 6}, want: ErrInvalidReview},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.CreateReview(&tt.review, nil); !errors.Is(err, tt.want) {
+				t.Fatalf("CreateReview() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(repo.reviews) != 0 {
+		t.Errorf("reviews = %d, want none", len(repo.reviews))
+	}
+}
+
+func TestSetTourRatingsAggregatesReviews(t *testing.T) {
+	repo := newFakeRepo()
+	first := repo.addTourEvent(uuid.New(), time.Now().Add(-48*time.Hour), 0)
+	second := repo.addTourEvent(uuid.New(), time.Now().Add(-24*time.Hour), 0)
+	second.Tour, second.TourID = first.Tour, first.TourID
+	unreviewed := repo.addTourEvent(uuid.New(), time.Now().Add(-24*time.Hour), 0)
+	uc, _, _ := newTestUseCase(repo)
+
+	for _, review := range []struct {
+		tourEvent *entity.TourEvent
+		rating    int
+	}{{first, 5}, {first, 4}, {second, 4}, {second, 2}} {
+		paid := repo.addPurchase(review.tourEvent, entity.PurchaseStatusPaid, 1)
+		if _, err := uc.CreateReview(&entity.Review{TourEventID: review.tourEvent.ID, UserID: paid.UserID, Rating: review.rating}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tours := []*entity.Tour{{ID: first.TourID}, {ID: unreviewed.TourID}}
+	if err := uc.setTourRatings(tours); err != nil {
+		t.Fatal(err)
+	}
+	rating := tours[0].Rating
+	if rating.Count != 4 || rating.Average != 3.75 {
+		t.Fatalf("rating = %v from %d reviews, want 3.75 from 4", rating.Average, rating.Count)
+	}
+	want := map[int]int64{1: 0, 2: 1, 3: 0, 4: 2, 5: 1}
+	for stars, count := range want {
+		if rating.Histogram[stars] != count {
+			t.Errorf("%d stars = %d reviews, want %d", stars, rating.Histogram[stars], count)
+		}
+	}
+	if empty := tours[1].Rating; empty.Count != 0 || empty.Average != 0 || len(empty.Histogram) != 5 {
+		t.Errorf("rating of a tour without reviews = %+v, want an empty one", empty)
+	}
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"path/filepath"
+	"strings"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/geo"
+)
+
+const (
+	_maxWaypoints      = 100
+	_maxTrackPositions = 50000
+)
+
+var (
+	ErrInvalidWaypoints = errors.New("invalid waypoints")
+	ErrInvalidTrack     = errors.New("invalid track")
+)
+
+// SetWaypoints replaces the route of a tour. The route starts at a meeting
+// point, passes named stops and finishes at an end point.
+func (t *TourismUseCase) SetWaypoints(tourID uuid.UUID, dtos []entity.WaypointDTO) ([]*entity.Waypoint, error) {
+	if err := validateWaypoints(dtos); err != nil {
+		return nil, err
+	}
+
+	waypoints := make([]*entity.Waypoint, 0, len(dtos))
+	for i, dto := range dtos {
+		waypoints = append(waypoints, &entity.Waypoint{
+			TourID:      tourID,
+			Position:    i,
+			Kind:        dto.Kind,
+			Name:        strings.TrimSpace(dto.Name),
+			Description: dto.Description,
+			Latitude:    dto.Latitude,
+			Longitude:   dto.Longitude,
+			StopMinutes: dto.StopMinutes,
+		})
+	}
+	if err := t.repo.ReplaceWaypoints(tourID, waypoints); err != nil {
+		return nil, err
+	}
+	return waypoints, nil
+}
+
+func validateWaypoints(waypoints []entity.WaypointDTO) error {
+	if len(waypoints) < 2 || len(waypoints) > _maxWaypoints {
+		return fmt.Errorf("%w: a route has between 2 and %d waypoints", ErrInvalidWaypoints, _maxWaypoints)
+	}
+	last := len(waypoints) - 1
+	for i, waypoint := range waypoints {
+		wantKind := entity.WaypointKindStop
+		switch i {
+		case 0:
+			wantKind = entity.WaypointKindMeeting
+		case last:
+			wantKind = entity.WaypointKindEnd
+		}
+		if waypoint.Kind != wantKind {
+			return fmt.Errorf("%w: waypoint %d must be a %s point, the route goes from the meeting point through the stops to the end point", ErrInvalidWaypoints, i, wantKind)
+		}
+		if waypoint.Kind == entity.WaypointKindStop && strings.TrimSpace(waypoint.Name) == "" {
+			return fmt.Errorf("%w: stop %d has no name", ErrInvalidWaypoints, i)
+		}
+		if waypoint.StopMinutes < 0 {
+			return fmt.Errorf("%w: waypoint %d has a negative duration", ErrInvalidWaypoints, i)
+		}
+		if !validLatitude(waypoint.Latitude) || !validLongitude(waypoint.Longitude) {
+			return fmt.Errorf("%w: waypoint %d: %v", ErrInvalidWaypoints, i, ErrInvalidLocation)
+		}
+	}
+	return nil
+}
+
+// ImportTourTrack replaces the track of a tour with the lines of a GPX or
+// GeoJSON file. The format is taken from the file extension, or from the
+// content when the extension is unknown.
+func (t *TourismUseCase) ImportTourTrack(tourID uuid.UUID, filename string, data []byte) (*entity.TourTrack, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if format != "gpx" && format != "geojson" && format != "json" {
+		format = "geojson"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '<' {
+			format = "gpx"
+		}
+	}
+
+	var segments [][][]float64
+	var err error
+	if format == "gpx" {
+		segments, err = geo.ParseGPX(bytes.NewReader(data))
+	} else {
+		format = "geojson"
+		segments, err = geo.ParseGeoJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTrack, err)
+	}
+
+	positions := 0
+	for _, segment := range segments {
+		positions += len(segment)
+	}
+	if positions > _maxTrackPositions {
+		return nil, fmt.Errorf("%w: the track has more than %d points", ErrInvalidTrack, _maxTrackPositions)
+	}
+
+	track := &entity.TourTrack{TourID: tourID, Segments: segments, Source: format}
+	if err := t.repo.SaveTourTrack(track); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+func (t *TourismUseCase) DeleteTourTrack(tourID uuid.UUID) error {
+	return t.repo.DeleteTourTrack(tourID)
+}
+
+// GetTourRouteMap returns the route of a tour as a GeoJSON FeatureCollection.
+func (t *TourismUseCase) GetTourRouteMap(tourID uuid.UUID) (*entity.FeatureCollection, error) {
+	waypoints, err := t.repo.GetWaypoints(tourID)
+	if err != nil {
+		return nil, err
+	}
+	track, err := t.repo.GetTourTrack(tourID)
+	if err != nil {
+		return nil, err
+	}
+	return buildRouteMap(waypoints, track), nil
+}
+
+// buildRouteMap draws the track of a tour, or straight lines between its
+// waypoints when it has no track, followed by a point for every waypoint.
+// The distance is measured along the same line.
+func buildRouteMap(waypoints []*entity.Waypoint, track *entity.TourTrack) *entity.FeatureCollection {
+	routeMap := &entity.FeatureCollection{Type: "FeatureCollection", Features: []*entity.Feature{}}
+
+	var lines [][][]float64
+	properties := map[string]interface{}{"kind": "track"}
+	if track != nil {
+		lines = track.Segments
+	} else if len(waypoints) >= 2 {
+		line := make([][]float64, 0, len(waypoints))
+		for _, waypoint := range waypoints {
+			line = append(line, []float64{waypoint.Longitude, waypoint.Latitude})
+		}
+		lines = [][][]float64{line}
+		properties["kind"] = "waypoints"
+	}
+
+	if len(lines) > 0 {
+		geometry := &entity.Geometry{Type: "MultiLineString", Coordinates: lines}
+		if len(lines) == 1 {
+			geometry = &entity.Geometry{Type: "LineString", Coordinates: lines[0]}
+		}
+		var distance float64
+		for _, line := range lines {
+			distance += geo.LengthKm(line)
+		}
+		routeMap.DistanceKm = math.Round(distance*100) / 100
+		properties["distance_km"] = routeMap.DistanceKm
+		routeMap.Features = append(routeMap.Features, &entity.Feature{Type: "Feature", Geometry: geometry, Properties: properties})
+	}
+
+	for _, waypoint := range waypoints {
+		routeMap.Features = append(routeMap.Features, &entity.Feature{
+			Type:     "Feature",
+			Geometry: &entity.Geometry{Type: "Point", Coordinates: []float64{waypoint.Longitude, waypoint.Latitude}},
+			Properties: map[string]interface{}{
+				"id":           waypoint.ID,
+				"position":     waypoint.Position,
+				"kind":         waypoint.Kind,
+				"name":         waypoint.Name,
+				"description":  waypoint.Description,
+				"stop_minutes": waypoint.StopMinutes,
+			},
+		})
+	}
+	return routeMap
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"tourism-backend/internal/entity"
+)
+
+func newRoute() []entity.WaypointDTO {
+	return []entity.WaypointDTO{
+		{Kind: entity.WaypointKindMeeting, Name: "Sairan bus stop", Latitude: 43.2389, Longitude: 76.8897},
+		{Kind: entity.WaypointKindStop, Name: "Dam", Latitude: 43.0575, Longitude: 76.9853, StopMinutes: 30},
+		{Kind: entity.WaypointKindEnd, Latitude: 43.0505, Longitude: 76.9853},
+	}
+}
+
+func TestValidateWaypoints(t *testing.T) {
+	if err := validateWaypoints(newRoute()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(route []entity.WaypointDTO) []entity.WaypointDTO
+	}{
+		{
+			name:   "only a meeting point",
+			change: func(route []entity.WaypointDTO) []entity.WaypointDTO { return route[:1] },
+		},
+		{
+			name: "route not starting at a meeting point",
+			change: func(route []entity.WaypointDTO) []entity.WaypointDTO {
+				route[0].Kind = entity.WaypointKindStop
+				return route
+			},
+		},
+		{
+			name: "stop without a name",
+			change: func(route []entity.WaypointDTO) []entity.WaypointDTO {
+				route[1].Name = " "
+				return route
+			},
+		},
+		{
+			name: "point off the globe",
+			change: func(route []entity.WaypointDTO) []entity.WaypointDTO {
+				route[2].Longitude = 190
+				return route
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateWaypoints(tt.change(newRoute())); !errors.Is(err, ErrInvalidWaypoints) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidWaypoints)
+			}
+		})
+	}
+}
+
+func TestBuildRouteMap(t *testing.T) {
+	var waypoints []*entity.Waypoint
+	for i, dto := range newRoute() {
+		waypoints = append(waypoints, &entity.Waypoint{Position: i, Kind: dto.Kind, Latitude: dto.Latitude, Longitude: dto.Longitude})
+	}
+
+	straight := buildRouteMap(waypoints, nil)
+	if len(straight.Features) != 4 || straight.Features[0].Geometry.Type != "LineString" {
+		t.Fatalf("features = %d, want a line and 3 points", len(straight.Features))
+	}
+	if straight.DistanceKm < 22 || straight.DistanceKm > 23 {
+		t.Fatalf("distance = %v km, want about 22.4", straight.DistanceKm)
+	}
+
+	track := &entity.TourTrack{Segments: [][][]float64{
+		{{76.8897, 43.2389}, {76.95, 43.15}},
+		{{76.95, 43.15}, {76.9853, 43.0505}},
+	}}
+	tracked := buildRouteMap(waypoints, track)
+	if tracked.Features[0].Geometry.Type != "MultiLineString" || tracked.Features[0].Properties["kind"] != "track" {
+		t.Fatalf("first feature = %+v, want the track", tracked.Features[0])
+	}
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/rrule"
+)
+
+var (
+	ErrScheduleNotFound  = errors.New("tour schedule not found")
+	ErrScheduleForbidden = errors.New("you are not the owner of this tour schedule")
+	ErrInvalidSchedule   = errors.New("invalid tour schedule")
+)
+
+// CreateTourSchedule creates a schedule of a tour and its events up to the
+// generation horizon.
+func (t *TourismUseCase) CreateTourSchedule(tourID uuid.UUID, dto *entity.CreateTourScheduleDTO) (*entity.TourSchedule, error) {
+	schedule := &entity.TourSchedule{
+		TourID:         tourID,
+		RRule:          dto.RRule,
+		TimeZone:       dto.TimeZone,
+		StartsAt:       dto.StartsAt,
+		Price:          dto.Price,
+		Place:          dto.Place,
+		AmountOfPlaces: dto.AmountOfPlaces,
+		ExceptionDates: dto.ExceptionDates,
+	}
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	schedule, err := t.repo.CreateTourSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.generateSchedule(schedule, time.Now()); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (t *TourismUseCase) GetTourSchedules(tourID uuid.UUID) ([]*entity.TourSchedule, error) {
+	return t.repo.GetTourSchedules(tourID)
+}
+
+// UpdateTourSchedule changes a schedule of the provider. Its upcoming events
+// without sales follow the new settings: they move, appear or disappear with
+// the rule and take the new price, place and number of places.
+func (t *TourismUseCase) UpdateTourSchedule(providerID, scheduleID uuid.UUID, dto *entity.UpdateTourScheduleDTO) (*entity.TourSchedule, error) {
+	schedule, err := t.providerSchedule(providerID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.RRule != nil {
+		schedule.RRule = *dto.RRule
+	}
+	if dto.TimeZone != nil {
+		schedule.TimeZone = *dto.TimeZone
+	}
+	if dto.StartsAt != nil {
+		schedule.StartsAt = *dto.StartsAt
+	}
+	if dto.Price != nil {
+		schedule.Price = *dto.Price
+	}
+	if dto.Place != nil {
+		schedule.Place = *dto.Place
+	}
+	if dto.AmountOfPlaces != nil {
+		schedule.AmountOfPlaces = *dto.AmountOfPlaces
+	}
+	if dto.ExceptionDates != nil {
+		schedule.ExceptionDates = *dto.ExceptionDates
+	}
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	if err := t.repo.UpdateTourSchedule(schedule); err != nil {
+		return nil, err
+	}
+	if _, err := t.generateSchedule(schedule, time.Now()); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// DeleteTourSchedule deletes a schedule of the provider with its upcoming
+// events without sales. Events with sales stay and can be cancelled one by one.
+func (t *TourismUseCase) DeleteTourSchedule(providerID, scheduleID uuid.UUID) error {
+	if _, err := t.providerSchedule(providerID, scheduleID); err != nil {
+		return err
+	}
+	return t.repo.DeleteTourSchedule(scheduleID, time.Now())
+}
+
+func (t *TourismUseCase) providerSchedule(providerID, scheduleID uuid.UUID) (*entity.TourSchedule, error) {
+	schedule, err := t.repo.GetTourSchedule(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, ErrScheduleNotFound
+	}
+	if schedule.Tour.OwnerID != providerID {
+		return nil, ErrScheduleForbidden
+	}
+	return schedule, nil
+}
+
+// validateSchedule checks the rule, time zone and exception dates of a
+// schedule and writes the exception dates as YYYY-MM-DD.
+func validateSchedule(schedule *entity.TourSchedule) error {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil || schedule.TimeZone == "" {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, schedule.TimeZone)
+	}
+	if _, err := rrule.Parse(schedule.RRule, loc); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if schedule.StartsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at is required", ErrInvalidSchedule)
+	}
+	if schedule.AmountOfPlaces <= 0 {
+		return fmt.Errorf("%w: amount_of_places must be positive", ErrInvalidSchedule)
+	}
+
+	dates := make([]string, 0, len(schedule.ExceptionDates))
+	for _, value := range schedule.ExceptionDates {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("%w: exception date %q is not YYYY-MM-DD", ErrInvalidSchedule, value)
+		}
+		dates = append(dates, date.Format("2006-01-02"))
+	}
+	schedule.ExceptionDates = dates
+	return nil
+}
+
+// scheduleOccurrences returns the start times of a schedule in [from, to),
+// without those on its exception dates.
+func scheduleOccurrences(schedule *entity.TourSchedule, from, to time.Time) ([]time.Time, error) {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, schedule.TimeZone)
+	}
+	rule, err := rrule.Parse(schedule.RRule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	exceptions := make(map[string]bool, len(schedule.ExceptionDates))
+	for _, date := range schedule.ExceptionDates {
+		exceptions[date] = true
+	}
+	var occurrences []time.Time
+	for _, occurrence := range rule.Between(schedule.StartsAt.In(loc), from, to) {
+		if !exceptions[occurrence.Format("2006-01-02")] {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	return occurrences, nil
+}
+
+// generateSchedule brings the events of a schedule from a date on in line
+// with it, up to the generation horizon. It returns how many events were created.
+func (t *TourismUseCase) generateSchedule(schedule *entity.TourSchedule, from time.Time) (int, error) {
+	until := time.Now().Add(t.scheduleCfg.Horizon)
+	occurrences, err := scheduleOccurrences(schedule, from, until)
+	if err != nil {
+		return 0, err
+	}
+	return t.repo.SyncScheduleOccurrences(schedule, from, occurrences, until)
+}
+
+// GenerateScheduledEvents extends every schedule with the events that entered
+// the generation horizon. It returns how many events were created.
+func (t *TourismUseCase) GenerateScheduledEvents() (int, error) {
+	now := time.Now()
+	schedules, err := t.repo.GetSchedulesToGenerate(now.Add(t.scheduleCfg.Horizon))
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, schedule := range schedules {
+		from := now
+		if schedule.GeneratedUntil != nil && schedule.GeneratedUntil.After(now) {
+			from = *schedule.GeneratedUntil
+		}
+		n, err := t.generateSchedule(schedule, from)
+		if err != nil {
+			log.Printf("Generate events of tour schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		created += n
+	}
+	return created, nil
+}
+
+// RunScheduleGenerator generates the events of the schedules every interval
+// until ctx is done.
+func (t *TourismUseCase) RunScheduleGenerator(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := t.GenerateScheduledEvents()
+		if err != nil {
+			log.Printf("Schedule generator error: %v", err)
+		} else if created > 0 {
+			log.Printf("Schedule generator created %d tour events", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"tourism-backend/internal/entity"
+)
+
+func TestScheduleOccurrencesSkipExceptionDates(t *testing.T) {
+	schedule := &entity.TourSchedule{
+		RRule:          "FREQ=WEEKLY;BYDAY=SA",
+		TimeZone:       "Asia/Almaty",
+		StartsAt:       time.Date(2026, time.November, 7, 4, 0, 0, 0, time.UTC), // 09:00 in Almaty
+		AmountOfPlaces: 20,
+		ExceptionDates: []string{"2026-11-14"},
+	}
+	if err := validateSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	occurrences, err := scheduleOccurrences(schedule, from, from.AddDate(0, 0, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, occurrence := range occurrences {
+		got = append(got, occurrence.Format("2006-01-02 15:04"))
+	}
+	want := []string{"2026-11-07 09:00", "2026-11-21 09:00", "2026-11-28 09:00"}
+	if len(got) != len(want) {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("occurrences = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestValidateScheduleRejects(t *testing.T) {
+	valid := entity.TourSchedule{
+		RRule:          "FREQ=DAILY",
+		TimeZone:       "Europe/Berlin",
+		StartsAt:       time.Now(),
+		AmountOfPlaces: 10,
+	}
+
+	tests := []struct {
+		name   string
+		change func(*entity.TourSchedule)
+	}{
+		{name: "unknown time zone", change: func(s *entity.TourSchedule) { s.TimeZone = "Mars/Olympus" }},
+		{name: "invalid rule", change: func(s *entity.TourSchedule) { s.RRule = "FREQ=SECONDLY" }},
+		{name: "no places", change: func(s *entity.TourSchedule) { s.AmountOfPlaces = 0 }},
+		{name: "invalid exception date", change: func(s *entity.TourSchedule) { s.ExceptionDates = []string{"14.11.2026"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := valid
+			tt.change(&schedule)
+			if err := validateSchedule(&schedule); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("validateSchedule() error = %v, want ErrInvalidSchedule", err)
+			}
+		})
+	}
+}
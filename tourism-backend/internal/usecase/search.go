@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"tourism-backend/internal/entity"
+)
+
+const (
+	_defaultSearchLimit = 20
+	_maxSearchLimit     = 50
+)
+
+var ErrEmptySearchQuery = errors.New("search query is empty")
+
+// SearchTours returns the tours best matching the query with their ratings.
+func (t *TourismUseCase) SearchTours(query string, limit, offset int) ([]*entity.TourSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit < 1 {
+		limit = _defaultSearchLimit
+	}
+	if limit > _maxSearchLimit {
+		limit = _maxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	results, err := t.repo.SearchTours(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	tours := make([]*entity.Tour, 0, len(results))
+	for _, result := range results {
+		tours = append(tours, &result.Tour)
+	}
+	if err := t.setTourRatings(tours); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"tourism-backend/internal/entity"
+)
+
+// searchRepo records the search the use case ran and finds a single tour.
+type searchRepo struct {
+	*fakeRepo
+	searched      bool
+	query         string
+	limit, offset int
+	found         *entity.TourSearchResult
+}
+
+func (r *searchRepo) SearchTours(query string, limit, offset int) ([]*entity.TourSearchResult, error) {
+	r.searched = true
+	r.query, r.limit, r.offset = query, limit, offset
+	return []*entity.TourSearchResult{r.found}, nil
+}
+
+func TestSearchToursRejectsEmptyQuery(t *testing.T) {
+	for _, query := range []string{"", "   ", "\t\n"} {
+		repo := &searchRepo{fakeRepo: newFakeRepo()}
+		uc := &TourismUseCase{repo: repo}
+
+		if _, err := uc.SearchTours(query, 10, 0); !errors.Is(err, ErrEmptySearchQuery) {
+			t.Errorf("SearchTours(%q) error = %v, want ErrEmptySearchQuery", query, err)
+		}
+		if repo.searched {
+			t.Errorf("SearchTours(%q) searched the repo", query)
+		}
+	}
+}
+
+func TestSearchToursBoundsPage(t *testing.T) {
+	tests := []struct {
+		name                  string
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{name: "given page", limit: 10, offset: 20, wantLimit: 10, wantOffset: 20},
+		{name: "no limit", limit: 0, offset: 0, wantLimit: _defaultSearchLimit, wantOffset: 0},
+		{name: "negative limit", limit: -5, offset: 0, wantLimit: _defaultSearchLimit, wantOffset: 0},
+		{name: "limit above the maximum", limit: 1000, offset: 0, wantLimit: _maxSearchLimit, wantOffset: 0},
+		{name: "negative offset", limit: 10, offset: -3, wantLimit: 10, wantOffset: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &searchRepo{fakeRepo: newFakeRepo(), found: &entity.TourSearchResult{Tour: entity.Tour{ID: uuid.New()}}}
+			uc := &TourismUseCase{repo: repo}
+
+			results, err := uc.SearchTours("  kolsai lakes ", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.query != "kolsai lakes" || repo.limit != tt.wantLimit || repo.offset != tt.wantOffset {
+				t.Fatalf("searched %q with limit %d and offset %d, want %q with limit %d and offset %d",
+					repo.query, repo.limit, repo.offset, "kolsai lakes", tt.wantLimit, tt.wantOffset)
+			}
+			if len(results) != 1 || results[0].Tour.Rating == nil {
+				t.Fatalf("results = %+v, want the tour with its rating", results)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// RunSeatHoldSweeper expires unpaid purchases and unclaimed waitlist offers
+// every interval until ctx is done.
+func (t *TourismUseCase) RunSeatHoldSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := t.ExpireSeatHolds()
+		if err != nil {
+			log.Printf("Seat hold sweeper error: %v", err)
+		} else if expired > 0 {
+			log.Printf("Seat hold sweeper expired %d purchases", expired)
+		}
+
+		expired, err = t.ExpireWaitlistOffers()
+		if err != nil {
+			log.Printf("Seat hold sweeper error: %v", err)
+		} else if expired > 0 {
+			log.Printf("Seat hold sweeper expired %d waitlist offers", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireSeatHolds marks processing purchases with an expired seat hold as Expired,
+// returns their seats to the waitlist and notifies the users. It returns how many purchases expired.
+func (t *TourismUseCase) ExpireSeatHolds() (int, error) {
+	purchases, err := t.repo.GetExpiredSeatHolds(time.Now(), t.purchaseCfg.SeatHoldTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, purchase := range purchases {
+		if err := t.expirePurchase(purchase); err != nil {
+			log.Printf("Expire purchase %s: %v", purchase.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (t *TourismUseCase) expirePurchase(purchase *entity.Purchase) error {
+	if err := checkPurchaseTransition(purchase.Status, entity.PurchaseStatusExpired); err != nil {
+		return err
+	}
+
+	// Stop a charge that is still in flight so it cannot complete after the seat is gone.
+	if purchase.PaymentIntentID != "" {
+		if err := t.refunder.Refund(purchase); err != nil {
+			return fmt.Errorf("stop payment: %w", err)
+		}
+	}
+
+	if err := t.repo.ReleasePurchase(purchase.ID, purchase.Status, entity.PurchaseStatusExpired); err != nil {
+		return err
+	}
+	purchase.Status = entity.PurchaseStatusExpired
+	t.offerFreedSeats(purchase.TourEventID)
+
+	kafkaMessage := entity.Notification{
+		Topic: "PAYMENT",
+		Data: map[string]interface{}{
+			"Text":    "Your seat hold expired because the payment was not completed",
+			"Payment": purchase,
+		},
+		Recipients: []uuid.UUID{purchase.UserID},
+	}
+
+	t.PublishMessage("notifications", kafkaMessage)
+
+	return nil
+}
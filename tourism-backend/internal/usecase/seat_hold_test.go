@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// addTicketTypePurchase stores a purchase of places seats of a ticket type of
+// the tour event, holding them on the ticket type too.
+func addTicketTypePurchase(repo *fakeRepo, tourEvent *entity.TourEvent, status string, places int, holdExpiresAt time.Time) *entity.Purchase {
+	if len(tourEvent.TicketTypes) == 0 {
+		tourEvent.TicketTypes = []entity.TicketType{{ID: uuid.New(), TourEventID: tourEvent.ID, Name: "Adult", Price: 10000, MaxPlaces: 10}}
+	}
+	tourEvent.TicketTypes[0].HeldPlaces += places
+
+	purchase := repo.addPurchase(tourEvent, status, places)
+	purchase.HoldExpiresAt = &holdExpiresAt
+	purchase.Items = []entity.PurchaseItem{{PurchaseID: purchase.ID, TicketTypeID: tourEvent.TicketTypes[0].ID, Quantity: places, UnitPrice: 10000}}
+	return purchase
+}
+
+func TestExpireSeatHoldsReturnsSeats(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 1)
+	expired := addTicketTypePurchase(repo, tourEvent, entity.PurchaseStatusProcessing, 3, time.Now().Add(-time.Minute))
+	holding := addTicketTypePurchase(repo, tourEvent, entity.PurchaseStatusProcessing, 1, time.Now().Add(time.Minute))
+	paid := addTicketTypePurchase(repo, tourEvent, entity.PurchaseStatusPaid, 2, time.Now().Add(-time.Minute))
+	uc, refunder, producer := newTestUseCase(repo)
+
+	count, err := uc.ExpireSeatHolds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expired %d purchases, want 1", count)
+	}
+	if status := repo.purchases[expired.ID].Status; status != entity.PurchaseStatusExpired {
+		t.Errorf("expired hold = %s, want Expired", status)
+	}
+	for _, purchase := range []*entity.Purchase{holding, paid} {
+		if status := repo.purchases[purchase.ID].Status; status == entity.PurchaseStatusExpired {
+			t.Errorf("purchase of %d places expired, want it kept", purchase.Quantity)
+		}
+	}
+	if places := tourEvent.AmountOfPlaces; places != 4 {
+		t.Errorf("free places = %v, want the 3 expired seats returned", places)
+	}
+	if held := tourEvent.TicketTypes[0].HeldPlaces; held != 3 {
+		t.Errorf("held places of the ticket type = %d, want 3 of the remaining purchases", held)
+	}
+	if len(refunder.refunded) != 0 {
+		t.Errorf("refunded = %v, want nothing, no charge was started", refunder.refunded)
+	}
+	if topics := producer.topics(); len(topics) != 1 || topics[0] != "PAYMENT" {
+		t.Errorf("notifications = %v, want one PAYMENT", topics)
+	}
+}
+
+func TestExpireSeatHoldsStopsChargeInFlight(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	purchase := addTicketTypePurchase(repo, tourEvent, entity.PurchaseStatusProcessing, 2, time.Now().Add(-time.Minute))
+	purchase.PaymentIntentID = "pi_in_flight"
+	uc, refunder, _ := newTestUseCase(repo)
+
+	if _, err := uc.ExpireSeatHolds(); err != nil {
+		t.Fatal(err)
+	}
+	if len(refunder.refunded) != 1 || repo.purchases[purchase.ID].Status != entity.PurchaseStatusExpired {
+		t.Fatalf("refunded = %v, status = %s, want the charge stopped and the purchase expired",
+			refunder.refunded, repo.purchases[purchase.ID].Status)
+	}
+}
+
+func TestExpirePurchaseLeavesPaidPurchase(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	purchase := addTicketTypePurchase(repo, tourEvent, entity.PurchaseStatusProcessing, 2, time.Now().Add(-time.Minute))
+	uc, _, producer := newTestUseCase(repo)
+
+	// The sweeper loaded the purchase while it was processing, and its payment
+	// succeeded right after.
+	stale := *purchase
+	repo.purchases[purchase.ID].Status = entity.PurchaseStatusPaid
+
+	if err := uc.expirePurchase(&stale); err == nil {
+		t.Fatal("expirePurchase() of a paid purchase succeeded")
+	}
+	if status := repo.purchases[purchase.ID].Status; status != entity.PurchaseStatusPaid {
+		t.Errorf("status = %s, want Paid", status)
+	}
+	if places, held := tourEvent.AmountOfPlaces, tourEvent.TicketTypes[0].HeldPlaces; places != 0 || held != 2 {
+		t.Errorf("free places = %v, held places = %d, want the seats kept by the purchase", places, held)
+	}
+	if len(producer.notifications) != 0 {
+		t.Errorf("notifications = %v, want none", producer.topics())
+	}
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/ticket"
+	"tourism-backend/utils"
+)
+
+var (
+	ErrTicketRevoked     = errors.New("ticket is no longer valid")
+	ErrTicketAlreadyUsed = errors.New("ticket was already checked in")
+)
+
+// GetPurchaseQR returns one signed QR ticket per seat of the purchase. The
+// tickets expire ticket.validity after the start of the tour event.
+func (t *TourismUseCase) GetPurchaseQR(userID, purchaseID uuid.UUID) ([]*entity.PurchaseQRDTO, error) {
+	purchase, err := t.repo.GetPurchaseQR(userID, purchaseID)
+	if err != nil {
+		log.Println("GetPurchaseQR err:", err)
+		return nil, err
+	}
+
+	expiresAt := purchase.TourEvent.Date.Add(t.ticketCfg.Validity)
+	result := make([]*entity.PurchaseQRDTO, 0, purchase.Quantity)
+	for seat := 0; seat < purchase.Quantity; seat++ {
+		token, err := ticket.Sign(t.ticketKey, ticket.Ticket{
+			PurchaseID:  purchase.ID,
+			TourEventID: purchase.TourEventID,
+			Seat:        seat,
+			ExpiresAt:   expiresAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign ticket for seat %d: %w", seat, err)
+		}
+		qr := utils.GenerateQRCode(token, seat)
+		if qr == nil {
+			return nil, fmt.Errorf("generate QR code for seat %d failed", seat)
+		}
+		qr.ExpiresAt = expiresAt
+		result = append(result, qr)
+	}
+	return result, nil
+}
+
+// GetTicketKey returns the public key that verifies ticket tokens, so that
+// scanners can check tickets without a connection.
+func (t *TourismUseCase) GetTicketKey() *entity.TicketKeyDTO {
+	return &entity.TicketKeyDTO{
+		Algorithm: "Ed25519",
+		PublicKey: ticket.EncodePublicKey(t.ticketPublicKey()),
+	}
+}
+
+// CheckTicket verifies a scanned ticket token for the provider of its tour
+// event and tells whether it was checked in already.
+func (t *TourismUseCase) CheckTicket(providerID uuid.UUID, token string) (*entity.CheckedTicketDTO, error) {
+	checked, err := t.scanTicket(providerID, token)
+	if err != nil {
+		return nil, err
+	}
+	checked.CheckIn, err = t.repo.GetCheckIn(checked.Purchase.ID, checked.Seat)
+	if err != nil {
+		return nil, err
+	}
+	return checked, nil
+}
+
+// CheckInTicket marks a scanned ticket as used by the provider of its tour
+// event. Only the owner of the tour can check tickets in, there are no staff
+// accounts to delegate it to. A ticket is used once, a second scan fails with
+// ErrTicketAlreadyUsed.
+func (t *TourismUseCase) CheckInTicket(providerID uuid.UUID, token string) (*entity.CheckedTicketDTO, error) {
+	checked, err := t.scanTicket(providerID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	checkIn, created, err := t.repo.CreateCheckIn(&entity.CheckIn{
+		PurchaseID:    checked.Purchase.ID,
+		Seat:          checked.Seat,
+		TourEventID:   checked.Purchase.TourEventID,
+		CheckedInAt:   time.Now(),
+		CheckedInByID: providerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: seat %d was checked in at %s by %s", ErrTicketAlreadyUsed,
+			checkIn.Seat, checkIn.CheckedInAt.Format(time.RFC3339), checkIn.CheckedInByID)
+	}
+	checked.CheckIn = checkIn
+	return checked, nil
+}
+
+// scanTicket verifies a ticket token for the provider of its tour event.
+// Besides the signature it checks that the purchase is still paid and has the
+// seat. An expired token is still accepted when its tour event was moved to a
+// later date.
+func (t *TourismUseCase) scanTicket(providerID uuid.UUID, token string) (*entity.CheckedTicketDTO, error) {
+	now := time.Now()
+	scanned, verifyErr := ticket.Verify(t.ticketPublicKey(), token, now)
+	if verifyErr != nil && !errors.Is(verifyErr, ticket.ErrTicketExpired) {
+		return nil, verifyErr
+	}
+
+	purchase, err := t.repo.GetPurchaseByID(scanned.PurchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ticket.ErrInvalidTicket, err)
+	}
+	if purchase.TourEvent.Tour.OwnerID != providerID {
+		return nil, ErrPurchaseForbidden
+	}
+	if verifyErr != nil && !now.Before(purchase.TourEvent.Date.Add(t.ticketCfg.Validity)) {
+		return nil, verifyErr
+	}
+	if purchase.TourEventID != scanned.TourEventID || scanned.Seat >= purchase.Quantity {
+		return nil, fmt.Errorf("%w: the purchase has no seat %d at this tour event", ErrTicketRevoked, scanned.Seat)
+	}
+	if purchase.Status != entity.PurchaseStatusPaid {
+		return nil, fmt.Errorf("%w: the purchase is %s", ErrTicketRevoked, purchase.Status)
+	}
+
+	return &entity.CheckedTicketDTO{
+		Seat:      scanned.Seat,
+		ExpiresAt: scanned.ExpiresAt,
+		Purchase:  purchase,
+	}, nil
+}
+
+// GetTourEventAttendance lists the paid seats of a tour event of the provider
+// with their check-ins.
+func (t *TourismUseCase) GetTourEventAttendance(providerID, tourEventID uuid.UUID) (*entity.AttendanceDTO, error) {
+	tourEvent, err := t.repo.GetTourEventByID(tourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("get tour event attendance: %w", err)
+	}
+	if tourEvent.Tour.OwnerID != providerID {
+		return nil, ErrTourEventForbidden
+	}
+
+	purchases, checkIns, err := t.repo.GetAttendance(tourEventID)
+	if err != nil {
+		return nil, err
+	}
+	type seatKey struct {
+		purchaseID uuid.UUID
+		seat       int
+	}
+	checkInsBySeat := make(map[seatKey]*entity.CheckIn, len(checkIns))
+	for _, checkIn := range checkIns {
+		checkInsBySeat[seatKey{checkIn.PurchaseID, checkIn.Seat}] = checkIn
+	}
+
+	attendance := &entity.AttendanceDTO{
+		TourEventID: tourEvent.ID,
+		Date:        tourEvent.Date,
+		Tickets:     make([]*entity.AttendanceTicketDTO, 0),
+	}
+	for _, purchase := range purchases {
+		for seat := 0; seat < purchase.Quantity; seat++ {
+			checkIn := checkInsBySeat[seatKey{purchase.ID, seat}]
+			attendance.Sold++
+			if checkIn != nil {
+				attendance.CheckedIn++
+			}
+			attendance.Tickets = append(attendance.Tickets, &entity.AttendanceTicketDTO{
+				PurchaseID: purchase.ID,
+				UserID:     purchase.UserID,
+				Username:   purchase.User.Username,
+				Seat:       seat,
+				CheckIn:    checkIn,
+			})
+		}
+	}
+	if tourEvent.Date.After(time.Now()) {
+		attendance.Remaining = attendance.Sold - attendance.CheckedIn
+	} else {
+		attendance.NoShow = attendance.Sold - attendance.CheckedIn
+	}
+	return attendance, nil
+}
+
+func (t *TourismUseCase) ticketPublicKey() ed25519.PublicKey {
+	return t.ticketKey.Public().(ed25519.PublicKey)
+}
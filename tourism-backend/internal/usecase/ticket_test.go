@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/ticket"
+)
+
+func newTicketUseCase(t *testing.T, repo *fakeRepo) *TourismUseCase {
+	t.Helper()
+	uc, _, _ := newTestUseCase(repo)
+	key, err := ticket.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.ticketKey = key
+	uc.ticketCfg.Validity = 24 * time.Hour
+	return uc
+}
+
+// signTicket returns the token of a seat of the purchase, signed with key.
+func signTicket(t *testing.T, key ed25519.PrivateKey, purchase *entity.Purchase, tourEvent *entity.TourEvent, seat int) string {
+	t.Helper()
+	token, err := ticket.Sign(key, ticket.Ticket{
+		PurchaseID:  purchase.ID,
+		TourEventID: purchase.TourEventID,
+		Seat:        seat,
+		ExpiresAt:   tourEvent.Date.Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestCheckInTicketOnce(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(time.Hour), 3)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc := newTicketUseCase(t, repo)
+	token := signTicket(t, uc.ticketKey, paid, tourEvent, 1)
+
+	checked, err := uc.CheckInTicket(ownerID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked.Seat != 1 || checked.CheckIn == nil || checked.CheckIn.CheckedInByID != ownerID {
+		t.Fatalf("checked ticket = %+v, want seat 1 checked in by the owner", checked)
+	}
+
+	if _, err := uc.CheckInTicket(ownerID, token); !errors.Is(err, ErrTicketAlreadyUsed) {
+		t.Fatalf("second CheckInTicket() error = %v, want ErrTicketAlreadyUsed", err)
+	}
+	checked, err = uc.CheckTicket(ownerID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked.CheckIn == nil {
+		t.Fatal("CheckTicket() does not report the check-in")
+	}
+
+	// The other seat of the purchase is a ticket of its own.
+	if _, err := uc.CheckInTicket(ownerID, signTicket(t, uc.ticketKey, paid, tourEvent, 0)); err != nil {
+		t.Fatalf("CheckInTicket() of the other seat error = %v", err)
+	}
+}
+
+func TestCheckInTicketRejects(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(time.Hour), 3)
+	past := repo.addTourEvent(ownerID, time.Now().Add(-48*time.Hour), 3)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc := newTicketUseCase(t, repo)
+	otherKey, err := ticket.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		providerID uuid.UUID
+		token      string
+		want       error
+	}{
+		{
+			name:       "refunded purchase",
+			providerID: ownerID,
+			token:      signTicket(t, uc.ticketKey, repo.addPurchase(tourEvent, entity.PurchaseStatusRefunded, 1), tourEvent, 0),
+			want:       ErrTicketRevoked,
+		},
+		{
+			name:       "purchase waiting for its refund",
+			providerID: ownerID,
+			token:      signTicket(t, uc.ticketKey, repo.addPurchase(tourEvent, entity.PurchaseStatusCancelRequested, 1), tourEvent, 0),
+			want:       ErrTicketRevoked,
+		},
+		{name: "seat the purchase does not have", providerID: ownerID, token: signTicket(t, uc.ticketKey, paid, tourEvent, 2), want: ErrTicketRevoked},
+		{name: "other provider", providerID: uuid.New(), token: signTicket(t, uc.ticketKey, paid, tourEvent, 0), want: ErrPurchaseForbidden},
+		{name: "forged signature", providerID: ownerID, token: signTicket(t, otherKey, paid, tourEvent, 0), want: ticket.ErrInvalidTicket},
+		{
+			name:       "expired ticket",
+			providerID: ownerID,
+			token:      signTicket(t, uc.ticketKey, repo.addPurchase(past, entity.PurchaseStatusPaid, 1), past, 0),
+			want:       ticket.ErrTicketExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.CheckInTicket(tt.providerID, tt.token); !errors.Is(err, tt.want) {
+				t.Fatalf("CheckInTicket() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if len(repo.checkIns) != 0 {
+		t.Errorf("check-ins = %d, want none", len(repo.checkIns))
+	}
+}
+
+func TestGetTourEventAttendanceCounts(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(time.Hour), 3)
+	couple := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 1)
+	repo.addPurchase(tourEvent, entity.PurchaseStatusRefunded, 4)
+	uc := newTicketUseCase(t, repo)
+
+	if _, err := uc.CheckInTicket(ownerID, signTicket(t, uc.ticketKey, couple, tourEvent, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	attendance, err := uc.GetTourEventAttendance(ownerID, tourEvent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attendance.Sold != 3 || attendance.CheckedIn != 1 || attendance.Remaining != 2 || attendance.NoShow != 0 {
+		t.Fatalf("before the event: sold %d, checked in %d, remaining %d, no-show %d, want 3, 1, 2 and 0",
+			attendance.Sold, attendance.CheckedIn, attendance.Remaining, attendance.NoShow)
+	}
+	if len(attendance.Tickets) != 3 {
+		t.Errorf("tickets = %d, want one per paid seat", len(attendance.Tickets))
+	}
+
+	tourEvent.Date = time.Now().Add(-time.Hour)
+	attendance, err = uc.GetTourEventAttendance(ownerID, tourEvent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attendance.Sold != 3 || attendance.CheckedIn != 1 || attendance.Remaining != 0 || attendance.NoShow != 2 {
+		t.Fatalf("after the event: sold %d, checked in %d, remaining %d, no-show %d, want 3, 1, 0 and 2",
+			attendance.Sold, attendance.CheckedIn, attendance.Remaining, attendance.NoShow)
+	}
+
+	if _, err := uc.GetTourEventAttendance(uuid.New(), tourEvent.ID); !errors.Is(err, ErrTourEventForbidden) {
+		t.Errorf("GetTourEventAttendance() of another provider error = %v, want ErrTourEventForbidden", err)
+	}
+}
+
+func TestGetPurchaseQRReturnsTicketPerSeat(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(time.Hour), 3)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 3)
+	uc := newTicketUseCase(t, repo)
+
+	tickets, err := uc.GetPurchaseQR(paid.UserID, paid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 3 {
+		t.Fatalf("tickets = %d, want one per place", len(tickets))
+	}
+	for seat, qr := range tickets {
+		if qr.Seat != seat || qr.QRCode == "" {
+			t.Errorf("ticket %d = seat %d with QR code %q, want seat %d with a QR code", seat, qr.Seat, qr.QRCode, seat)
+		}
+		verified, err := ticket.Verify(uc.ticketPublicKey(), qr.Token, time.Now())
+		if err != nil {
+			t.Fatalf("ticket of seat %d: %v", seat, err)
+		}
+		if verified.PurchaseID != paid.ID || verified.Seat != seat {
+			t.Errorf("token of seat %d = %+v, want seat %d of the purchase", seat, verified, seat)
+		}
+	}
+
+	if _, err := uc.GetPurchaseQR(uuid.New(), paid.ID); err == nil {
+		t.Error("GetPurchaseQR() of another user succeeded")
+	}
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrInvalidTicketType      = errors.New("invalid ticket type")
+	ErrInvalidTicketSelection = errors.New("invalid ticket selection")
+)
+
+// validateTicketTypes checks the ticket types of a new tour event.
+func validateTicketTypes(tourEvent *entity.TourEvent) error {
+	names := make(map[string]struct{}, len(tourEvent.TicketTypes))
+	for _, ticketType := range tourEvent.TicketTypes {
+		name := strings.ToLower(strings.TrimSpace(ticketType.Name))
+		if name == "" {
+			return fmt.Errorf("%w: name is empty", ErrInvalidTicketType)
+		}
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("%w: %q is listed twice", ErrInvalidTicketType, ticketType.Name)
+		}
+		names[name] = struct{}{}
+
+		if ticketType.Price < 0 {
+			return fmt.Errorf("%w: price of %q is negative", ErrInvalidTicketType, ticketType.Name)
+		}
+		if ticketType.MaxPlaces < 0 || float64(ticketType.MaxPlaces) > tourEvent.AmountOfPlaces {
+			return fmt.Errorf("%w: max_places of %q must be between 0 and the amount of places", ErrInvalidTicketType, ticketType.Name)
+		}
+	}
+	return nil
+}
+
+// resolveTicketItems checks the ticket types a purchase asks for against its
+// tour event, prices them and sets the purchase quantity to their sum.
+// Purchases of tour events without ticket types keep their quantity and have no items.
+func resolveTicketItems(purchase *entity.Purchase, tourEvent *entity.TourEvent) error {
+	if len(tourEvent.TicketTypes) == 0 {
+		if len(purchase.Items) > 0 {
+			return fmt.Errorf("%w: tour event has no ticket types", ErrInvalidTicketSelection)
+		}
+		return nil
+	}
+	if len(purchase.Items) == 0 {
+		return fmt.Errorf("%w: choose the ticket types to buy", ErrInvalidTicketSelection)
+	}
+
+	ticketTypes := make(map[uuid.UUID]*entity.TicketType, len(tourEvent.TicketTypes))
+	for i := range tourEvent.TicketTypes {
+		ticketTypes[tourEvent.TicketTypes[i].ID] = &tourEvent.TicketTypes[i]
+	}
+
+	items := make([]entity.PurchaseItem, 0, len(purchase.Items))
+	index := make(map[uuid.UUID]int, len(purchase.Items))
+	quantity := 0
+	for _, item := range purchase.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity must be positive", ErrInvalidTicketSelection)
+		}
+		ticketType, ok := ticketTypes[item.TicketTypeID]
+		if !ok {
+			return fmt.Errorf("%w: ticket type %s does not belong to the tour event", ErrInvalidTicketSelection, item.TicketTypeID)
+		}
+		quantity += item.Quantity
+
+		if i, ok := index[item.TicketTypeID]; ok {
+			items[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.TicketTypeID] = len(items)
+		items = append(items, entity.PurchaseItem{
+			TicketTypeID: ticketType.ID,
+			Quantity:     item.Quantity,
+			UnitPrice:    ticketType.Price,
+		})
+	}
+
+	purchase.Items = items
+	purchase.Quantity = quantity
+	return nil
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"tourism-backend/internal/entity"
+)
+
+func newTicketedTourEvent() *entity.TourEvent {
+	return &entity.TourEvent{
+		ID:             uuid.New(),
+		Price:          10000,
+		AmountOfPlaces: 20,
+		TicketTypes: []entity.TicketType{
+			{ID: uuid.New(), Name: "Adult", Price: 10000},
+			{ID: uuid.New(), Name: "Child", Price: 6000, MaxPlaces: 5},
+		},
+	}
+}
+
+func TestResolveTicketItems(t *testing.T) {
+	tourEvent := newTicketedTourEvent()
+	adult, child := tourEvent.TicketTypes[0].ID, tourEvent.TicketTypes[1].ID
+	purchase := &entity.Purchase{
+		TourEventID: tourEvent.ID,
+		Quantity:    1,
+		Items: []entity.PurchaseItem{
+			{TicketTypeID: adult, Quantity: 2},
+			{TicketTypeID: child, Quantity: 1},
+			{TicketTypeID: child, Quantity: 1},
+		},
+	}
+
+	if err := resolveTicketItems(purchase, tourEvent); err != nil {
+		t.Fatal(err)
+	}
+	if purchase.Quantity != 4 {
+		t.Fatalf("quantity = %d, want 4", purchase.Quantity)
+	}
+	if len(purchase.Items) != 2 || purchase.Items[1].Quantity != 2 || purchase.Items[1].UnitPrice != 6000 {
+		t.Fatalf("items = %+v, want adult x2 and child x2 at 6000", purchase.Items)
+	}
+
+	quote := quotePrice(tourEvent, nil, purchase.Items, purchase.Quantity, tourEvent.Date)
+	if quote.Total != 32000 {
+		t.Fatalf("total = %v, want 32000", quote.Total)
+	}
+}
+
+func TestResolveTicketItemsRejectsSelection(t *testing.T) {
+	tests := []struct {
+		name  string
+		items func(tourEvent *entity.TourEvent) []entity.PurchaseItem
+	}{
+		{
+			name:  "no ticket types chosen",
+			items: func(*entity.TourEvent) []entity.PurchaseItem { return nil },
+		},
+		{
+			name: "ticket type of another tour event",
+			items: func(*entity.TourEvent) []entity.PurchaseItem {
+				return []entity.PurchaseItem{{TicketTypeID: uuid.New(), Quantity: 1}}
+			},
+		},
+		{
+			name: "non-positive quantity",
+			items: func(tourEvent *entity.TourEvent) []entity.PurchaseItem {
+				return []entity.PurchaseItem{{TicketTypeID: tourEvent.TicketTypes[0].ID, Quantity: 0}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tourEvent := newTicketedTourEvent()
+			purchase := &entity.Purchase{TourEventID: tourEvent.ID, Items: tt.items(tourEvent)}
+
+			err := resolveTicketItems(purchase, tourEvent)
+			if !errors.Is(err, ErrInvalidTicketSelection) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidTicketSelection)
+			}
+		})
+	}
+}
+
+func TestValidateTicketTypes(t *testing.T) {
+	tourEvent := newTicketedTourEvent()
+	if err := validateTicketTypes(tourEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	tourEvent.TicketTypes = append(tourEvent.TicketTypes, entity.TicketType{Name: "adult ", Price: 9000})
+	if err := validateTicketTypes(tourEvent); !errors.Is(err, ErrInvalidTicketType) {
+		t.Fatalf("err = %v, want duplicate name rejected", err)
+	}
+
+	tourEvent = newTicketedTourEvent()
+	tourEvent.TicketTypes[1].MaxPlaces = 21
+	if err := validateTicketTypes(tourEvent); !errors.Is(err, ErrInvalidTicketType) {
+		t.Fatalf("err = %v, want max_places above the capacity rejected", err)
+	}
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrTourNotFound     = errors.New("tour not found")
+	ErrTourHasPurchases = errors.New("tour has purchases of upcoming events")
+	ErrTourArchived     = errors.New("tour is archived and takes no new purchases")
+)
+
+// UpdateTour changes the fields of a tour set in the request.
+func (t *TourismUseCase) UpdateTour(tourID uuid.UUID, dto *entity.UpdateTourDTO) (*entity.Tour, error) {
+	fields := make(map[string]interface{})
+	if dto.Name != nil {
+		fields["name"] = *dto.Name
+	}
+	if dto.Description != nil {
+		fields["description"] = *dto.Description
+	}
+	if dto.Route != nil {
+		fields["route"] = *dto.Route
+	}
+	if dto.TelegramChatURL != nil {
+		fields["telegram_chat_url"] = *dto.TelegramChatURL
+	}
+	if dto.AirpanoLink != nil {
+		fields["airpano_link"] = *dto.AirpanoLink
+	}
+
+	tour, err := t.repo.UpdateTour(tourID, fields)
+	if err != nil {
+		return nil, err
+	}
+	if tour == nil {
+		return nil, ErrTourNotFound
+	}
+	return tour, nil
+}
+
+// ArchiveTour hides a tour from the listings and stops the sales of its
+// events. Its events and purchases are kept.
+func (t *TourismUseCase) ArchiveTour(tourID uuid.UUID) error {
+	now := time.Now()
+	return t.setTourArchived(tourID, &now)
+}
+
+// RestoreTour lists an archived tour again.
+func (t *TourismUseCase) RestoreTour(tourID uuid.UUID) error {
+	return t.setTourArchived(tourID, nil)
+}
+
+func (t *TourismUseCase) setTourArchived(tourID uuid.UUID, archivedAt *time.Time) error {
+	found, err := t.repo.SetTourArchived(tourID, archivedAt)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrTourNotFound
+	}
+	return nil
+}
+
+// DeleteTour deletes a tour with its events. It is refused while upcoming
+// events have active purchases, unless refund is set: then the upcoming events
+// are cancelled one by one like CancelTourEvent does, refunding their purchases
+// and ending their waitlists. A failed refund stops the deletion with the
+// remaining events untouched, deleting again retries it.
+func (t *TourismUseCase) DeleteTour(tourID uuid.UUID, refund bool) error {
+	now := time.Now()
+	purchases, err := t.repo.GetActiveTourPurchases(tourID, now)
+	if err != nil {
+		return err
+	}
+	if len(purchases) > 0 && !refund {
+		return fmt.Errorf("%w: %d purchases must be cancelled and refunded first", ErrTourHasPurchases, len(purchases))
+	}
+
+	tourEvents, err := t.repo.GetTourEventsByTourID(tourID)
+	if err != nil {
+		return err
+	}
+	for _, tourEvent := range tourEvents {
+		if !tourEvent.Date.After(now) {
+			continue
+		}
+		loaded, err := t.repo.GetTourEventByID(tourEvent.ID)
+		if err != nil {
+			return fmt.Errorf("get tour event %s: %w", tourEvent.ID, err)
+		}
+		if _, err := t.cancelTourEvent(loaded); err != nil {
+			return fmt.Errorf("cancel tour event %s: %w", tourEvent.ID, err)
+		}
+	}
+
+	deleted, err := t.repo.DeleteTour(tourID, now)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTourHasPurchases
+	}
+	return nil
+}
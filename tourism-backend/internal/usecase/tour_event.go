@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrTourEventForbidden = errors.New("you are not the owner of this tour event")
+	ErrInvalidTourEvent   = errors.New("invalid tour event")
+	ErrTourEventClosed    = errors.New("tour event is cancelled or already took place")
+)
+
+// UpdateTourEvent changes the fields of a tour event set in the request.
+// Paid purchasers are told when the date or the place changes, and new places
+// go to the waitlist first. Events of a schedule edited this way are detached
+// from it.
+func (t *TourismUseCase) UpdateTourEvent(providerID, tourEventID uuid.UUID, dto *entity.UpdateTourEventDTO) (*entity.TourEvent, error) {
+	tourEvent, err := t.changeableTourEvent(providerID, tourEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if dto.Date != nil {
+		if !dto.Date.After(time.Now()) {
+			return nil, fmt.Errorf("%w: date must be in the future", ErrInvalidTourEvent)
+		}
+		fields["date"] = *dto.Date
+	}
+	if dto.Price != nil {
+		fields["price"] = *dto.Price
+	}
+	if dto.Place != nil {
+		fields["place"] = *dto.Place
+	}
+	if dto.InstaPostURL != nil {
+		fields["insta_post_url"] = *dto.InstaPostURL
+	}
+
+	ok, err := t.repo.UpdateTourEvent(tourEventID, fields, dto.AmountOfPlaces)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: amount_of_places is below the places already sold", ErrInvalidTourEvent)
+	}
+
+	updated, err := t.repo.GetTourEventByID(tourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("update tour event: %w", err)
+	}
+	if !updated.Date.Equal(tourEvent.Date) || updated.Place != tourEvent.Place {
+		t.notifyTourEventChange(updated)
+	}
+	if updated.AmountOfPlaces > tourEvent.AmountOfPlaces {
+		t.offerFreedSeats(tourEventID)
+	}
+	return updated, nil
+}
+
+// CancelTourEvent closes a tour event for good, ends its waitlist, cancels and
+// refunds all of its purchases and tells every purchaser and person in line.
+// A refund that fails is retried by cancelling the tour event again, the
+// purchaser is told once the refund went through.
+func (t *TourismUseCase) CancelTourEvent(providerID, tourEventID uuid.UUID) (*entity.TourEvent, error) {
+	tourEvent, err := t.repo.GetTourEventByID(tourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel tour event: %w", err)
+	}
+	if tourEvent.Tour.OwnerID != providerID {
+		return nil, ErrTourEventForbidden
+	}
+	if tourEvent.CancelledAt == nil && !tourEvent.Date.After(time.Now()) {
+		return nil, ErrTourEventClosed
+	}
+	return t.cancelTourEvent(tourEvent)
+}
+
+// cancelTourEvent cancels a tour event the caller is allowed to cancel.
+func (t *TourismUseCase) cancelTourEvent(tourEvent *entity.TourEvent) (*entity.TourEvent, error) {
+	if tourEvent.CancelledAt == nil {
+		now := time.Now()
+		ended, err := t.repo.CancelTourEvent(tourEvent.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		tourEvent.IsOpened = false
+		tourEvent.CancelledAt = &now
+
+		for _, entry := range ended {
+			t.publishWaitlistMessage(entry, fmt.Sprintf("%s on %s has been cancelled, you are no longer on its waitlist",
+				tourEvent.Tour.Name, tourEvent.Date.Format(time.RFC3339)))
+		}
+	}
+
+	purchases, err := t.repo.GetActiveTourEventPurchases(tourEvent.ID)
+	if err != nil {
+		return nil, err
+	}
+	var recipients []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(purchases))
+	failed := 0
+	for _, purchase := range purchases {
+		if err := t.refundPurchase(purchase); err != nil {
+			log.Printf("Cancel purchase %s of tour event %s: %v", purchase.ID, tourEvent.ID, err)
+			failed++
+			continue
+		}
+		if !seen[purchase.UserID] {
+			seen[purchase.UserID] = true
+			recipients = append(recipients, purchase.UserID)
+		}
+	}
+
+	if len(recipients) > 0 {
+		t.PublishMessage("notifications", entity.Notification{
+			Topic: "TOUR_EVENT",
+			Data: map[string]interface{}{
+				"Text": fmt.Sprintf("%s on %s has been cancelled, your purchase is refunded",
+					tourEvent.Tour.Name, tourEvent.Date.Format(time.RFC3339)),
+				"TourEvent": tourEvent,
+			},
+			Recipients: recipients,
+		})
+	}
+	if failed > 0 {
+		return nil, fmt.Errorf("cancel tour event: %d of %d refunds failed, cancel again to retry", failed, len(purchases))
+	}
+	return tourEvent, nil
+}
+
+// changeableTourEvent returns a tour event of the provider that is neither
+// cancelled nor over.
+func (t *TourismUseCase) changeableTourEvent(providerID, tourEventID uuid.UUID) (*entity.TourEvent, error) {
+	tourEvent, err := t.repo.GetTourEventByID(tourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("get tour event: %w", err)
+	}
+	if tourEvent.Tour.OwnerID != providerID {
+		return nil, ErrTourEventForbidden
+	}
+	if tourEvent.CancelledAt != nil || !tourEvent.Date.After(time.Now()) {
+		return nil, ErrTourEventClosed
+	}
+	return tourEvent, nil
+}
+
+func (t *TourismUseCase) notifyTourEventChange(tourEvent *entity.TourEvent) {
+	recipients, err := t.repo.GetPaidPurchaserIDs(tourEvent.ID)
+	if err != nil {
+		log.Printf("Notify purchasers of tour event %s: %v", tourEvent.ID, err)
+		return
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	t.PublishMessage("notifications", entity.Notification{
+		Topic: "TOUR_EVENT",
+		Data: map[string]interface{}{
+			"Text": fmt.Sprintf("%s now takes place on %s at %s",
+				tourEvent.Tour.Name, tourEvent.Date.Format(time.RFC3339), tourEvent.Place),
+			"TourEvent": tourEvent,
+		},
+		Recipients: recipients,
+	})
+}
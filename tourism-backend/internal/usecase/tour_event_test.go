@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestUpdateTourEventNotifiesPurchasersOfNewDateOnly(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(48*time.Hour), 3)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc, _, producer := newTestUseCase(repo)
+
+	price := 15000.0
+	if _, err := uc.UpdateTourEvent(ownerID, tourEvent.ID, &entity.UpdateTourEventDTO{Price: &price}); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.notifications) != 0 {
+		t.Fatalf("notifications = %v, want none for a new price", producer.topics())
+	}
+
+	date := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	updated, err := uc.UpdateTourEvent(ownerID, tourEvent.ID, &entity.UpdateTourEventDTO{Date: &date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Date.Equal(date) || updated.Price != price {
+		t.Fatalf("tour event = %s at %v, want %s at %v", updated.Date, updated.Price, date, price)
+	}
+	if topics := producer.topics(); len(topics) != 1 || topics[0] != "TOUR_EVENT" {
+		t.Fatalf("notifications = %v, want one TOUR_EVENT", topics)
+	}
+	if recipients := producer.notifications[0].Recipients; len(recipients) != 1 || recipients[0] != paid.UserID {
+		t.Errorf("recipients = %v, want the purchaser", recipients)
+	}
+}
+
+func TestUpdateTourEventRejects(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(48*time.Hour), 3)
+	repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	past := repo.addTourEvent(ownerID, time.Now().Add(-time.Hour), 3)
+	uc, _, _ := newTestUseCase(repo)
+
+	tooFew, yesterday := 1.0, time.Now().Add(-24*time.Hour)
+	tests := []struct {
+		name        string
+		providerID  uuid.UUID
+		tourEventID uuid.UUID
+		dto         entity.UpdateTourEventDTO
+		want        error
+	}{
+		{name: "places below the sold ones", providerID: ownerID, tourEventID: tourEvent.ID, dto: entity.UpdateTourEventDTO{AmountOfPlaces: &tooFew}, want: ErrInvalidTourEvent},
+		{name: "date in the past", providerID: ownerID, tourEventID: tourEvent.ID, dto: entity.UpdateTourEventDTO{Date: &yesterday}, want: ErrInvalidTourEvent},
+		{name: "other provider", providerID: uuid.New(), tourEventID: tourEvent.ID, want: ErrTourEventForbidden},
+		{name: "tour event over", providerID: ownerID, tourEventID: past.ID, want: ErrTourEventClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.UpdateTourEvent(tt.providerID, tt.tourEventID, &tt.dto); !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateTourEvent() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if places := repo.tourEvents[tourEvent.ID].AmountOfPlaces; places != 3 {
+		t.Errorf("amount of places = %v, want 3 unchanged", places)
+	}
+}
+
+func TestCancelTourEventRefundsPurchasesAndEndsWaitlist(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(48*time.Hour), 0)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	processing := repo.addPurchase(tourEvent, entity.PurchaseStatusProcessing, 1)
+	waiting := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 1)
+	offered := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusOffered, 1)
+	uc, refunder, producer := newTestUseCase(repo)
+
+	cancelled, err := uc.CancelTourEvent(ownerID, tourEvent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.CancelledAt == nil || cancelled.IsOpened {
+		t.Fatalf("tour event is not cancelled: %+v", cancelled)
+	}
+	for _, purchase := range []*entity.Purchase{paid, processing} {
+		if status := repo.purchases[purchase.ID].Status; status != entity.PurchaseStatusRefunded {
+			t.Errorf("purchase status = %s, want Refunded", status)
+		}
+	}
+	if len(refunder.refunded) != 2 {
+		t.Errorf("refunded = %v, want both purchases", refunder.refunded)
+	}
+	for _, entry := range []*entity.WaitlistEntry{waiting, offered} {
+		if status := repo.waitlist[entry.ID].Status; status != entity.WaitlistStatusExpired {
+			t.Errorf("waitlist status = %s, want Expired", status)
+		}
+	}
+
+	// Purchasers hear about the cancellation once, without a separate refund message.
+	counts := make(map[string]int)
+	for _, topic := range producer.topics() {
+		counts[topic]++
+	}
+	if counts["TOUR_EVENT"] != 1 || counts["WAITLIST"] != 2 || counts["PAYMENT"] != 0 {
+		t.Fatalf("notifications = %v, want one TOUR_EVENT and two WAITLIST", producer.topics())
+	}
+	for _, notification := range producer.notifications {
+		if notification.Topic == "TOUR_EVENT" && len(notification.Recipients) != 2 {
+			t.Errorf("TOUR_EVENT recipients = %v, want both purchasers", notification.Recipients)
+		}
+	}
+
+	if _, err := uc.ClaimWaitlistOffer(offered.UserID, offered.ID, nil); !errors.Is(err, ErrWaitlistOfferUnavailable) {
+		t.Errorf("ClaimWaitlistOffer() error = %v, want ErrWaitlistOfferUnavailable", err)
+	}
+}
+
+func TestCancelTourEventRetriesFailedRefunds(t *testing.T) {
+	repo := newFakeRepo()
+	ownerID := uuid.New()
+	tourEvent := repo.addTourEvent(ownerID, time.Now().Add(48*time.Hour), 1)
+	paid := repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	uc, refunder, producer := newTestUseCase(repo)
+
+	refunder.err = errors.New("card issuer unavailable")
+	if _, err := uc.CancelTourEvent(ownerID, tourEvent.ID); err == nil {
+		t.Fatal("CancelTourEvent() succeeded with a failed refund")
+	}
+	if status := repo.purchases[paid.ID].Status; status != entity.PurchaseStatusCancelRequested {
+		t.Fatalf("purchase status = %s, want CancelRequested", status)
+	}
+	if repo.tourEvents[tourEvent.ID].CancelledAt == nil {
+		t.Fatal("tour event is not cancelled")
+	}
+	if len(producer.notifications) != 0 {
+		t.Fatalf("notifications = %v, want none before the refund", producer.topics())
+	}
+
+	refunder.err = nil
+	if _, err := uc.CancelTourEvent(ownerID, tourEvent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status := repo.purchases[paid.ID].Status; status != entity.PurchaseStatusRefunded {
+		t.Fatalf("purchase status = %s, want Refunded", status)
+	}
+	if topics := producer.topics(); len(topics) != 1 || topics[0] != "TOUR_EVENT" {
+		t.Fatalf("notifications = %v, want one TOUR_EVENT", topics)
+	}
+}
+
+func TestClaimWaitlistOfferOfClosedTourEvent(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	offered := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusOffered, 1)
+	tourEvent.IsOpened = false
+	uc, _, _ := newTestUseCase(repo)
+
+	if _, err := uc.ClaimWaitlistOffer(offered.UserID, offered.ID, nil); !errors.Is(err, ErrWaitlistOfferUnavailable) {
+		t.Fatalf("ClaimWaitlistOffer() error = %v, want ErrWaitlistOfferUnavailable", err)
+	}
+}
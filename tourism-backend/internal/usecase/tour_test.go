@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// addTourWithEvents stores a tour with an upcoming event on each of the next
+// days, each with a paid purchase.
+func addTourWithEvents(repo *fakeRepo, days int) []*entity.TourEvent {
+	var tourEvents []*entity.TourEvent
+	for day := 1; day <= days; day++ {
+		tourEvent := repo.addTourEvent(uuid.New(), time.Now().AddDate(0, 0, day), 1)
+		if day > 1 {
+			tourEvent.Tour, tourEvent.TourID = tourEvents[0].Tour, tourEvents[0].TourID
+		}
+		repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+		tourEvents = append(tourEvents, tourEvent)
+	}
+	return tourEvents
+}
+
+func TestDeleteTourRefusesPurchasesWithoutRefund(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvents := addTourWithEvents(repo, 2)
+	uc, refunder, _ := newTestUseCase(repo)
+
+	if err := uc.DeleteTour(tourEvents[0].TourID, false); !errors.Is(err, ErrTourHasPurchases) {
+		t.Fatalf("DeleteTour() error = %v, want ErrTourHasPurchases", err)
+	}
+	if repo.deleted[tourEvents[0].TourID] || len(refunder.refunded) != 0 {
+		t.Fatal("tour was deleted or refunded")
+	}
+	for _, tourEvent := range tourEvents {
+		if !tourEvent.IsOpened || tourEvent.CancelledAt != nil {
+			t.Errorf("tour event %s was closed", tourEvent.ID)
+		}
+	}
+}
+
+func TestDeleteTourCancelsEventsAndRefunds(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvents := addTourWithEvents(repo, 2)
+	waiting := repo.addWaitlistEntry(tourEvents[1], entity.WaitlistStatusWaiting, 1)
+	uc, refunder, producer := newTestUseCase(repo)
+
+	if err := uc.DeleteTour(tourEvents[0].TourID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.deleted[tourEvents[0].TourID] {
+		t.Fatal("tour was not deleted")
+	}
+	if len(refunder.refunded) != 2 {
+		t.Errorf("refunded = %v, want both purchases", refunder.refunded)
+	}
+	for _, tourEvent := range tourEvents {
+		if tourEvent.IsOpened || tourEvent.CancelledAt == nil {
+			t.Errorf("tour event %s was not cancelled", tourEvent.ID)
+		}
+	}
+	if status := repo.waitlist[waiting.ID].Status; status != entity.WaitlistStatusExpired {
+		t.Errorf("waitlist status = %s, want Expired", status)
+	}
+	counts := make(map[string]int)
+	for _, topic := range producer.topics() {
+		counts[topic]++
+	}
+	if counts["TOUR_EVENT"] != 2 || counts["WAITLIST"] != 1 || counts["PAYMENT"] != 0 {
+		t.Errorf("notifications = %v, want two TOUR_EVENT and one WAITLIST", producer.topics())
+	}
+}
+
+func TestDeleteTourStopsAtFailedRefund(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvents := addTourWithEvents(repo, 2)
+	uc, refunder, _ := newTestUseCase(repo)
+
+	refunder.err = errors.New("card issuer unavailable")
+	if err := uc.DeleteTour(tourEvents[0].TourID, true); err == nil {
+		t.Fatal("DeleteTour() succeeded with a failed refund")
+	}
+	if repo.deleted[tourEvents[0].TourID] {
+		t.Fatal("tour was deleted")
+	}
+	// The first event is cancelled for good, the second one is still on sale.
+	if first := tourEvents[0]; first.IsOpened || first.CancelledAt == nil {
+		t.Errorf("first tour event is not cancelled: opened %v, cancelled at %v", first.IsOpened, first.CancelledAt)
+	}
+	if second := tourEvents[1]; !second.IsOpened || second.CancelledAt != nil {
+		t.Errorf("second tour event was closed: opened %v, cancelled at %v", second.IsOpened, second.CancelledAt)
+	}
+
+	refunder.err = nil
+	if err := uc.DeleteTour(tourEvents[0].TourID, true); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.deleted[tourEvents[0].TourID] || len(refunder.refunded) != 2 {
+		t.Fatalf("deleted = %v, refunded = %v, want the tour deleted and both purchases refunded", repo.deleted[tourEvents[0].TourID], refunder.refunded)
+	}
+}
+
+func TestCreatePurchaseOfArchivedTour(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 3)
+	archivedAt := time.Now()
+	tourEvent.Tour.ArchivedAt = &archivedAt
+	uc, _, _ := newTestUseCase(repo)
+
+	purchase := &entity.Purchase{TourEventID: tourEvent.ID, UserID: uuid.New(), Quantity: 1}
+	if _, err := uc.CreatePurchase(purchase, ""); !errors.Is(err, ErrTourArchived) {
+		t.Fatalf("CreatePurchase() error = %v, want ErrTourArchived", err)
+	}
+}
@@ -1,27 +1,35 @@
 package usecase
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
 	"log"
 	"mime/multipart"
+	"time"
+	"tourism-backend/config"
 	"tourism-backend/internal/entity"
-	"tourism-backend/internal/usecase/repo"
 	"tourism-backend/utils"
 )
 
 // TranslationUseCase -.
 type TourismUseCase struct {
-	repo     *repo.TourismRepo
-	producer sarama.SyncProducer
+	repo        TourismRepo
+	producer    sarama.SyncProducer
+	refunder    PaymentRefunder
+	purchaseCfg config.Purchase
+	uploadCfg   config.Upload
+	scheduleCfg config.Schedule
+	ticketCfg   config.Ticket
+	ticketKey   ed25519.PrivateKey
 	//telegram *client.Client
 }
 
 // // NewTourismUseCase -.
 //
-//	func NewTourismUseCase(r *repo.TourismRepo, p sarama.SyncProducer, t *client.Client) *TourismUseCase {
+//	func NewTourismUseCase(r TourismRepo, p sarama.SyncProducer, t *client.Client) *TourismUseCase {
 //		return &TourismUseCase{
 //			repo:     r,
 //			producer: p,
@@ -30,40 +38,23 @@ type TourismUseCase struct {
 //	}
 //
 // NewTourismUseCase -.
-func NewTourismUseCase(r *repo.TourismRepo, p sarama.SyncProducer) *TourismUseCase {
+func NewTourismUseCase(r TourismRepo, p sarama.SyncProducer, refunder PaymentRefunder, purchaseCfg config.Purchase, uploadCfg config.Upload, scheduleCfg config.Schedule, ticketCfg config.Ticket, ticketKey ed25519.PrivateKey) *TourismUseCase {
 	return &TourismUseCase{
-		repo:     r,
-		producer: p,
+		repo:        r,
+		producer:    p,
+		refunder:    refunder,
+		purchaseCfg: purchaseCfg,
+		uploadCfg:   uploadCfg,
+		scheduleCfg: scheduleCfg,
+		ticketCfg:   ticketCfg,
+		ticketKey:   ticketKey,
 	}
 }
 
 func (r *TourismUseCase) GetTourEventsByTourID(tourID uuid.UUID) ([]*entity.TourEvent, error) {
 	return r.repo.GetTourEventsByTourID(tourID)
 }
-func (r *TourismUseCase) CheckPurchase(userID, purchaseID uuid.UUID) (*entity.Purchase, error) {
-	result, err := r.repo.CheckPurchase(userID, purchaseID)
-	if err != nil {
-		log.Println("CheckPurchase err:", err)
-		return nil, fmt.Errorf("check purchase error: %w", err)
-	}
-	if result == nil {
-		log.Println("CheckPurchase result is nil")
-		return nil, fmt.Errorf("you are not the owner of this tour event")
-	}
-	return result, nil
-}
-
-func (r *TourismUseCase) GetPurchaseQR(userID, purchaseID uuid.UUID) (*entity.PurchaseQRDTO, error) {
-	_, err := r.repo.GetPurchaseQR(userID, purchaseID)
-	if err != nil {
-		log.Println("GetPurchaseQR err:", err)
-		return nil, err
-	}
-	result := utils.GenerateQRCode(purchaseID)
-	return result, nil
-}
-
-func (r *TourismUseCase) SaveMyAvatar(userID uuid.UUID, avatar string) error {
+func (r *TourismUseCase) SaveMyAvatar(userID uuid.UUID, avatar *multipart.FileHeader) (string, error) {
 	return r.repo.SaveMyAvatar(userID, avatar)
 }
 
@@ -79,16 +70,13 @@ func (r *TourismUseCase) LikeTour(userID uuid.UUID, tourID uuid.UUID) (*entity.U
 	return r.repo.LikeTour(userID, tourID)
 }
 
+// GetMe returns the user with the first page of their purchases.
 func (r *TourismUseCase) GetMe(id uuid.UUID) (*entity.User, error) {
-	return r.repo.GetMe(id)
+	return r.repo.GetMe(id, _defaultPageLimit)
 }
 
-func (r *TourismUseCase) ChangeTour(tour *entity.Tour) (*entity.Tour, error) {
-	return r.repo.ChangeTour(tour)
-}
-
-func (r *TourismUseCase) AddFilesToTourByTourID(panoramaEntity []*entity.Panorama) ([]*entity.Panorama, error) {
-	return r.repo.AddFileToTourByTourID(panoramaEntity)
+func (r *TourismUseCase) AddFilesToTourByTourID(tourID uuid.UUID, panoramas []*multipart.FileHeader) ([]*entity.Panorama, error) {
+	return r.repo.AddFileToTourByTourID(tourID, panoramas)
 }
 
 func (r *TourismUseCase) GetTourEventByID(id uuid.UUID) (*entity.TourEvent, error) {
@@ -104,15 +92,25 @@ func (r *TourismUseCase) GetWeatherByTourEventID(tourEventID uuid.UUID) (*entity
 	return result, nil
 }
 
-func (r *TourismUseCase) GetFilteredTourEvents(filter *entity.TourEventFilter) ([]*entity.TourEvent, error) {
-	return r.repo.GetFilteredTourEvents(filter)
+func (r *TourismUseCase) GetFilteredTourEvents(filter *entity.TourEventFilter, page *entity.PageQuery) (*entity.TourEventPage, error) {
+	if err := preparePage(page, _tourEventSorts); err != nil {
+		return nil, err
+	}
+	tourEvents, next, err := r.repo.GetFilteredTourEvents(filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.TourEventPage{TourEvents: tourEvents, NextCursor: encodeCursor(next)}, nil
 }
 
-func (r *TourismUseCase) GetTourLocationByID(tourLocationID uuid.UUID) (*entity.TourLocation, error) {
-	return r.repo.GetTourLocationByID(tourLocationID)
+func (r *TourismUseCase) GetTourLocationByTourID(tourID uuid.UUID) (*entity.TourLocation, error) {
+	return r.repo.GetTourLocationByTourID(tourID)
 }
 
 func (r *TourismUseCase) CreateTourLocation(tourLocation *entity.CreateTourLocationDTO) (*entity.TourLocation, error) {
+	if !validLatitude(tourLocation.Latitude) || !validLongitude(tourLocation.Longitude) {
+		return nil, ErrInvalidLocation
+	}
 	return r.repo.CreateTourLocation(tourLocation)
 }
 
@@ -128,14 +126,61 @@ func (r *TourismUseCase) GetAllCategories() ([]entity.Category, error) {
 	return categories, nil
 }
 
-func (t *TourismUseCase) CreatePurchase(purchase *entity.Purchase) (*entity.Purchase, error) {
-	return t.repo.CreatePurchase(purchase)
+// CreatePurchase prices a purchase with the ticket types and pricing rules of
+// its tour event, holds its seats and queues its payment. An empty promoCode
+// means no promo code is applied.
+func (t *TourismUseCase) CreatePurchase(purchase *entity.Purchase, promoCode string) (*entity.Purchase, error) {
+	tourEvent, err := t.repo.GetTourEventByID(purchase.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("create purchase: %w", err)
+	}
+	if tourEvent.Tour.ArchivedAt != nil {
+		return nil, ErrTourArchived
+	}
+	if err := resolveTicketItems(purchase, tourEvent); err != nil {
+		return nil, err
+	}
+	if err := t.pricePurchase(purchase, tourEvent); err != nil {
+		return nil, err
+	}
+
+	var promo *entity.PromoCode
+	if promoCode != "" {
+		promo, err = t.findPromoCode(purchase.UserID, promoCode, tourEvent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	holdExpiresAt := time.Now().Add(t.purchaseCfg.SeatHoldTTL)
+	purchase.HoldExpiresAt = &holdExpiresAt
+	created, err := t.repo.CreatePurchase(purchase, promo)
+	if err != nil {
+		return nil, err
+	}
+	return t.payFreePurchase(created)
+}
+
+// payFreePurchase marks a processing purchase that discounts made free as Paid.
+// There is nothing to charge, and gateways reject charges of zero.
+func (t *TourismUseCase) payFreePurchase(purchase *entity.Purchase) (*entity.Purchase, error) {
+	if !purchase.IsFree() || purchase.Status != entity.PurchaseStatusProcessing {
+		return purchase, nil
+	}
+	if err := t.PayTourEvent(purchase); err != nil {
+		return nil, err
+	}
+	return purchase, nil
 }
 
 func (t *TourismUseCase) PayTourEvent(purchase *entity.Purchase) error {
+	if err := checkPurchaseTransition(purchase.Status, entity.PurchaseStatusPaid); err != nil {
+		return err
+	}
+
 	result := t.repo.PayTourEvent(purchase)
 	if result == nil {
-		log.Printf("Error paying tour event: ", result)
+		log.Printf("Error paying tour event: %s", purchase.ID)
 		return fmt.Errorf("Error paying tour event")
 	}
 
@@ -168,6 +213,10 @@ func (t *TourismUseCase) CreateTourEvent(tourEvent *entity.TourEvent) (*entity.T
 	//}
 	//tourEvent.TelegramChatURL = strconv.FormatInt(chat.ChatId, 10)
 
+	if err := validateTicketTypes(tourEvent); err != nil {
+		return nil, err
+	}
+
 	result, err := t.repo.CreateTourEvent(tourEvent)
 	if err != nil {
 		return nil, fmt.Errorf("create tour event: %w", err)
@@ -190,15 +239,31 @@ func (t *TourismUseCase) GetTourByID(id string) (*entity.Tour, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := t.setTourRatings([]*entity.Tour{tour}); err != nil {
+		return nil, err
+	}
+	routeMap, err := t.GetTourRouteMap(tour.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(routeMap.Features) > 0 {
+		tour.RouteMap = routeMap
+	}
 	return tour, nil
 }
 
-func (t *TourismUseCase) GetTours() ([]entity.Tour, error) {
-	tours, err := t.repo.GetTours()
+func (t *TourismUseCase) GetTours(page *entity.PageQuery) (*entity.TourPage, error) {
+	if err := preparePage(page, _tourSorts); err != nil {
+		return nil, err
+	}
+	tours, next, err := t.repo.GetTours(page)
 	if err != nil {
 		return nil, err
 	}
-	return tours, nil
+	if err := t.setTourRatings(tours); err != nil {
+		return nil, err
+	}
+	return &entity.TourPage{Tours: tours, NextCursor: encodeCursor(next)}, nil
 }
 
 func (t *TourismUseCase) PublishMessage(topic string, value interface{}) {
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"tourism-backend/internal/entity"
+	"tourism-backend/pkg/imaging"
+	"tourism-backend/pkg/media"
+)
+
+var (
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrUploadForbidden        = errors.New("you are not the owner of this upload")
+	ErrInvalidUpload          = errors.New("invalid upload")
+	ErrUploadClosed           = errors.New("upload is no longer open")
+	ErrUploadOffsetMismatch   = errors.New("upload offset does not match the received data")
+	ErrUploadChecksumMismatch = errors.New("upload checksum mismatch")
+)
+
+// CreateUpload opens a resumable upload of a video or panorama of a tour. The
+// file is sent in chunks with AppendUpload and attached to the tour once all
+// of its bytes arrived.
+func (t *TourismUseCase) CreateUpload(userID, tourID uuid.UUID, dto *entity.CreateUploadDTO) (*entity.UploadSession, error) {
+	var maxSize int64
+	switch dto.Kind {
+	case entity.UploadKindVideo:
+		maxSize = t.uploadCfg.MaxVideoSize
+	case entity.UploadKindPanorama:
+		maxSize = t.uploadCfg.MaxPanoramaSize
+	default:
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidUpload, entity.UploadKindVideo, entity.UploadKindPanorama)
+	}
+	if dto.Size <= 0 || dto.Size > maxSize {
+		return nil, fmt.Errorf("%w: a %s has between 1 and %d bytes", ErrInvalidUpload, dto.Kind, maxSize)
+	}
+	checksum := strings.ToLower(dto.Checksum)
+	if decoded, err := hex.DecodeString(checksum); checksum != "" && (err != nil || len(decoded) != sha256.Size) {
+		return nil, fmt.Errorf("%w: checksum must be the SHA-256 of the file in hex", ErrInvalidUpload)
+	}
+
+	session := &entity.UploadSession{
+		TourID:    tourID,
+		OwnerID:   userID,
+		Kind:      dto.Kind,
+		Filename:  dto.Filename,
+		Size:      dto.Size,
+		Checksum:  checksum,
+		Status:    entity.UploadStatusOpen,
+		ExpiresAt: time.Now().Add(t.uploadCfg.SessionTTL),
+	}
+	if err := t.repo.CreateUploadSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (t *TourismUseCase) GetUpload(userID, uploadID uuid.UUID) (*entity.UploadSession, error) {
+	session, err := t.repo.GetUploadSession(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrUploadNotFound
+	}
+	if session.OwnerID != userID {
+		return nil, ErrUploadForbidden
+	}
+	return session, nil
+}
+
+// AppendUpload adds the chunk in body at offset, which must be the offset the
+// session is at. checksum is an optional "sha256 <base64 digest>" of the chunk.
+// The upload completes with its last chunk, an empty chunk at the end of the
+// file retries a completion that failed.
+func (t *TourismUseCase) AppendUpload(userID, uploadID uuid.UUID, offset int64, checksum string, body io.Reader) (*entity.UploadSession, error) {
+	session, err := t.GetUpload(userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != entity.UploadStatusOpen || time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadClosed
+	}
+	if offset != session.Offset {
+		return nil, fmt.Errorf("%w: the upload is at offset %d", ErrUploadOffsetMismatch, session.Offset)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, t.uploadCfg.MaxChunkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read upload chunk: %w", err)
+	}
+	if int64(len(data)) > t.uploadCfg.MaxChunkSize {
+		return nil, fmt.Errorf("%w: a chunk has at most %d bytes", ErrInvalidUpload, t.uploadCfg.MaxChunkSize)
+	}
+	if offset+int64(len(data)) > session.Size {
+		return nil, fmt.Errorf("%w: the chunk goes past the %d bytes of the file", ErrInvalidUpload, session.Size)
+	}
+	if checksum != "" {
+		if err := verifyChunkChecksum(checksum, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) > 0 {
+		appended, err := t.repo.AppendUploadChunk(session, offset, data)
+		if err != nil {
+			return nil, err
+		}
+		if !appended {
+			return nil, ErrUploadOffsetMismatch
+		}
+	} else if session.Offset < session.Size {
+		return nil, fmt.Errorf("%w: the chunk is empty", ErrInvalidUpload)
+	}
+
+	if session.Offset == session.Size {
+		if err := t.completeUpload(session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// completeUpload verifies the checksum of the file of an upload session and
+// attaches the file to the tour. Refused files fail the session.
+func (t *TourismUseCase) completeUpload(session *entity.UploadSession) error {
+	if session.Checksum != "" {
+		data, err := t.repo.OpenUpload(session.ID)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, data)
+		data.Close()
+		if err != nil {
+			return fmt.Errorf("read upload: %w", err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != session.Checksum {
+			if err := t.repo.FailUpload(session, ErrUploadChecksumMismatch.Error()); err != nil {
+				return err
+			}
+			return ErrUploadChecksumMismatch
+		}
+	}
+
+	attached, err := t.repo.AttachUpload(session)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooLarge) || errors.Is(err, media.ErrUnsupportedType) {
+		if err := t.repo.FailUpload(session, err.Error()); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !attached {
+		return ErrUploadClosed
+	}
+	return nil
+}
+
+// verifyChunkChecksum checks a chunk against a checksum in the format of the
+// Upload-Checksum header of tus, the algorithm and the base64 digest.
+func verifyChunkChecksum(checksum string, data []byte) error {
+	algorithm, encoded, ok := strings.Cut(checksum, " ")
+	if !ok || algorithm != "sha256" {
+		return fmt.Errorf("%w: checksum must be \"sha256 <base64 digest>\"", ErrInvalidUpload)
+	}
+	want, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: checksum digest is not base64", ErrInvalidUpload)
+	}
+	got := sha256.Sum256(data)
+	if !bytes.Equal(got[:], want) {
+		return ErrUploadChecksumMismatch
+	}
+	return nil
+}
+
+// CancelUpload drops an upload session with the chunks received so far.
+func (t *TourismUseCase) CancelUpload(userID, uploadID uuid.UUID) error {
+	session, err := t.GetUpload(userID, uploadID)
+	if err != nil {
+		return err
+	}
+	return t.repo.DeleteUploadSession(session)
+}
+
+// ExpireUploadSessions drops open upload sessions past their expiry with their
+// chunks, returning how many were dropped.
+func (t *TourismUseCase) ExpireUploadSessions() (int, error) {
+	sessions, err := t.repo.GetExpiredUploadSessions(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if err := t.repo.DeleteUploadSession(session); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// RunUploadSweeper drops expired upload sessions every interval until ctx is done.
+func (t *TourismUseCase) RunUploadSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := t.ExpireUploadSessions()
+		if err != nil {
+			log.Printf("Upload sweeper error: %v", err)
+		} else if expired > 0 {
+			log.Printf("Upload sweeper dropped %d expired uploads", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
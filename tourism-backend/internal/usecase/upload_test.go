@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestVerifyChunkChecksum(t *testing.T) {
+	chunk := []byte("chunk of a tour video")
+	sum := sha256.Sum256(chunk)
+	valid := "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     error
+	}{
+		{name: "matching digest", checksum: valid},
+		{name: "other digest", checksum: "sha256 " + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)), want: ErrUploadChecksumMismatch},
+		{name: "unsupported algorithm", checksum: "md5 " + base64.StdEncoding.EncodeToString(sum[:16]), want: ErrInvalidUpload},
+		{name: "digest without algorithm", checksum: base64.StdEncoding.EncodeToString(sum[:]), want: ErrInvalidUpload},
+		{name: "digest not base64", checksum: "sha256 !!!", want: ErrInvalidUpload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyChunkChecksum(tt.checksum, chunk); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+var (
+	ErrWaitlistForbidden        = errors.New("you are not allowed to manage this waitlist entry")
+	ErrWaitlistNotNeeded        = errors.New("tour event still has enough places, purchase it directly")
+	ErrAlreadyWaitlisted        = errors.New("you are already on the waitlist of this tour event")
+	ErrWaitlistOfferUnavailable = errors.New("waitlist entry has no open offer")
+	ErrWaitlistTooLarge         = errors.New("tour event has fewer places than requested")
+)
+
+// JoinWaitlist puts the user in line for a tour event without enough free places.
+// Nobody can wait for more places than the tour event has in total.
+func (t *TourismUseCase) JoinWaitlist(userID uuid.UUID, dto *entity.JoinWaitlistDTO) (*entity.WaitlistEntry, error) {
+	tourEvent, err := t.repo.GetTourEventByID(dto.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("join waitlist: %w", err)
+	}
+	if !tourEvent.IsOpened {
+		return nil, fmt.Errorf("tour event is closed")
+	}
+	if tourEvent.AmountOfPlaces >= float64(dto.Quantity) {
+		return nil, ErrWaitlistNotNeeded
+	}
+	held, err := t.repo.GetHeldSeats(dto.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("join waitlist: %w", err)
+	}
+	if capacity := tourEvent.AmountOfPlaces + held; float64(dto.Quantity) > capacity {
+		return nil, fmt.Errorf("%w: it has %v places", ErrWaitlistTooLarge, capacity)
+	}
+
+	active, err := t.repo.GetActiveWaitlistEntry(userID, dto.TourEventID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, ErrAlreadyWaitlisted
+	}
+
+	return t.repo.CreateWaitlistEntry(&entity.WaitlistEntry{
+		TourEventID: dto.TourEventID,
+		UserID:      userID,
+		Quantity:    dto.Quantity,
+		Status:      entity.WaitlistStatusWaiting,
+	})
+}
+
+func (t *TourismUseCase) GetMyWaitlist(userID uuid.UUID) ([]*entity.WaitlistEntry, error) {
+	return t.repo.GetWaitlistEntriesByUserID(userID)
+}
+
+// LeaveWaitlist takes the user out of line. Places of an open offer go to the next person.
+func (t *TourismUseCase) LeaveWaitlist(userID, entryID uuid.UUID) error {
+	entry, err := t.repo.GetWaitlistEntryByID(entryID)
+	if err != nil {
+		return fmt.Errorf("leave waitlist: %w", err)
+	}
+	if entry.UserID != userID {
+		return ErrWaitlistForbidden
+	}
+
+	switch entry.Status {
+	case entity.WaitlistStatusWaiting:
+		return t.repo.UpdateWaitlistStatus(entryID, entity.WaitlistStatusWaiting, entity.WaitlistStatusLeft)
+	case entity.WaitlistStatusOffered:
+		if err := t.repo.ReleaseWaitlistOffer(entryID, entity.WaitlistStatusLeft); err != nil {
+			return fmt.Errorf("leave waitlist: %w", err)
+		}
+		t.offerFreedSeats(entry.TourEventID)
+		return nil
+	default:
+		return fmt.Errorf("waitlist entry is already %s", entry.Status)
+	}
+}
+
+// ClaimWaitlistOffer buys the places offered to the user. For tour events with
+// ticket types, items must split exactly the offered places between them. The
+// purchase is paid like any other one and holds its seats for the usual seat hold.
+func (t *TourismUseCase) ClaimWaitlistOffer(userID, entryID uuid.UUID, items []entity.PurchaseItem) (*entity.Purchase, error) {
+	entry, err := t.repo.GetWaitlistEntryByID(entryID)
+	if err != nil {
+		return nil, fmt.Errorf("claim waitlist offer: %w", err)
+	}
+	if entry.UserID != userID {
+		return nil, ErrWaitlistForbidden
+	}
+	if entry.Status != entity.WaitlistStatusOffered || entry.OfferExpiresAt == nil || entry.OfferExpiresAt.Before(time.Now()) {
+		return nil, ErrWaitlistOfferUnavailable
+	}
+
+	tourEvent, err := t.repo.GetTourEventByID(entry.TourEventID)
+	if err != nil {
+		return nil, fmt.Errorf("claim waitlist offer: %w", err)
+	}
+	if !tourEvent.IsOpened || tourEvent.CancelledAt != nil || tourEvent.Tour.ArchivedAt != nil {
+		return nil, fmt.Errorf("%w: the tour event is closed", ErrWaitlistOfferUnavailable)
+	}
+
+	holdExpiresAt := time.Now().Add(t.purchaseCfg.SeatHoldTTL)
+	purchase := &entity.Purchase{
+		TourEventID:   entry.TourEventID,
+		UserID:        userID,
+		Status:        entity.PurchaseStatusProcessing,
+		Quantity:      entry.Quantity,
+		HoldExpiresAt: &holdExpiresAt,
+		Items:         items,
+	}
+	if err := resolveTicketItems(purchase, tourEvent); err != nil {
+		return nil, err
+	}
+	if purchase.Quantity != entry.Quantity {
+		return nil, fmt.Errorf("%w: the offer is for %d places", ErrInvalidTicketSelection, entry.Quantity)
+	}
+	if err := t.pricePurchase(purchase, tourEvent); err != nil {
+		return nil, err
+	}
+	claimed, err := t.repo.ClaimWaitlistOffer(entryID, purchase)
+	if err != nil {
+		return nil, err
+	}
+	return t.payFreePurchase(claimed)
+}
+
+// ExpireWaitlistOffers ends offers that were not claimed in time and passes
+// their places to the following people in line. It returns how many offers expired.
+func (t *TourismUseCase) ExpireWaitlistOffers() (int, error) {
+	entries, err := t.repo.GetExpiredWaitlistOffers(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	tourEvents := make(map[uuid.UUID]struct{})
+	for _, entry := range entries {
+		if err := t.repo.ReleaseWaitlistOffer(entry.ID, entity.WaitlistStatusExpired); err != nil {
+			log.Printf("Expire waitlist offer %s: %v", entry.ID, err)
+			continue
+		}
+		entry.Status = entity.WaitlistStatusExpired
+		expired++
+		tourEvents[entry.TourEventID] = struct{}{}
+
+		t.publishWaitlistMessage(entry, "Your waitlist offer expired because it was not claimed in time")
+	}
+
+	for tourEventID := range tourEvents {
+		t.offerFreedSeats(tourEventID)
+	}
+	return expired, nil
+}
+
+// offerFreedSeats offers the free places of a tour event to the people in line.
+// It is called whenever places go back to a tour event.
+func (t *TourismUseCase) offerFreedSeats(tourEventID uuid.UUID) {
+	offers, err := t.repo.OfferWaitlistSeats(tourEventID, time.Now().Add(t.purchaseCfg.WaitlistOfferTTL))
+	if err != nil {
+		log.Printf("Offer waitlist seats of tour event %s: %v", tourEventID, err)
+		return
+	}
+
+	for _, entry := range offers {
+		t.publishWaitlistMessage(entry, fmt.Sprintf("A place you were waiting for is available, claim it before %s",
+			entry.OfferExpiresAt.Format(time.RFC3339)))
+	}
+}
+
+func (t *TourismUseCase) publishWaitlistMessage(entry *entity.WaitlistEntry, text string) {
+	kafkaMessage := entity.Notification{
+		Topic: "WAITLIST",
+		Data: map[string]interface{}{
+			"Text":     text,
+			"Waitlist": entry,
+		},
+		Recipients: []uuid.UUID{entry.UserID},
+	}
+
+	t.PublishMessage("notifications", kafkaMessage)
+}
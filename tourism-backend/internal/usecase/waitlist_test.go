@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+func TestFailPurchaseOffersSeatsInLineOrder(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	processing := repo.addPurchase(tourEvent, entity.PurchaseStatusProcessing, 2)
+	first := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 1)
+	second := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 1)
+	third := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 1)
+	uc, _, producer := newTestUseCase(repo)
+
+	if err := uc.FailPurchase(repo.purchases[processing.ID]); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []*entity.WaitlistEntry{first, second} {
+		if entry.Status != entity.WaitlistStatusOffered || entry.OfferExpiresAt == nil {
+			t.Errorf("entry with %d places = %s, want Offered", entry.Quantity, entry.Status)
+		}
+	}
+	if third.Status != entity.WaitlistStatusWaiting {
+		t.Errorf("last entry = %s, want it still waiting", third.Status)
+	}
+	if places := tourEvent.AmountOfPlaces; places != 0 {
+		t.Errorf("free places = %v, want the offered ones taken", places)
+	}
+	counts := make(map[string]int)
+	for _, topic := range producer.topics() {
+		counts[topic]++
+	}
+	if counts["PAYMENT"] != 1 || counts["WAITLIST"] != 2 {
+		t.Errorf("notifications = %v, want one PAYMENT and two WAITLIST", producer.topics())
+	}
+}
+
+func TestFailPurchaseSkipsWaitlistEntriesThatDoNotFit(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	processing := repo.addPurchase(tourEvent, entity.PurchaseStatusProcessing, 2)
+	group := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 3)
+	couple := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 2)
+	single := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 1)
+	uc, _, _ := newTestUseCase(repo)
+
+	if err := uc.FailPurchase(repo.purchases[processing.ID]); err != nil {
+		t.Fatal(err)
+	}
+	if group.Status != entity.WaitlistStatusWaiting {
+		t.Errorf("group of 3 = %s, want it still waiting for 3 places", group.Status)
+	}
+	if couple.Status != entity.WaitlistStatusOffered {
+		t.Errorf("couple behind the group = %s, want Offered", couple.Status)
+	}
+	if single.Status != entity.WaitlistStatusWaiting {
+		t.Errorf("single behind the couple = %s, want it waiting, the places went to the couple", single.Status)
+	}
+}
+
+func TestExpireWaitlistOffersOffersSeatsAgain(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 0)
+	offered := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusOffered, 2)
+	expiredAt := time.Now().Add(-time.Minute)
+	offered.OfferExpiresAt = &expiredAt
+	next := repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusWaiting, 2)
+	uc, _, producer := newTestUseCase(repo)
+	uc.purchaseCfg.WaitlistOfferTTL = time.Hour
+
+	expired, err := uc.ExpireWaitlistOffers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 1 || offered.Status != entity.WaitlistStatusExpired {
+		t.Fatalf("expired %d, first entry = %s, want its offer expired", expired, offered.Status)
+	}
+	if next.Status != entity.WaitlistStatusOffered || !next.OfferExpiresAt.After(time.Now()) {
+		t.Fatalf("next entry = %s until %v, want a new offer", next.Status, next.OfferExpiresAt)
+	}
+	if places := tourEvent.AmountOfPlaces; places != 0 {
+		t.Errorf("free places = %v, want the places passed on to the next entry", places)
+	}
+	if topics := producer.topics(); len(topics) != 2 {
+		t.Errorf("notifications = %v, want the expiry and the new offer", topics)
+	}
+
+	if expired, err := uc.ExpireWaitlistOffers(); err != nil || expired != 0 {
+		t.Errorf("second ExpireWaitlistOffers() = %d, %v, want nothing expired", expired, err)
+	}
+}
+
+func TestJoinWaitlistRejectsMoreThanCapacity(t *testing.T) {
+	repo := newFakeRepo()
+	tourEvent := repo.addTourEvent(uuid.New(), time.Now().Add(48*time.Hour), 1)
+	repo.addPurchase(tourEvent, entity.PurchaseStatusPaid, 2)
+	repo.addWaitlistEntry(tourEvent, entity.WaitlistStatusOffered, 1)
+	uc, _, _ := newTestUseCase(repo)
+
+	if _, err := uc.JoinWaitlist(uuid.New(), &entity.JoinWaitlistDTO{TourEventID: tourEvent.ID, Quantity: 5}); !errors.Is(err, ErrWaitlistTooLarge) {
+		t.Fatalf("JoinWaitlist() of 5 places error = %v, want ErrWaitlistTooLarge", err)
+	}
+	entry, err := uc.JoinWaitlist(uuid.New(), &entity.JoinWaitlistDTO{TourEventID: tourEvent.ID, Quantity: 4})
+	if err != nil {
+		t.Fatalf("JoinWaitlist() of all 4 places error = %v", err)
+	}
+	if entry.Status != entity.WaitlistStatusWaiting {
+		t.Errorf("entry = %s, want Waiting", entry.Status)
+	}
+	if _, err := uc.JoinWaitlist(uuid.New(), &entity.JoinWaitlistDTO{TourEventID: tourEvent.ID, Quantity: 1}); !errors.Is(err, ErrWaitlistNotNeeded) {
+		t.Errorf("JoinWaitlist() of a free place error = %v, want ErrWaitlistNotNeeded", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package geo reads route tracks from GPX and GeoJSON files and measures them.
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+const _earthRadiusKm = 6371.0088
+
+var ErrNoTrack = errors.New("the file has no track")
+
+// DistanceKm returns the great-circle distance between two positions given as
+// [longitude, latitude, ...].
+func DistanceKm(from, to []float64) float64 {
+	lat1, lat2 := radians(from[1]), radians(to[1])
+	dLat := lat2 - lat1
+	dLng := radians(to[0] - from[0])
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * _earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// LengthKm returns the length of a line of positions.
+func LengthKm(line [][]float64) float64 {
+	var length float64
+	for i := 1; i < len(line); i++ {
+		length += DistanceKm(line[i-1], line[i])
+	}
+	return length
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// validPosition reports whether a [longitude, latitude, ...] position lies on the globe.
+func validPosition(position []float64) bool {
+	return len(position) >= 2 &&
+		position[0] >= -180 && position[0] <= 180 &&
+		position[1] >= -90 && position[1] <= 90
+}
+
+// cleanSegments drops segments too short to draw a line.
+func cleanSegments(segments [][][]float64) ([][][]float64, error) {
+	cleaned := segments[:0]
+	for _, segment := range segments {
+		if len(segment) >= 2 {
+			cleaned = append(cleaned, segment)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, ErrNoTrack
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,68 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Big Almaty Lake</name>
+    <trkseg>
+      <trkpt lat="43.0500" lon="76.9850"><ele>2500</ele></trkpt>
+      <trkpt lat="43.0600" lon="76.9900"><ele>2550</ele></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="43.0700" lon="76.9950"></trkpt>
+      <trkpt lat="43.0800" lon="77.0000"></trkpt>
+      <trkpt lat="43.0900" lon="77.0050"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestParseGPX(t *testing.T) {
+	segments, err := ParseGPX(strings.NewReader(testGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 || len(segments[0]) != 2 || len(segments[1]) != 3 {
+		t.Fatalf("segments = %v, want 2 and 3 points", segments)
+	}
+	if first := segments[0][0]; first[0] != 76.985 || first[1] != 43.05 || first[2] != 2500 {
+		t.Fatalf("first point = %v, want [76.985 43.05 2500]", first)
+	}
+}
+
+func TestParseGeoJSON(t *testing.T) {
+	data := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [76.9, 43.2]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[76.9, 43.2], [77.0, 43.3]]}, "properties": {}},
+		{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [[[77.0, 43.3], [77.1, 43.4]], [[77.2, 43.5]]]}, "properties": {}}
+	]}`
+
+	segments, err := ParseGeoJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("segments = %v, want the two lines without the single point one", segments)
+	}
+}
+
+func TestParseGeoJSONWithoutLines(t *testing.T) {
+	_, err := ParseGeoJSON([]byte(`{"type": "Point", "coordinates": [76.9, 43.2]}`))
+	if !errors.Is(err, ErrNoTrack) {
+		t.Fatalf("err = %v, want %v", err, ErrNoTrack)
+	}
+}
+
+func TestLengthKm(t *testing.T) {
+	// Almaty to Astana is about 970 km in a straight line.
+	length := LengthKm([][]float64{{76.9286, 43.2567}, {71.4460, 51.1801}})
+	if math.Abs(length-970) > 10 {
+		t.Fatalf("length = %.1f km, want about 970", length)
+	}
+}
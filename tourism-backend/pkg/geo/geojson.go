@@ -0,0 +1,82 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type geoJSONObject struct {
+	Type        string           `json:"type"`
+	Features    []*geoJSONObject `json:"features"`
+	Geometry    *geoJSONObject   `json:"geometry"`
+	Geometries  []*geoJSONObject `json:"geometries"`
+	Coordinates json.RawMessage  `json:"coordinates"`
+}
+
+// ParseGeoJSON reads the LineString and MultiLineString geometries of a GeoJSON
+// FeatureCollection, Feature or geometry. Other geometries are ignored.
+func ParseGeoJSON(data []byte) ([][][]float64, error) {
+	var object geoJSONObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("parse geojson: %w", err)
+	}
+
+	var segments [][][]float64
+	if err := collectLines(&object, &segments); err != nil {
+		return nil, err
+	}
+	return cleanSegments(segments)
+}
+
+func collectLines(object *geoJSONObject, segments *[][][]float64) error {
+	switch object.Type {
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			if feature != nil {
+				if err := collectLines(feature, segments); err != nil {
+					return err
+				}
+			}
+		}
+	case "Feature":
+		if object.Geometry != nil {
+			return collectLines(object.Geometry, segments)
+		}
+	case "GeometryCollection":
+		for _, geometry := range object.Geometries {
+			if geometry != nil {
+				if err := collectLines(geometry, segments); err != nil {
+					return err
+				}
+			}
+		}
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(object.Coordinates, &line); err != nil {
+			return fmt.Errorf("parse geojson: %w", err)
+		}
+		return appendLines(segments, line)
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &lines); err != nil {
+			return fmt.Errorf("parse geojson: %w", err)
+		}
+		return appendLines(segments, lines...)
+	case "Point", "MultiPoint", "Polygon", "MultiPolygon":
+	default:
+		return fmt.Errorf("parse geojson: unknown type %q", object.Type)
+	}
+	return nil
+}
+
+func appendLines(segments *[][][]float64, lines ...[][]float64) error {
+	for _, line := range lines {
+		for _, position := range line {
+			if !validPosition(position) {
+				return fmt.Errorf("parse geojson: position %v is out of range", position)
+			}
+		}
+		*segments = append(*segments, line)
+	}
+	return nil
+}
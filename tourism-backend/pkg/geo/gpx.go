@@ -0,0 +1,69 @@
+package geo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+type gpxPoint struct {
+	Lat       float64  `xml:"lat,attr"`
+	Lon       float64  `xml:"lon,attr"`
+	Elevation *float64 `xml:"ele"`
+}
+
+// ParseGPX reads the track segments of a GPX file, or its routes when it has
+// no tracks, as lines of [longitude, latitude(, elevation)] positions.
+func ParseGPX(r io.Reader) ([][][]float64, error) {
+	var file gpxFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse gpx: %w", err)
+	}
+
+	var segments [][][]float64
+	for _, track := range file.Tracks {
+		for _, segment := range track.Segments {
+			line, err := gpxLine(segment.Points)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, line)
+		}
+	}
+	if len(segments) == 0 {
+		for _, route := range file.Routes {
+			line, err := gpxLine(route.Points)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, line)
+		}
+	}
+	return cleanSegments(segments)
+}
+
+func gpxLine(points []gpxPoint) ([][]float64, error) {
+	line := make([][]float64, 0, len(points))
+	for _, point := range points {
+		position := []float64{point.Lon, point.Lat}
+		if point.Elevation != nil {
+			position = append(position, *point.Elevation)
+		}
+		if !validPosition(position) {
+			return nil, fmt.Errorf("parse gpx: point %v is out of range", position)
+		}
+		line = append(line, position)
+	}
+	return line, nil
+}
@@ -0,0 +1,146 @@
+// Package ical writes iCalendar (RFC 5545) feeds that calendar apps can
+// subscribe to.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of a feed.
+const ContentType = "text/calendar; charset=utf-8"
+
+// _maxLineOctets is the longest content line before it has to be folded.
+const _maxLineOctets = 75
+
+// Event statuses.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is a VEVENT. End, Location, Description, Geo and Status are left out
+// when empty.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Geo         *Geo
+	Status      string
+}
+
+// Geo is the position of an event.
+type Geo struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Calendar is a VCALENDAR. RefreshInterval tells subscribers how often to
+// fetch it again.
+type Calendar struct {
+	Name            string
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Encode writes the calendar to w.
+func (c *Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//tourism-backend//calendar//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		interval := duration(c.RefreshInterval)
+		line("REFRESH-INTERVAL;VALUE=DURATION", interval)
+		line("X-PUBLISHED-TTL", interval)
+	}
+
+	for _, event := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", timestamp(event.Stamp))
+		line("DTSTART", timestamp(event.Start))
+		if !event.End.IsZero() {
+			line("DTEND", timestamp(event.End))
+		}
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", escape(event.Location))
+		}
+		if event.Geo != nil {
+			line("GEO", fmt.Sprintf("%.6f;%.6f", event.Geo.Latitude, event.Geo.Longitude))
+		}
+		if event.Status != "" {
+			line("STATUS", event.Status)
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line, folded into lines of at most 75 octets
+// without splitting a UTF-8 character.
+func writeLine(w *bufio.Writer, line string) {
+	limit := _maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts too.
+		limit = _maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var _escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value.
+func escape(text string) string {
+	return _escaper.Replace(text)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// duration formats a whole number of minutes as an RFC 5545 duration.
+func duration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("PT%dH", minutes/60)
+	}
+	return fmt.Sprintf("PT%dM", minutes)
+}
@@ -0,0 +1,72 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	start := time.Date(2026, time.November, 7, 9, 0, 0, 0, time.FixedZone("ALMT", 5*3600))
+	calendar := Calendar{
+		Name:            "My tours",
+		RefreshInterval: time.Hour,
+		Events: []Event{{
+			UID:         "purchase-1@tourism-backend",
+			Stamp:       start.Add(-24 * time.Hour),
+			Start:       start,
+			Summary:     "Big Almaty Lake, Kolsai",
+			Description: "2 places\nBring water; it is a long walk",
+			Location:    "Gate 3",
+			Geo:         &Geo{Latitude: 43.05, Longitude: 76.985},
+			Status:      StatusConfirmed,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:My tours\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n",
+		"DTSTART:20261107T040000Z\r\n",
+		"SUMMARY:Big Almaty Lake\\, Kolsai\r\n",
+		"DESCRIPTION:2 places\\nBring water\\; it is a long walk\r\n",
+		"GEO:43.050000;76.985000\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar has no %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestLongLinesAreFolded(t *testing.T) {
+	calendar := Calendar{Events: []Event{{
+		UID:     "event",
+		Summary: strings.Repeat("Шымбулак ", 20),
+	}}}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line of %d octets: %q", len(line), line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+			continue
+		}
+		unfolded.WriteString("\n" + line)
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+strings.Repeat("Шымбулак ", 20)+"\n") {
+		t.Fatalf("unfolded calendar lost the summary:\n%s", unfolded.String())
+	}
+}
@@ -0,0 +1,195 @@
+// Package imaging validates uploaded images and renders them into resized
+// JPEG renditions without their metadata.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+	"sync"
+
+	// Decoders of the accepted formats.
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// ContentType and Ext describe the encoded renditions.
+	ContentType = "image/jpeg"
+	Ext         = ".jpg"
+
+	// MaxPixels bounds the size of a decoded photo, so that a small file
+	// cannot expand into gigabytes of pixels. Decoding, flattening and
+	// orienting an image takes up to about 12 bytes per pixel.
+	MaxPixels = 40_000_000
+	// MaxPanoramaPixels bounds the size of a decoded panorama, which fits the
+	// 72 MP equirectangular images of 360° cameras.
+	MaxPanoramaPixels = 75_000_000
+	// MaxPixelsInFlight bounds the pixels of all the images processed at
+	// once, so that concurrent uploads cannot exhaust the memory either.
+	// Process waits for earlier images to be done beyond it.
+	MaxPixelsInFlight = 80_000_000
+
+	_jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image is too large")
+)
+
+// _formats are the accepted content types, sniffed from the data of the file
+// instead of trusting its name.
+var _formats = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Rendition is a size an image is rendered at. The image is scaled down to fit
+// into MaxWidth x MaxHeight keeping its aspect ratio, it is never scaled up.
+type Rendition struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Output is an encoded rendition.
+type Output struct {
+	Name   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Process decodes an image of at most maxPixels pixels and encodes it at every
+// rendition. The EXIF orientation is applied to the pixels, EXIF, GPS and any
+// other metadata is dropped since the renditions are encoded from pixels only.
+func Process(data []byte, renditions []Rendition, maxPixels int) ([]*Output, error) {
+	if contentType := http.DetectContentType(data); !_formats[contentType] {
+		return nil, fmt.Errorf("%w: %s, upload a JPEG, PNG or GIF image", ErrUnsupportedFormat, contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	pixels := config.Width * config.Height
+	if pixels > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels, at most %d megapixels are accepted", ErrTooLarge, config.Width, config.Height, maxPixels/1_000_000)
+	}
+	_inFlight.acquire(pixels)
+	defer _inFlight.release(pixels)
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	src := orient(flatten(decoded), exifOrientation(data))
+
+	outputs := make([]*Output, 0, len(renditions))
+	for _, rendition := range renditions {
+		width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), rendition.MaxWidth, rendition.MaxHeight)
+		resized := resize(src, width, height)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: _jpegQuality}); err != nil {
+			return nil, fmt.Errorf("encode %s rendition: %w", rendition.Name, err)
+		}
+		outputs = append(outputs, &Output{Name: rendition.Name, Width: width, Height: height, Data: buf.Bytes()})
+	}
+	return outputs, nil
+}
+
+// budget is a count of pixels shared by concurrent calls of Process.
+type budget struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	size int
+	free int
+}
+
+var _inFlight = newBudget(MaxPixelsInFlight)
+
+func newBudget(size int) *budget {
+	b := &budget{size: size, free: size}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire waits until n pixels are free and takes them. An image larger than
+// the whole budget waits for all the others to be done.
+func (b *budget) acquire(n int) {
+	n = min(n, b.size)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.free < n {
+		b.cond.Wait()
+	}
+	b.free -= n
+}
+
+func (b *budget) release(n int) {
+	n = min(n, b.size)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.free += n
+	b.cond.Broadcast()
+}
+
+// flatten draws an image over a white background, JPEG has no transparency.
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// fit returns the size of a width x height image scaled down to fit into
+// maxWidth x maxHeight.
+func fit(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, height*maxWidth/width)
+	}
+	return max(1, width*maxHeight/height), maxHeight
+}
+
+// resize scales an image down by averaging the source pixels covered by each
+// destination pixel.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	if width == srcWidth && height == srcHeight {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					a += int(row[sx*4+3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+// jpegWithOrientation encodes a 40x20 image, red on the left half and blue on
+// the right one, with an EXIF segment holding the orientation and a GPS tag.
+func jpegWithOrientation(t *testing.T, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func TestProcessAppliesOrientationAndStripsMetadata(t *testing.T) {
+	data := jpegWithOrientation(t, 6)
+	if got := exifOrientation(data); got != 6 {
+		t.Fatalf("orientation = %d, want 6", got)
+	}
+
+	outputs, err := Process(data, []Rendition{{Name: "full", MaxWidth: 100, MaxHeight: 100}, {Name: "thumb", MaxWidth: 5, MaxHeight: 5}}, MaxPixels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, thumb := outputs[0], outputs[1]
+	if full.Width != 20 || full.Height != 40 || thumb.Width != 2 || thumb.Height != 5 {
+		t.Fatalf("sizes = %dx%d and %dx%d, want 20x40 and 2x5", full.Width, full.Height, thumb.Width, thumb.Height)
+	}
+	if bytes.Contains(full.Data, []byte("Exif")) {
+		t.Fatal("rendition still holds EXIF data")
+	}
+
+	// Rotating clockwise brings the red left half to the top.
+	img, err := jpeg.Decode(bytes.NewReader(full.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Fatalf("top of the rendition is not red: r=%d b=%d", r, b)
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "text with an image name", data: []byte("<html><body>not an image</body></html>")},
+		{name: "truncated jpeg", data: jpegWithOrientation(t, 1)[:100]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data, []Rendition{{Name: "full", MaxWidth: 10, MaxHeight: 10}}, MaxPixels); !errors.Is(err, ErrUnsupportedFormat) {
+				t.Fatalf("err = %v, want %v", err, ErrUnsupportedFormat)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsImagesOverTheLimit(t *testing.T) {
+	data := jpegWithOrientation(t, 1) // 40x20
+	if _, err := Process(data, []Rendition{{Name: "full", MaxWidth: 10, MaxHeight: 10}}, 799); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want %v", err, ErrTooLarge)
+	}
+	if _, err := Process(data, []Rendition{{Name: "full", MaxWidth: 10, MaxHeight: 10}}, 800); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBudgetWaitsForFreePixels(t *testing.T) {
+	b := newBudget(100)
+	b.acquire(60)
+
+	acquired := make(chan struct{})
+	go func() {
+		b.acquire(60)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired more pixels than the budget holds")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	b.release(60)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire did not return once the pixels were released")
+	}
+
+	// An image larger than the budget takes all of it.
+	b.release(60)
+	b.acquire(500)
+	b.release(500)
+	if b.free != 100 {
+		t.Fatalf("free = %d, want 100", b.free)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height, maxWidth, maxHeight int
+		wantWidth, wantHeight              int
+	}{
+		{width: 4000, height: 3000, maxWidth: 800, maxHeight: 600, wantWidth: 800, wantHeight: 600},
+		{width: 3000, height: 4000, maxWidth: 800, maxHeight: 600, wantWidth: 450, wantHeight: 600},
+		{width: 8000, height: 10, maxWidth: 320, maxHeight: 320, wantWidth: 320, wantHeight: 1},
+		{width: 300, height: 200, maxWidth: 800, maxHeight: 600, wantWidth: 300, wantHeight: 200},
+	}
+	for _, tt := range tests {
+		width, height := fit(tt.width, tt.height, tt.maxWidth, tt.maxHeight)
+		if width != tt.wantWidth || height != tt.wantHeight {
+			t.Errorf("fit(%dx%d into %dx%d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxWidth, tt.maxHeight, width, height, tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package media stores uploaded media files on the local disk or in an
+// S3-compatible object store.
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"tourism-backend/config"
+)
+
+// PrivatePrefix starts the keys of media that is only handed out through
+// signed URLs.
+const PrivatePrefix = "private/"
+
+var ErrNotFound = errors.New("media not found")
+
+// Store keeps media files under slash-separated keys such as
+// "images/4f72a1cb-6ed4-4f01-b38b-b605d3062236.png".
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the address public media is downloaded from.
+	URL(key string) string
+	// SignedURL returns an address for private media that expires after the
+	// configured TTL.
+	SignedURL(key string) (string, error)
+	// KeyFromURL returns the key of the media a URL returned by URL points to.
+	KeyFromURL(url string) (string, bool)
+	// List calls fn with every stored key and the time it was last modified.
+	List(ctx context.Context, fn func(key string, modTime time.Time) error) error
+}
+
+// NewStore creates the store selected by the configuration.
+func NewStore(cfg config.Media) (Store, error) {
+	switch cfg.Backend {
+	case "local":
+		signingKey := []byte(cfg.SigningKey)
+		if len(signingKey) == 0 {
+			// Signed URLs stop working after a restart, set MEDIA_SIGNING_KEY to keep them.
+			signingKey = make([]byte, 32)
+			if _, err := rand.Read(signingKey); err != nil {
+				return nil, fmt.Errorf("generate media signing key: %w", err)
+			}
+		}
+		return NewLocalStore(cfg.LocalDir, cfg.LocalURL, signingKey, cfg.SignedURLTTL), nil
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			PathStyle:    cfg.S3PathStyle,
+			PublicURL:    cfg.S3PublicURL,
+			SignedURLTTL: cfg.SignedURLTTL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown media backend %q", cfg.Backend)
+	}
+}
+
+// IsPrivate reports whether the media under key needs a signed URL.
+func IsPrivate(key string) bool {
+	return strings.HasPrefix(key, PrivatePrefix)
+}
@@ -0,0 +1,33 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrUnsupportedType = errors.New("unsupported media type")
+
+// _videoExts maps the accepted video content types to their file extension.
+var _videoExts = map[string]string{
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/quicktime": ".mov",
+}
+
+// DetectVideo sniffs the content type of a video from its first bytes and
+// returns it with the file extension to store it under.
+func DetectVideo(head []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(head)
+	// QuickTime files start with an ftyp box of the "qt  " brand, which
+	// http.DetectContentType does not know.
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) && bytes.Equal(head[8:12], []byte("qt  ")) {
+		contentType = "video/quicktime"
+	}
+	ext, ok := _videoExts[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s, upload an MP4, WebM or QuickTime video", ErrUnsupportedType, contentType)
+	}
+	return contentType, ext, nil
+}
@@ -0,0 +1,112 @@
+package payment
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"tourism-backend/internal/entity"
+)
+
+// Scenarios of the FakeGateway.
+const (
+	// FakeScenarioSuccess charges and refunds always succeed.
+	FakeScenarioSuccess = "success"
+	// FakeScenarioDecline charges are declined like a rejected card.
+	FakeScenarioDecline = "decline"
+	// FakeScenarioTimeout charges time out and nothing is captured.
+	FakeScenarioTimeout = "timeout"
+	// FakeScenarioPartial charges are captured but the answer is lost with a
+	// timeout, only Status reveals that the money was taken.
+	FakeScenarioPartial = "partial"
+	// FakeScenarioRefundFail charges succeed and refunds fail.
+	FakeScenarioRefundFail = "refund_fail"
+)
+
+// FakeGateway is a deterministic in-memory PaymentGateway for local development.
+type FakeGateway struct {
+	mu       sync.Mutex
+	scenario string
+	latency  time.Duration
+	currency string
+	charges  map[string]Charge
+}
+
+func NewFakeGateway(scenario string, latency time.Duration, currency string) (*FakeGateway, error) {
+	switch scenario {
+	case FakeScenarioSuccess, FakeScenarioDecline, FakeScenarioTimeout, FakeScenarioPartial, FakeScenarioRefundFail:
+	default:
+		return nil, fmt.Errorf("unknown fake payment scenario %q", scenario)
+	}
+
+	return &FakeGateway{
+		scenario: scenario,
+		latency:  latency,
+		currency: currency,
+		charges:  make(map[string]Charge),
+	}, nil
+}
+
+func (g *FakeGateway) Charge(req ChargeRequest) (*Charge, error) {
+	time.Sleep(g.latency)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := "fake_" + req.PurchaseID.String()
+	if charge, ok := g.charges[id]; ok {
+		return &charge, nil
+	}
+
+	charge := Charge{
+		ID:            id,
+		ClientSecret:  id + "_secret",
+		Amount:        req.Amount,
+		Currency:      g.currency,
+		PaymentMethod: "fake_card",
+	}
+
+	switch g.scenario {
+	case FakeScenarioDecline:
+		charge.Status = ChargeStatusFailed
+		g.charges[id] = charge
+		return &charge, fmt.Errorf("%w: card declined", ErrPaymentDeclined)
+	case FakeScenarioTimeout:
+		return nil, ErrPaymentTimeout
+	case FakeScenarioPartial:
+		charge.Status = ChargeStatusSucceeded
+		g.charges[id] = charge
+		lost := charge
+		lost.Status = ChargeStatusPending
+		return &lost, ErrPaymentTimeout
+	default:
+		charge.Status = ChargeStatusSucceeded
+		g.charges[id] = charge
+		return &charge, nil
+	}
+}
+
+func (g *FakeGateway) Refund(purchase *entity.Purchase) error {
+	time.Sleep(g.latency)
+
+	if g.scenario == FakeScenarioRefundFail {
+		return fmt.Errorf("fake refund failed for purchase %s", purchase.ID)
+	}
+	return nil
+}
+
+func (g *FakeGateway) Status(chargeID string) (*Charge, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	charge, ok := g.charges[chargeID]
+	if !ok {
+		return nil, fmt.Errorf("charge %s not found", chargeID)
+	}
+	return &charge, nil
+}
+
+// ClientConfirmed is false, the fake gateway charges as soon as the payment
+// workers ask. Promo codes have to be given when the purchase is created.
+func (g *FakeGateway) ClientConfirmed() bool {
+	return false
+}
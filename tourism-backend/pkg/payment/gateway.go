@@ -0,0 +1,82 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"tourism-backend/config"
+	"tourism-backend/internal/entity"
+)
+
+const (
+	GatewayStripe = "stripe"
+	GatewayFake   = "fake"
+)
+
+// Charge statuses reported by a PaymentGateway.
+const (
+	ChargeStatusPending   = "pending"
+	ChargeStatusSucceeded = "succeeded"
+	ChargeStatusFailed    = "failed"
+)
+
+var (
+	ErrPaymentDeclined = errors.New("payment declined")
+	ErrPaymentTimeout  = errors.New("payment gateway timeout")
+)
+
+// PaymentGateway charges and refunds purchases and reports the status of charges.
+type PaymentGateway interface {
+	// Charge creates a charge for the purchase. Charging the same purchase twice
+	// returns the same charge. A pending charge is finished by the gateway later.
+	Charge(req ChargeRequest) (*Charge, error)
+	// Refund returns the money of a purchase. Purchases without a charge are a no-op.
+	Refund(purchase *entity.Purchase) error
+	// Status returns the current state of a charge.
+	Status(chargeID string) (*Charge, error)
+	// ClientConfirmed reports whether charges wait for the client to confirm
+	// them. The payment workers leave those purchases to CreatePaymentIntent,
+	// so that a promo code can still be applied before the amount is fixed.
+	ClientConfirmed() bool
+}
+
+type ChargeRequest struct {
+	PurchaseID uuid.UUID
+	// Amount is in the smallest currency unit of the gateway currency.
+	Amount int64
+}
+
+type Charge struct {
+	ID            string
+	Status        string
+	ClientSecret  string
+	Amount        int64
+	Currency      string
+	PaymentMethod string
+	ReceiptEmail  string
+}
+
+// NewGateway returns the payment gateway selected by cfg.Payment.Gateway.
+func NewGateway(cfg *config.Config) (PaymentGateway, error) {
+	switch cfg.Payment.Gateway {
+	case GatewayStripe:
+		if cfg.Stripe.SecretKey == "" {
+			return nil, fmt.Errorf("stripe gateway requires STRIPE_SECRET_KEY")
+		}
+		// The webhook marks purchases paid, its events must be signed with a secret.
+		if cfg.Stripe.WebhookSecret == "" {
+			return nil, fmt.Errorf("stripe gateway requires STRIPE_WEBHOOK_SECRET")
+		}
+		return NewStripeGateway(cfg.Stripe.SecretKey, cfg.Payment.Currency), nil
+	case GatewayFake:
+		return NewFakeGateway(cfg.Payment.FakeScenario, cfg.Payment.FakeLatency, cfg.Payment.Currency)
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", cfg.Payment.Gateway)
+	}
+}
+
+// AmountFromPrice converts a price to the smallest currency unit.
+func AmountFromPrice(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
@@ -1,59 +1,227 @@
 package payment
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"sync"
 	"time"
+	"tourism-backend/config"
 	"tourism-backend/internal/entity"
 	"tourism-backend/internal/usecase"
 )
 
+const _maxRetryBackoff = 30 * time.Minute
+
+// PaymentProcessor charges purchases queued as payment jobs in Postgres.
+// Jobs survive restarts, and processing a purchase twice is harmless because
+// gateways charge a purchase at most once and status changes are conditional.
 type PaymentProcessor struct {
-	mu             sync.Mutex
-	PurchaseQueue  chan *entity.Purchase
 	tourismUsecase usecase.TourismInterface
+	queue          usecase.PaymentQueue
+	gateway        PaymentGateway
+	workers        int
+	maxAttempts    int
+	retryBackoff   time.Duration
+	pollInterval   time.Duration
+	jobLease       time.Duration
+	wakeup         chan struct{}
 }
 
-func NewPaymentProcessor(bufferSize int, usecase usecase.TourismInterface) *PaymentProcessor {
-	p := &PaymentProcessor{
-		PurchaseQueue:  make(chan *entity.Purchase, bufferSize),
+func NewPaymentProcessor(usecase usecase.TourismInterface, queue usecase.PaymentQueue, gateway PaymentGateway, cfg config.Payment) *PaymentProcessor {
+	return &PaymentProcessor{
 		tourismUsecase: usecase,
+		queue:          queue,
+		gateway:        gateway,
+		workers:        cfg.Workers,
+		maxAttempts:    cfg.MaxAttempts,
+		retryBackoff:   cfg.RetryBackoff,
+		pollInterval:   cfg.PollInterval,
+		jobLease:       cfg.JobLease,
+		wakeup:         make(chan struct{}, cfg.Workers),
 	}
+}
 
-	// Start the worker goroutine
-	go p.ProcessPurchases()
+// Start recovers purchases left in Processing and starts the workers.
+func (p *PaymentProcessor) Start(ctx context.Context) {
+	recovered, err := p.queue.RecoverPaymentJobs()
+	if err != nil {
+		log.Printf("Payment recovery error: %v\n", err)
+	} else if recovered > 0 {
+		log.Printf("Recovered %d processing purchases\n", recovered)
+	}
 
-	return p
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
 }
 
-func (p *PaymentProcessor) ProcessPurchases() {
-	for purchase := range p.PurchaseQueue {
-		p.mu.Lock()
+// Notify wakes an idle worker up after a purchase was queued.
+func (p *PaymentProcessor) Notify() {
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
 
-		log.Printf("Processing purchase: User %s -> TourEvent %s\n", purchase.UserID, purchase.TourEventID)
+func (p *PaymentProcessor) work(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
 
-		// Simulate payment processing
-		time.Sleep(5 * time.Second) // Simulate network delay
+	for {
+		for p.runNextJob() {
+			if ctx.Err() != nil {
+				return
+			}
+		}
 
-		// Mock payment API
-		success := mockPaymentGateway(1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wakeup:
+		}
+	}
+}
 
-		if success {
-			err := p.tourismUsecase.PayTourEvent(purchase)
-			if err != nil {
-				log.Printf("Payment processing error: %v\n", err)
-				continue
-			}
-			log.Printf("Payment successful for User %s on TourEvent %s\n", purchase.UserID, purchase.TourEventID)
-		} else {
-			log.Printf("Payment failed for User %s\n", purchase.UserID)
+// runNextJob processes one due job and reports whether there was one.
+func (p *PaymentProcessor) runNextJob() bool {
+	job, err := p.queue.ClaimPaymentJob(p.jobLease)
+	if err != nil {
+		log.Printf("Payment queue error: %v\n", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	err = p.processPurchase(&job.Purchase)
+	switch {
+	case err == nil:
+		err = p.queue.CompletePaymentJob(job)
+	case job.Attempts >= p.maxAttempts:
+		log.Printf("Payment for purchase %s failed after %d attempts: %v\n", job.PurchaseID, job.Attempts, err)
+		if failErr := p.tourismUsecase.FailPurchase(&job.Purchase); failErr != nil {
+			log.Printf("Payment processing error: %v\n", failErr)
+		}
+		err = p.queue.FailPaymentJob(job, err)
+	default:
+		log.Printf("Payment for purchase %s will be retried: %v\n", job.PurchaseID, err)
+		err = p.queue.RetryPaymentJob(job, time.Now().Add(p.backoff(job.Attempts)), err)
+	}
+	switch {
+	case errors.Is(err, usecase.ErrPaymentJobLeaseLost):
+		// The job took longer than its lease and belongs to another worker now.
+		log.Printf("Payment job for purchase %s was claimed again, dropping attempt %d\n", job.PurchaseID, job.Attempts)
+	case err != nil:
+		log.Printf("Payment queue error: %v\n", err)
+	}
+	return true
+}
+
+// backoff doubles the retry delay with every attempt.
+func (p *PaymentProcessor) backoff(attempts int) time.Duration {
+	delay := p.retryBackoff
+	for i := 1; i < attempts && delay < _maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > _maxRetryBackoff {
+		delay = _maxRetryBackoff
+	}
+	return delay
+}
+
+// processPurchase charges a purchase. It returns an error only when the
+// payment should be retried.
+func (p *PaymentProcessor) processPurchase(purchase *entity.Purchase) error {
+	if purchase.Status != entity.PurchaseStatusProcessing {
+		if purchase.PaymentIntentID != "" && purchase.Status != entity.PurchaseStatusPaid {
+			// An earlier attempt charged the purchase after it left Processing.
+			return p.refundAbandonedCharge(purchase, purchase.PaymentIntentID)
 		}
+		// Already paid, cancelled or expired by someone else.
+		return nil
+	}
+	if purchase.IsFree() {
+		// Discounts took off the whole amount, gateways reject charges of zero.
+		return ignoreFinished(p.tourismUsecase.PayTourEvent(purchase))
+	}
+	if p.gateway.ClientConfirmed() {
+		// The client creates the charge through CreatePaymentIntent and its
+		// webhook reports the result. Charging here would fix the amount
+		// before the client can apply a promo code.
+		return nil
+	}
+
+	log.Printf("Processing purchase: User %s -> TourEvent %s\n", purchase.UserID, purchase.TourEventID)
+
+	charge, err := p.gateway.Charge(ChargeRequest{
+		PurchaseID: purchase.ID,
+		Amount:     AmountFromPrice(purchase.Amount),
+	})
+	if errors.Is(err, ErrPaymentTimeout) && charge != nil {
+		// The charge was created but its result was lost, ask the gateway for it.
+		charge, err = p.gateway.Status(charge.ID)
+	}
+	if errors.Is(err, ErrPaymentDeclined) {
+		log.Printf("Payment failed for User %s: %v\n", purchase.UserID, err)
+		return ignoreFinished(p.tourismUsecase.FailPurchase(purchase))
+	}
+	if err != nil {
+		return fmt.Errorf("charge purchase %s: %w", purchase.ID, err)
+	}
 
-		p.mu.Unlock()
+	err = p.tourismUsecase.AttachPaymentIntent(purchase.UserID, purchase.ID, charge.ID)
+	if errors.Is(err, usecase.ErrInvalidPurchaseTransition) {
+		// The purchase was cancelled or expired while it was being charged, and
+		// its cancellation had no charge to refund.
+		return p.refundAbandonedCharge(purchase, charge.ID)
 	}
+	if err != nil {
+		return err
+	}
+	purchase.PaymentIntentID = charge.ID
+
+	switch charge.Status {
+	case ChargeStatusSucceeded:
+		// PayTourEvent fails when the purchase left Processing since the charge
+		// was attached, the retry then refunds the charge.
+		if err := p.tourismUsecase.PayTourEvent(purchase); err != nil {
+			return ignoreFinished(err)
+		}
+		log.Printf("Payment successful for User %s on TourEvent %s\n", purchase.UserID, purchase.TourEventID)
+	case ChargeStatusFailed:
+		log.Printf("Payment failed for User %s\n", purchase.UserID)
+		return ignoreFinished(p.tourismUsecase.FailPurchase(purchase))
+	default:
+		// The gateway finishes the charge asynchronously and reports it through its webhook.
+		log.Printf("Payment pending for User %s on TourEvent %s\n", purchase.UserID, purchase.TourEventID)
+	}
+	return nil
+}
+
+// refundAbandonedCharge gives back the money of a charge made for a purchase
+// that is no longer Processing. The charge is recorded on the purchase first,
+// so a failed refund is retried with the job and the charge is never lost.
+func (p *PaymentProcessor) refundAbandonedCharge(purchase *entity.Purchase, chargeID string) error {
+	if err := p.tourismUsecase.RecordPaymentIntent(purchase.ID, chargeID); err != nil {
+		return err
+	}
+	purchase.PaymentIntentID = chargeID
+
+	log.Printf("Purchase %s is %s, refunding its charge %s\n", purchase.ID, purchase.Status, chargeID)
+	if err := p.gateway.Refund(purchase); err != nil {
+		return fmt.Errorf("refund charge of purchase %s: %w", purchase.ID, err)
+	}
+	return nil
 }
 
-// Simulation of a payment gateway response
-func mockPaymentGateway(amount float64) bool {
-	return amount > 0 // Simulate always successful payment
+// ignoreFinished drops errors caused by a purchase that left Processing while it
+// was being charged, retrying would not change anything.
+func ignoreFinished(err error) error {
+	if errors.Is(err, usecase.ErrInvalidPurchaseTransition) {
+		log.Printf("Payment processing skipped: %v\n", err)
+		return nil
+	}
+	return err
 }
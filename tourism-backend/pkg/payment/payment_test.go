@@ -0,0 +1,394 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"tourism-backend/config"
+	"tourism-backend/internal/entity"
+	"tourism-backend/internal/usecase"
+)
+
+// fakeTourism records what the processor did with a purchase; any other method panics.
+type fakeTourism struct {
+	usecase.TourismInterface
+	paid      []uuid.UUID
+	failed    []uuid.UUID
+	attached  map[uuid.UUID]string
+	recorded  map[uuid.UUID]string
+	cancelled map[uuid.UUID]bool
+}
+
+func newFakeTourism() *fakeTourism {
+	return &fakeTourism{
+		attached:  make(map[uuid.UUID]string),
+		recorded:  make(map[uuid.UUID]string),
+		cancelled: make(map[uuid.UUID]bool),
+	}
+}
+
+func (f *fakeTourism) PayTourEvent(purchase *entity.Purchase) error {
+	f.paid = append(f.paid, purchase.ID)
+	return nil
+}
+
+func (f *fakeTourism) FailPurchase(purchase *entity.Purchase) error {
+	f.failed = append(f.failed, purchase.ID)
+	return nil
+}
+
+func (f *fakeTourism) AttachPaymentIntent(_, purchaseID uuid.UUID, paymentIntentID string) error {
+	if f.cancelled[purchaseID] {
+		return fmt.Errorf("%w: Processing -> Paid", usecase.ErrInvalidPurchaseTransition)
+	}
+	f.attached[purchaseID] = paymentIntentID
+	return nil
+}
+
+func (f *fakeTourism) RecordPaymentIntent(purchaseID uuid.UUID, paymentIntentID string) error {
+	f.recorded[purchaseID] = paymentIntentID
+	return nil
+}
+
+// refundingGateway records refunds and runs during while a charge is in flight.
+type refundingGateway struct {
+	*FakeGateway
+	during    func()
+	refundErr error
+	refunded  []string
+}
+
+func (g *refundingGateway) Charge(req ChargeRequest) (*Charge, error) {
+	if g.during != nil {
+		g.during()
+	}
+	return g.FakeGateway.Charge(req)
+}
+
+func (g *refundingGateway) Refund(purchase *entity.Purchase) error {
+	if g.refundErr != nil {
+		return g.refundErr
+	}
+	g.refunded = append(g.refunded, purchase.PaymentIntentID)
+	return g.FakeGateway.Refund(purchase)
+}
+
+// fakeQueue hands out a single job and records how it was finished. With
+// leaseLost set the job was claimed again before it could be finished.
+type fakeQueue struct {
+	usecase.PaymentQueue
+	job       *entity.PaymentJob
+	leaseLost bool
+	completed bool
+	retryAt   time.Time
+	failed    bool
+}
+
+func (q *fakeQueue) ClaimPaymentJob(time.Duration) (*entity.PaymentJob, error) {
+	job := q.job
+	q.job = nil
+	return job, nil
+}
+
+func (q *fakeQueue) CompletePaymentJob(*entity.PaymentJob) error {
+	if q.leaseLost {
+		return usecase.ErrPaymentJobLeaseLost
+	}
+	q.completed = true
+	return nil
+}
+
+func (q *fakeQueue) RetryPaymentJob(_ *entity.PaymentJob, runAt time.Time, _ error) error {
+	if q.leaseLost {
+		return usecase.ErrPaymentJobLeaseLost
+	}
+	q.retryAt = runAt
+	return nil
+}
+
+func (q *fakeQueue) FailPaymentJob(*entity.PaymentJob, error) error {
+	if q.leaseLost {
+		return usecase.ErrPaymentJobLeaseLost
+	}
+	q.failed = true
+	return nil
+}
+
+func newPurchase() *entity.Purchase {
+	return &entity.Purchase{
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+		Status: entity.PurchaseStatusProcessing,
+		Amount: 12500,
+	}
+}
+
+func processWithScenario(t *testing.T, scenario string) (*fakeTourism, *entity.Purchase, error) {
+	t.Helper()
+
+	gateway, err := NewFakeGateway(scenario, 0, "kzt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tourism := newFakeTourism()
+	p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+	purchase := newPurchase()
+	err = p.processPurchase(purchase)
+	return tourism, purchase, err
+}
+
+func TestProcessPurchaseSuccess(t *testing.T) {
+	tourism, purchase, err := processWithScenario(t, FakeScenarioSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tourism.paid) != 1 || tourism.paid[0] != purchase.ID {
+		t.Fatalf("paid = %v, want [%s]", tourism.paid, purchase.ID)
+	}
+	if tourism.attached[purchase.ID] == "" {
+		t.Fatal("charge was not attached to the purchase")
+	}
+}
+
+func TestProcessPurchaseDeclined(t *testing.T) {
+	tourism, purchase, err := processWithScenario(t, FakeScenarioDecline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tourism.failed) != 1 || tourism.failed[0] != purchase.ID {
+		t.Fatalf("failed = %v, want [%s]", tourism.failed, purchase.ID)
+	}
+	if len(tourism.paid) != 0 {
+		t.Fatalf("paid = %v, want none", tourism.paid)
+	}
+}
+
+func TestProcessPurchaseTimeout(t *testing.T) {
+	tourism, _, err := processWithScenario(t, FakeScenarioTimeout)
+
+	if err == nil {
+		t.Fatal("timeout was not reported for a retry")
+	}
+	if len(tourism.paid) != 0 || len(tourism.failed) != 0 {
+		t.Fatalf("paid = %v, failed = %v, want the purchase left processing", tourism.paid, tourism.failed)
+	}
+}
+
+func TestProcessPurchasePartialFailure(t *testing.T) {
+	tourism, purchase, err := processWithScenario(t, FakeScenarioPartial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tourism.paid) != 1 || tourism.paid[0] != purchase.ID {
+		t.Fatalf("paid = %v, want the captured charge recovered through Status", tourism.paid)
+	}
+}
+
+func TestProcessPurchaseSkipsFinishedPurchase(t *testing.T) {
+	gateway, _ := NewFakeGateway(FakeScenarioSuccess, 0, "kzt")
+	tourism := newFakeTourism()
+	p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+	purchase := newPurchase()
+	purchase.Status = entity.PurchaseStatusRefunded
+	if err := p.processPurchase(purchase); err != nil {
+		t.Fatal(err)
+	}
+	if len(tourism.attached) != 0 || len(tourism.paid) != 0 {
+		t.Fatal("a purchase that left Processing was charged")
+	}
+}
+
+func TestProcessPurchaseRefundsChargeOfPurchaseCancelledMeanwhile(t *testing.T) {
+	slow, _ := NewFakeGateway(FakeScenarioSuccess, 20*time.Millisecond, "kzt")
+	tourism := newFakeTourism()
+	purchase := newPurchase()
+	// The user cancels while the slow charge is in flight. The cancellation saw
+	// no charge yet, so only the processor can give the money back.
+	gateway := &refundingGateway{FakeGateway: slow, during: func() { tourism.cancelled[purchase.ID] = true }}
+	p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+	if err := p.processPurchase(purchase); err != nil {
+		t.Fatal(err)
+	}
+
+	chargeID := "fake_" + purchase.ID.String()
+	if tourism.recorded[purchase.ID] != chargeID {
+		t.Errorf("recorded charge = %q, want %q", tourism.recorded[purchase.ID], chargeID)
+	}
+	if len(gateway.refunded) != 1 || gateway.refunded[0] != chargeID {
+		t.Errorf("refunded = %v, want the captured charge", gateway.refunded)
+	}
+	if len(tourism.paid) != 0 {
+		t.Errorf("paid = %v, want the cancelled purchase left alone", tourism.paid)
+	}
+}
+
+func TestProcessPurchaseRetriesRefundOfAbandonedCharge(t *testing.T) {
+	fake, _ := NewFakeGateway(FakeScenarioSuccess, 0, "kzt")
+	gateway := &refundingGateway{FakeGateway: fake, refundErr: errors.New("gateway unavailable")}
+	tourism := newFakeTourism()
+	p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+	purchase := newPurchase()
+	purchase.Status = entity.PurchaseStatusRefunded
+	purchase.PaymentIntentID = "fake_" + purchase.ID.String()
+	if err := p.processPurchase(purchase); err == nil {
+		t.Fatal("failed refund was not reported for a retry")
+	}
+
+	gateway.refundErr = nil
+	if err := p.processPurchase(purchase); err != nil {
+		t.Fatal(err)
+	}
+	if len(gateway.refunded) != 1 {
+		t.Errorf("refunded = %v, want the charge refunded on retry", gateway.refunded)
+	}
+}
+
+// clientConfirmedGateway waits for the client to confirm its charges, like Stripe.
+type clientConfirmedGateway struct {
+	*FakeGateway
+	charged bool
+}
+
+func (g *clientConfirmedGateway) Charge(req ChargeRequest) (*Charge, error) {
+	g.charged = true
+	return g.FakeGateway.Charge(req)
+}
+
+func (g *clientConfirmedGateway) ClientConfirmed() bool {
+	return true
+}
+
+func TestProcessPurchaseLeavesClientConfirmedChargesToTheClient(t *testing.T) {
+	fake, _ := NewFakeGateway(FakeScenarioSuccess, 0, "kzt")
+	gateway := &clientConfirmedGateway{FakeGateway: fake}
+	tourism := newFakeTourism()
+	p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+	if err := p.processPurchase(newPurchase()); err != nil {
+		t.Fatal(err)
+	}
+	if gateway.charged || len(tourism.attached) != 0 {
+		t.Fatal("the worker charged a purchase that waits for the client, a promo code could no longer be applied")
+	}
+}
+
+func TestProcessPurchasePaysFreePurchasesWithoutCharge(t *testing.T) {
+	fake, _ := NewFakeGateway(FakeScenarioSuccess, 0, "kzt")
+	var charged bool
+	gateways := map[string]PaymentGateway{
+		"server charged":   &refundingGateway{FakeGateway: fake, during: func() { charged = true }},
+		"client confirmed": &clientConfirmedGateway{FakeGateway: fake},
+	}
+	for name, gateway := range gateways {
+		t.Run(name, func(t *testing.T) {
+			tourism := newFakeTourism()
+			p := &PaymentProcessor{tourismUsecase: tourism, gateway: gateway}
+
+			purchase := newPurchase()
+			purchase.Discount, purchase.Amount = purchase.Amount, 0
+			if err := p.processPurchase(purchase); err != nil {
+				t.Fatal(err)
+			}
+			if confirmed, ok := gateway.(*clientConfirmedGateway); charged || (ok && confirmed.charged) {
+				t.Fatal("a free purchase was charged")
+			}
+			if len(tourism.paid) != 1 {
+				t.Fatalf("paid = %v, want the purchase paid", tourism.paid)
+			}
+		})
+	}
+}
+
+func runJobWithTimeout(attempts, maxAttempts int) (*fakeTourism, *fakeQueue) {
+	gateway, _ := NewFakeGateway(FakeScenarioTimeout, 0, "kzt")
+	tourism := newFakeTourism()
+	queue := &fakeQueue{job: &entity.PaymentJob{ID: uuid.New(), Attempts: attempts, Purchase: *newPurchase()}}
+	p := &PaymentProcessor{
+		tourismUsecase: tourism,
+		queue:          queue,
+		gateway:        gateway,
+		maxAttempts:    maxAttempts,
+		retryBackoff:   time.Second,
+	}
+	p.runNextJob()
+	return tourism, queue
+}
+
+func TestRunNextJobRetriesWithBackoff(t *testing.T) {
+	start := time.Now()
+	tourism, queue := runJobWithTimeout(3, 5)
+
+	if queue.completed || queue.failed {
+		t.Fatal("job was finished, want a retry")
+	}
+	if wait := queue.retryAt.Sub(start); wait < 4*time.Second {
+		t.Fatalf("retry in %v, want at least 4s after the third attempt", wait)
+	}
+	if len(tourism.failed) != 0 {
+		t.Fatalf("failed = %v, want the purchase kept processing", tourism.failed)
+	}
+}
+
+func TestRunNextJobGivesUpAfterMaxAttempts(t *testing.T) {
+	tourism, queue := runJobWithTimeout(5, 5)
+
+	if !queue.failed {
+		t.Fatal("job was not marked failed")
+	}
+	if len(tourism.failed) != 1 {
+		t.Fatalf("failed = %v, want the purchase failed", tourism.failed)
+	}
+}
+
+func TestRunNextJobAfterLostLease(t *testing.T) {
+	gateway, _ := NewFakeGateway(FakeScenarioSuccess, 0, "kzt")
+	queue := &fakeQueue{job: &entity.PaymentJob{ID: uuid.New(), Attempts: 1, Purchase: *newPurchase()}, leaseLost: true}
+	p := &PaymentProcessor{tourismUsecase: newFakeTourism(), queue: queue, gateway: gateway}
+
+	if !p.runNextJob() {
+		t.Fatal("runNextJob() reported no job")
+	}
+	if queue.completed {
+		t.Fatal("job was completed by a worker that lost its lease")
+	}
+}
+
+func TestFakeGatewayRefundFail(t *testing.T) {
+	gateway, err := NewFakeGateway(FakeScenarioRefundFail, 0, "kzt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Refund(&entity.Purchase{ID: uuid.New()}); err == nil {
+		t.Fatal("refund succeeded, want error")
+	}
+}
+
+func TestNewGatewayStripeRequiresSecrets(t *testing.T) {
+	tests := []struct {
+		name, secretKey, webhookSecret string
+		wantErr                        bool
+	}{
+		{name: "no secret key", webhookSecret: "whsec_test", wantErr: true},
+		{name: "no webhook secret", secretKey: "sk_test", wantErr: true},
+		{name: "both secrets", secretKey: "sk_test", webhookSecret: "whsec_test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Payment.Gateway = GatewayStripe
+			cfg.Stripe.SecretKey, cfg.Stripe.WebhookSecret = tt.secretKey, tt.webhookSecret
+			if _, err := NewGateway(cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("NewGateway() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
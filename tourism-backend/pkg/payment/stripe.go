@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"fmt"
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/paymentintent"
+	"github.com/stripe/stripe-go/v82/refund"
+	"tourism-backend/internal/entity"
+)
+
+// StripeGateway charges purchases with Stripe PaymentIntents. Charges stay
+// pending until the client confirms them and the webhook reports the result.
+type StripeGateway struct {
+	currency string
+}
+
+func NewStripeGateway(secretKey, currency string) *StripeGateway {
+	stripe.Key = secretKey
+	return &StripeGateway{currency: currency}
+}
+
+func (g *StripeGateway) Charge(req ChargeRequest) (*Charge, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(req.Amount),
+		Currency: stripe.String(g.currency),
+		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+	}
+	params.AddMetadata("purchase_id", req.PurchaseID.String())
+	// Retries of the same charge get the same PaymentIntent. A promo code
+	// applied after a charge that was never attached changes the amount, which
+	// needs a new intent: Stripe refuses a key reused with other parameters.
+	params.SetIdempotencyKey(fmt.Sprintf("purchase-%s-%d", req.PurchaseID, req.Amount))
+
+	pi, err := paymentintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe create payment intent: %w", err)
+	}
+	return chargeFromPaymentIntent(pi), nil
+}
+
+func (g *StripeGateway) Refund(purchase *entity.Purchase) error {
+	if purchase.PaymentIntentID == "" {
+		return nil
+	}
+
+	pi, err := paymentintent.Get(purchase.PaymentIntentID, nil)
+	if err != nil {
+		return fmt.Errorf("stripe get payment intent: %w", err)
+	}
+
+	switch pi.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		params := &stripe.RefundParams{PaymentIntent: stripe.String(pi.ID)}
+		params.SetIdempotencyKey("refund-" + purchase.ID.String())
+		if _, err := refund.New(params); err != nil {
+			return fmt.Errorf("stripe refund: %w", err)
+		}
+	case stripe.PaymentIntentStatusCanceled:
+	default:
+		// Nothing was captured yet, cancelling the intent stops the charge.
+		if _, err := paymentintent.Cancel(pi.ID, nil); err != nil {
+			return fmt.Errorf("stripe cancel payment intent: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClientConfirmed is true, the client confirms the PaymentIntent with its
+// client secret.
+func (g *StripeGateway) ClientConfirmed() bool {
+	return true
+}
+
+func (g *StripeGateway) Status(chargeID string) (*Charge, error) {
+	pi, err := paymentintent.Get(chargeID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stripe get payment intent: %w", err)
+	}
+	return chargeFromPaymentIntent(pi), nil
+}
+
+func chargeFromPaymentIntent(pi *stripe.PaymentIntent) *Charge {
+	charge := &Charge{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Amount:       pi.Amount,
+		Currency:     string(pi.Currency),
+		ReceiptEmail: pi.ReceiptEmail,
+	}
+	if pi.PaymentMethod != nil {
+		charge.PaymentMethod = pi.PaymentMethod.ID
+	}
+
+	switch pi.Status {
+	case stripe.PaymentIntentStatusSucceeded:
+		charge.Status = ChargeStatusSucceeded
+	case stripe.PaymentIntentStatusCanceled:
+		charge.Status = ChargeStatusFailed
+	default:
+		charge.Status = ChargeStatusPending
+	}
+	return charge
+}
@@ -0,0 +1,12 @@
+SET search_path TO tourism, public;
+
+-- earthdistance measures great-circle distances on top of cube. Both go to public
+-- so that queries find them without a search_path.
+CREATE EXTENSION IF NOT EXISTS cube WITH SCHEMA public;
+CREATE EXTENSION IF NOT EXISTS earthdistance WITH SCHEMA public;
+
+-- Radius searches match earth_box(...) @> ll_to_earth(latitude, longitude) against this index.
+CREATE INDEX IF NOT EXISTS idx_tour_locations_earth ON tour_locations USING GIST (ll_to_earth(latitude, longitude));
+
+-- Bounding box searches compare the coordinates directly.
+CREATE INDEX IF NOT EXISTS idx_tour_locations_lat_lng ON tour_locations (latitude, longitude);
@@ -0,0 +1,70 @@
+SET search_path TO tourism;
+
+-- search_vector is maintained by triggers from the tour and the names of its categories.
+ALTER TABLE tours ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE INDEX IF NOT EXISTS idx_tours_search_vector ON tours USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION tours_search_vector_refresh() RETURNS trigger AS
+$$
+BEGIN
+    NEW.search_vector :=
+            setweight(to_tsvector('simple', coalesce(NEW.name, '')), 'A') ||
+            setweight(to_tsvector('simple', coalesce((SELECT string_agg(c.name, ' ')
+                                                      FROM tourism.tour_categories tc
+                                                               JOIN tourism.categories c ON c.id = tc.category_id
+                                                      WHERE tc.tour_id = NEW.id
+                                                        AND c.deleted_at IS NULL), '')), 'B') ||
+            setweight(to_tsvector('simple', coalesce(NEW.route, '')), 'C') ||
+            setweight(to_tsvector('simple', coalesce(NEW.description, '')), 'D');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tours_search_vector_update ON tours;
+CREATE TRIGGER tours_search_vector_update
+    BEFORE INSERT OR UPDATE OF name, description, route
+    ON tours
+    FOR EACH ROW
+EXECUTE FUNCTION tours_search_vector_refresh();
+
+-- Category changes touch the tours they belong to so that their trigger runs again.
+CREATE OR REPLACE FUNCTION tour_categories_search_vector_refresh() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE tourism.tours SET name = name WHERE id = OLD.tour_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE tourism.tours SET name = name WHERE id = NEW.tour_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tour_categories_search_vector_update ON tour_categories;
+CREATE TRIGGER tour_categories_search_vector_update
+    AFTER INSERT OR UPDATE OR DELETE
+    ON tour_categories
+    FOR EACH ROW
+EXECUTE FUNCTION tour_categories_search_vector_refresh();
+
+CREATE OR REPLACE FUNCTION categories_search_vector_refresh() RETURNS trigger AS
+$$
+BEGIN
+    UPDATE tourism.tours
+    SET name = name
+    WHERE id IN (SELECT tour_id FROM tourism.tour_categories WHERE category_id = NEW.id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+CREATE TRIGGER categories_search_vector_update
+    AFTER UPDATE OF name, deleted_at
+    ON categories
+    FOR EACH ROW
+EXECUTE FUNCTION categories_search_vector_refresh();
+
+-- Index the tours created before search existed.
+UPDATE tours SET name = name WHERE search_vector IS NULL;
@@ -63,12 +63,26 @@ func (p *Postgres) Migrate() error {
 		&entity.Panorama{},
 		&entity.User{},
 		&entity.TourEvent{},
+		&entity.TourSchedule{},
+		&entity.PromoCode{},
 		&entity.Purchase{},
+		&entity.PaymentJob{},
+		&entity.TicketType{},
+		&entity.PurchaseItem{},
+		&entity.CheckIn{},
+		&entity.WaitlistEntry{},
+		&entity.PricingRule{},
 		&entity.TourCategory{},
 		&entity.TourLocation{},
+		&entity.Waypoint{},
+		&entity.TourTrack{},
+		&entity.UploadSession{},
+		&entity.UploadChunk{},
 		&entity.Category{},
 		&entity.UserFavorites{},
 		&entity.UserActivity{},
+		&entity.Review{},
+		&entity.ReviewPhoto{},
 	)
 	if err != nil {
 		log.Println("Migrating entities to Postgres - err: %w", err)
@@ -96,5 +110,25 @@ func (p *Postgres) Migrate() error {
 		log.Println("Error executing tourembeddings")
 		panic(err)
 	}
+
+	queryTourSearch, err := ioutil.ReadFile("pkg/postgres/create_tour_search.sql")
+	if err != nil {
+		log.Println("Error reading create_tour_search.sql")
+		panic(err)
+	}
+	if err := p.Conn.Exec(string(queryTourSearch)).Error; err != nil {
+		log.Println("Error executing tour search")
+		panic(err)
+	}
+
+	queryTourGeo, err := ioutil.ReadFile("pkg/postgres/create_tour_geo.sql")
+	if err != nil {
+		log.Println("Error reading create_tour_geo.sql")
+		panic(err)
+	}
+	if err := p.Conn.Exec(string(queryTourGeo)).Error; err != nil {
+		log.Println("Error executing tour geo")
+		panic(err)
+	}
 	return nil
 }
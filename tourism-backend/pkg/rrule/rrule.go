@@ -0,0 +1,369 @@
+// Package rrule expands the recurrence rules of RFC 5545 into dates. It
+// supports the FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and
+// WKST parts, with a frequency of DAILY, WEEKLY, MONTHLY or YEARLY.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// _maxPeriods bounds the expansion of rules that never match, such as
+// BYMONTHDAY=30 with BYMONTH=2.
+const _maxPeriods = 100000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var _weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is an entry of BYDAY. A non-zero N picks the Nth such weekday of
+// the month, counted from its end when negative.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule is a parsed recurrence rule. A zero Count or Until does not limit it.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=SA", with or without the
+// "RRULE:" prefix. An UNTIL without the UTC designator is read in loc.
+func Parse(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: the rule is empty", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s is given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq, err = parseFrequency(value)
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(value)
+		case "COUNT":
+			rule.Count, err = parsePositive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(value, 31)
+		case "BYMONTH":
+			rule.ByMonth, err = parseMonths(value)
+		case "WKST":
+			rule.WeekStart, err = parseWeekday(value)
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRule, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRule, name, err)
+		}
+	}
+
+	if err := rule.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return rule, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Freq == "" {
+		return errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL exclude each other")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return errors.New("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return errors.New("BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+	if r.Freq == Yearly && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return errors.New("BYDAY with FREQ=YEARLY needs BYMONTH")
+	}
+	return nil
+}
+
+// Between returns the occurrences of the rule that fall in [from, to). The
+// series starts at start, which is its first occurrence if it matches the
+// rule, and every occurrence keeps the wall clock time of start in its
+// location.
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	count := 0
+	for i := 0; i < _maxPeriods; i++ {
+		periodStart, days := r.period(start, i)
+		if !periodStart.Before(to) || (!r.Until.IsZero() && periodStart.After(r.Until)) {
+			break
+		}
+		for _, day := range days {
+			occurrence := time.Date(day.Year(), day.Month(), day.Day(),
+				start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if occurrence.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && occurrence.After(r.Until) {
+				return occurrences
+			}
+			count++
+			if (r.Count > 0 && count > r.Count) || !occurrence.Before(to) {
+				return occurrences
+			}
+			if !occurrence.Before(from) {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+	return occurrences
+}
+
+// period returns the first day of the i-th period of the series and the days
+// of the period that match the rule, in order.
+func (r *Rule) period(start time.Time, i int) (time.Time, []time.Time) {
+	y, m, d := start.Date()
+	loc := start.Location()
+	step := i * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+		if r.matchMonth(day.Month()) && r.matchMonthDay(day) && r.matchWeekday(day) {
+			days = append(days, day)
+		}
+		return day, days
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := time.Date(y, m, d-offset+7*step, 0, 0, 0, 0, loc)
+		for k := 0; k < 7; k++ {
+			day := time.Date(y, m, d-offset+7*step+k, 0, 0, 0, 0, loc)
+			if !r.matchMonth(day.Month()) {
+				continue
+			}
+			if (len(r.ByDay) == 0 && day.Weekday() != start.Weekday()) || !r.matchWeekday(day) {
+				continue
+			}
+			days = append(days, day)
+		}
+		return weekStart, days
+	case Monthly:
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if r.matchMonth(first.Month()) {
+			days = r.monthDays(first, d)
+		}
+		return first, days
+	default:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(time.Date(y+step, month, 1, 0, 0, 0, 0, loc), d)...)
+		}
+		sort.Slice(days, func(a, b int) bool { return days[a].Before(days[b]) })
+		return time.Date(y+step, time.January, 1, 0, 0, 0, 0, loc), days
+	}
+}
+
+// monthDays returns the days of the month starting at first that match
+// BYMONTHDAY and BYDAY, or the day startDay if the rule has neither.
+func (r *Rule) monthDays(first time.Time, startDay int) []time.Time {
+	var days []time.Time
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			if day.Day() == startDay {
+				days = append(days, day)
+			}
+			continue
+		}
+		if r.matchMonthDay(day) && r.matchWeekday(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := daysIn(day)
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == day.Day() || monthDay < 0 && length+monthDay+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if weekday.Weekday != day.Weekday() {
+			continue
+		}
+		switch {
+		case weekday.N == 0:
+			return true
+		case weekday.N > 0 && (day.Day()-1)/7+1 == weekday.N:
+			return true
+		case weekday.N < 0 && (daysIn(day)-day.Day())/7+1 == -weekday.N:
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days of the month of day.
+func daysIn(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseFrequency(value string) (Frequency, error) {
+	switch freq := Frequency(value); freq {
+	case Daily, Weekly, Monthly, Yearly:
+		return freq, nil
+	default:
+		return "", fmt.Errorf("%s is not DAILY, WEEKLY, MONTHLY or YEARLY", value)
+	}
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s is not a positive number", value)
+	}
+	return n, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		// A date includes the whole day.
+		return until.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%s is not a date or a date-time", value)
+}
+
+// parseInts reads a list of numbers between -limit and limit, except 0.
+func parseInts(value string, limit int) ([]int, error) {
+	var ints []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n < -limit || n > limit {
+			return nil, fmt.Errorf("%s is not between 1 and %d or -%d and -1", item, limit, limit)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+func parseMonths(value string) ([]time.Month, error) {
+	var months []time.Month
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 1 || n > 12 {
+			return nil, fmt.Errorf("%s is not a month between 1 and 12", item)
+		}
+		months = append(months, time.Month(n))
+	}
+	return months, nil
+}
+
+// parseByDay reads weekdays such as SA, 1SA or -1SU.
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("%s is not a weekday", item)
+		}
+		weekday, err := parseWeekday(item[len(item)-2:])
+		if err != nil {
+			return nil, err
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := item[:len(item)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%s is not a weekday of the month", item)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	weekday, ok := _weekdays[value]
+	if !ok {
+		return 0, fmt.Errorf("%s is not a weekday", value)
+	}
+	return weekday, nil
+}
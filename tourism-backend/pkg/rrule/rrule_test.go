@@ -0,0 +1,99 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func dates(times []time.Time) []string {
+	result := make([]string, 0, len(times))
+	for _, t := range times {
+		result = append(result, t.Format("2006-01-02 15:04 MST"))
+	}
+	return result
+}
+
+func expand(t *testing.T, rule string, start time.Time, from, to time.Time) []string {
+	t.Helper()
+	r, err := Parse(rule, start.Location())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dates(r.Between(start, from, to))
+}
+
+func assertDates(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWeeklyKeepsWallClockOverDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.October, 17, 10, 0, 0, 0, berlin)
+	got := expand(t, "RRULE:FREQ=WEEKLY;BYDAY=SA", start, start, start.AddDate(0, 0, 21))
+	assertDates(t, got, "2026-10-17 10:00 CEST", "2026-10-24 10:00 CEST", "2026-10-31 10:00 CET")
+}
+
+func TestWeeklyIntervalAndCount(t *testing.T) {
+	start := time.Date(2026, time.January, 6, 9, 30, 0, 0, time.UTC) // a Tuesday
+	got := expand(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=5", start, start, start.AddDate(1, 0, 0))
+	assertDates(t, got,
+		"2026-01-06 09:30 UTC", "2026-01-08 09:30 UTC",
+		"2026-01-20 09:30 UTC", "2026-01-22 09:30 UTC",
+		"2026-02-03 09:30 UTC")
+}
+
+func TestCountIncludesOccurrencesBeforeFrom(t *testing.T) {
+	start := time.Date(2026, time.March, 1, 8, 0, 0, 0, time.UTC)
+	got := expand(t, "FREQ=DAILY;COUNT=3", start, start.AddDate(0, 0, 1), start.AddDate(0, 1, 0))
+	assertDates(t, got, "2026-03-02 08:00 UTC", "2026-03-03 08:00 UTC")
+}
+
+func TestMonthlyLastSundayUntil(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	got := expand(t, "FREQ=MONTHLY;BYDAY=-1SU;UNTIL=20260430", start, start, start.AddDate(2, 0, 0))
+	assertDates(t, got, "2026-01-25 12:00 UTC", "2026-02-22 12:00 UTC", "2026-03-29 12:00 UTC", "2026-04-26 12:00 UTC")
+}
+
+func TestMonthlySkipsMissingDays(t *testing.T) {
+	start := time.Date(2026, time.January, 31, 7, 0, 0, 0, time.UTC)
+	got := expand(t, "FREQ=MONTHLY", start, start, time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC))
+	assertDates(t, got, "2026-01-31 07:00 UTC", "2026-03-31 07:00 UTC")
+}
+
+func TestYearlyByMonthAndMonthDay(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 18, 0, 0, 0, time.UTC)
+	got := expand(t, "FREQ=YEARLY;BYMONTH=12,6;BYMONTHDAY=1,-1", start, start, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assertDates(t, got, "2026-06-01 18:00 UTC", "2026-06-30 18:00 UTC", "2026-12-01 18:00 UTC", "2026-12-31 18:00 UTC")
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=1SA",
+		"FREQ=WEEKLY;BYMONTHDAY=3",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=YEARLY;BYDAY=MO",
+	} {
+		if _, err := Parse(rule, time.UTC); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", rule, err)
+		}
+	}
+}
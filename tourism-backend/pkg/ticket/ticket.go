@@ -0,0 +1,125 @@
+// Package ticket signs and verifies the tokens printed in the QR codes of
+// purchased seats. A token is the base64url encoding of a binary payload and
+// its Ed25519 signature, so it can be checked with the public key alone,
+// without asking the server:
+//
+//	version (1) | purchase ID (16) | tour event ID (16) | seat (2) | expiry (4) | signature (64)
+//
+// Numbers are big-endian and the expiry is in Unix seconds.
+package ticket
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"time"
+)
+
+const (
+	_version     = 1
+	_payloadSize = 1 + 16 + 16 + 2 + 4
+	_tokenSize   = _payloadSize + ed25519.SignatureSize
+)
+
+var (
+	ErrInvalidTicket = errors.New("invalid ticket")
+	ErrTicketExpired = errors.New("ticket expired")
+)
+
+// Ticket admits one person to a tour event.
+type Ticket struct {
+	PurchaseID  uuid.UUID `json:"purchase_id"`
+	TourEventID uuid.UUID `json:"tour_event_id"`
+	Seat        int       `json:"seat"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Sign returns the token of the ticket signed with key.
+func Sign(key ed25519.PrivateKey, t Ticket) (string, error) {
+	if t.Seat < 0 || t.Seat > math.MaxUint16 {
+		return "", fmt.Errorf("%w: seat %d is out of range", ErrInvalidTicket, t.Seat)
+	}
+	expiresAt := t.ExpiresAt.Unix()
+	if expiresAt <= 0 || expiresAt > math.MaxUint32 {
+		return "", fmt.Errorf("%w: expiry %s is out of range", ErrInvalidTicket, t.ExpiresAt)
+	}
+
+	token := make([]byte, _payloadSize, _tokenSize)
+	token[0] = _version
+	copy(token[1:17], t.PurchaseID[:])
+	copy(token[17:33], t.TourEventID[:])
+	binary.BigEndian.PutUint16(token[33:35], uint16(t.Seat))
+	binary.BigEndian.PutUint32(token[35:39], uint32(expiresAt))
+	token = append(token, ed25519.Sign(key, token)...)
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Verify checks the signature of the token and returns its ticket. A ticket
+// with a valid signature is still returned with ErrTicketExpired after its
+// expiry.
+func Verify(key ed25519.PublicKey, token string, now time.Time) (*Ticket, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != _tokenSize {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidTicket)
+	}
+	if raw[0] != _version {
+		return nil, fmt.Errorf("%w: unknown version %d", ErrInvalidTicket, raw[0])
+	}
+	if !ed25519.Verify(key, raw[:_payloadSize], raw[_payloadSize:]) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidTicket)
+	}
+
+	t := &Ticket{
+		Seat:      int(binary.BigEndian.Uint16(raw[33:35])),
+		ExpiresAt: time.Unix(int64(binary.BigEndian.Uint32(raw[35:39])), 0).UTC(),
+	}
+	copy(t.PurchaseID[:], raw[1:17])
+	copy(t.TourEventID[:], raw[17:33])
+	if !now.Before(t.ExpiresAt) {
+		return t, fmt.Errorf("%w at %s", ErrTicketExpired, t.ExpiresAt.Format(time.RFC3339))
+	}
+	return t, nil
+}
+
+// GenerateKey returns a new private key.
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ticket key: %w", err)
+	}
+	return key, nil
+}
+
+// ParsePrivateKey reads a private key saved as the base64 encoding of its
+// 32-byte seed.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ticket private key is not a base64 %d-byte seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// EncodePrivateKey returns the base64 seed read by ParsePrivateKey.
+func EncodePrivateKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Seed())
+}
+
+// ParsePublicKey reads a public key saved in base64.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ticket public key is not a base64 %d-byte key", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// EncodePublicKey returns the base64 key read by ParsePublicKey.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
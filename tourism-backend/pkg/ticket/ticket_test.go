@@ -0,0 +1,92 @@
+package ticket
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.November, 7, 9, 0, 0, 0, time.UTC)
+	want := Ticket{
+		PurchaseID:  uuid.New(),
+		TourEventID: uuid.New(),
+		Seat:        2,
+		ExpiresAt:   now.Add(24 * time.Hour),
+	}
+
+	token, err := Sign(key, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 138 {
+		t.Errorf("token length = %d, want 138", len(token))
+	}
+
+	got, err := Verify(key.Public().(ed25519.PublicKey), token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("Verify() = %+v, want %+v", *got, want)
+	}
+
+	if _, err := Verify(key.Public().(ed25519.PublicKey), token, want.ExpiresAt); !errors.Is(err, ErrTicketExpired) {
+		t.Errorf("Verify() after expiry error = %v, want ErrTicketExpired", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+	now := time.Now()
+	token, err := Sign(key, Ticket{PurchaseID: uuid.New(), TourEventID: uuid.New(), ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip the first character of the purchase ID.
+	tampered := token[:2] + strings.Map(func(r rune) rune {
+		if r == 'A' {
+			return 'B'
+		}
+		return 'A'
+	}, token[2:3]) + token[3:]
+
+	for name, tc := range map[string]struct {
+		key   ed25519.PublicKey
+		token string
+	}{
+		"tampered":  {key.Public().(ed25519.PublicKey), tampered},
+		"other key": {other.Public().(ed25519.PublicKey), token},
+		"truncated": {key.Public().(ed25519.PublicKey), token[:100]},
+		"garbage":   {key.Public().(ed25519.PublicKey), "not a ticket"},
+	} {
+		if _, err := Verify(tc.key, tc.token, now); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("%s: Verify() error = %v, want ErrInvalidTicket", name, err)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key, _ := GenerateKey()
+	parsed, err := ParsePrivateKey(EncodePrivateKey(key))
+	if err != nil || !parsed.Equal(key) {
+		t.Fatalf("ParsePrivateKey() = %v, %v", parsed, err)
+	}
+	public := key.Public().(ed25519.PublicKey)
+	parsedPublic, err := ParsePublicKey(EncodePublicKey(public))
+	if err != nil || !parsedPublic.Equal(public) {
+		t.Fatalf("ParsePublicKey() = %v, %v", parsedPublic, err)
+	}
+	if _, err := ParsePrivateKey("c2hvcnQ="); err == nil {
+		t.Error("ParsePrivateKey() accepted a short seed")
+	}
+}
@@ -2,16 +2,13 @@ package utils
 
 import (
 	"encoding/base64"
-	"fmt"
-	"github.com/google/uuid"
 	"github.com/skip2/go-qrcode"
 	"tourism-backend/internal/entity"
 )
 
-func GenerateQRCode(purchaseID uuid.UUID) *entity.PurchaseQRDTO {
-	url := fmt.Sprintf("./v1/tours/provider/%s/check", purchaseID.String())
-
-	qrCodeBytes, err := qrcode.Encode(url, qrcode.Medium, 256)
+// GenerateQRCode renders the signed ticket token of a seat as a QR code.
+func GenerateQRCode(token string, seat int) *entity.PurchaseQRDTO {
+	qrCodeBytes, err := qrcode.Encode(token, qrcode.Medium, 256)
 	if err != nil {
 		// Handle error (could log or return a fallback)
 		return nil
@@ -21,7 +18,8 @@ func GenerateQRCode(purchaseID uuid.UUID) *entity.PurchaseQRDTO {
 	qrCodeBase64 := "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCodeBytes)
 
 	return &entity.PurchaseQRDTO{
+		Seat:   seat,
 		QRCode: qrCodeBase64,
-		URL:    url,
+		Token:  token,
 	}
 }
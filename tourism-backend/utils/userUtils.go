@@ -21,3 +21,9 @@ func GetUserIDFromContext(c *gin.Context) uuid.UUID {
 	}
 	return userID
 }
+
+func GetRoleFromContext(c *gin.Context) string {
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+	return roleStr
+}
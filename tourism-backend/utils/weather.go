@@ -51,9 +51,8 @@ func GetWeatherInfo(tourWeatherRQ *entity.WeatherInfoRQ) (*entity.WeatherInfo, e
 	if len(forecast.Forecast.ForecastDay) > 0 && len(forecast.Forecast.ForecastDay[0].Hour) > 0 {
 		weatherInfo := forecast.Forecast.ForecastDay[0].Hour[0]
 		return &weatherInfo, nil
-	} else {
-		fmt.Errorf("No weather data available.")
 	}
 
+	// No weather data available.
 	return nil, nil
 }